@@ -1,56 +1,265 @@
 package conf
 
 import (
-	"flag"
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
 )
 
-// LoadConfFromCmd 使用反射从命令行参数加载配置到结构体
+// ErrHelp 命令行参数中有 -h/--help，帮助信息已经输出
+var ErrHelp = pflag.ErrHelp
+
+// LoadConfFromCmd 使用反射从命令行参数 os.Args[1:] 加载配置到结构体，等同于 LoadConfFromArgs
 func LoadConfFromCmd[T any](config T) error {
-	if len(os.Args) < 2 {
-		return nil
+	return LoadConfFromArgs(config, os.Args[1:])
+}
+
+// LoadConfFromArgs 从命令行参数加载配置，使用独立的 FlagSet，不影响全局的 flag 包。
+// 参数名为字段名的小写短横线形式，嵌套结构体用点分隔，例如 --redis-host、--mysql.max-conn；
+// short 标签为单字母短参数，usage 标签为说明。切片使用逗号分隔，map 使用 k=v 逗号分隔，
+// time.Duration 使用 1s、500ms 这样的格式。遇到 -h/--help 时输出帮助并返回 ErrHelp，
+// 不认识的参数返回错误。位置参数被忽略，需要子命令和位置参数时使用 Commands
+func LoadConfFromArgs[T any](config T, args []string) error {
+	fs, _, err := newConfigFlagSet(commandName(), config)
+	if err != nil {
+		return err
 	}
-	// 使用反射获取结构体的字段
-	val := reflect.ValueOf(config)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
-	t := val.Type()
-	for i := 0; i < val.NumField(); i++ {
-		field := t.Field(i)
-		fieldName := field.Name
-		fieldType := field.Type.Kind()
-		fieldVal := val.Field(i)
-
-		// 创建命令行标志
-		switch fieldType {
-		case reflect.String:
-			if fieldVal.String() != "" {
-				continue
-			}
-			flag.StringVar(val.Field(i).Addr().Interface().(*string), fieldName, "", "usage")
-		case reflect.Int:
-			if fieldVal.Int() != 0 {
-				continue
-			}
-			flag.IntVar(val.Field(i).Addr().Interface().(*int), fieldName, 0, "usage")
-		case reflect.Float64:
-			if fieldVal.Float() != 0 {
-				continue
+	fs.SetOutput(os.Stderr)
+	return fs.Parse(args)
+}
+
+func commandName() string {
+	if len(os.Args) == 0 {
+		return "app"
+	}
+	return baseName(os.Args[0])
+}
+
+func baseName(path string) string {
+	if i := strings.LastIndexAny(path, `/\`); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
+
+// newConfigFlagSet 创建 FlagSet 并为配置结构体的每个叶子字段注册参数，
+// 返回的 fieldFlag 在解析后可以通过 set 判断哪些字段被设置
+func newConfigFlagSet(name string, config any) (*pflag.FlagSet, []*fieldFlag, error) {
+	fs := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	fs.SortFlags = false
+	fs.SetNormalizeFunc(normalizeFlagName)
+	flags, err := registerConfigFlags(fs, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of %s:\n%s", name, fs.FlagUsages())
+	}
+	return fs, flags, nil
+}
+
+// registerConfigFlags 为配置结构体的每个叶子字段注册命令行参数，已有的值作为默认值展示
+func registerConfigFlags(fs *pflag.FlagSet, config any) ([]*fieldFlag, error) {
+	var flags []*fieldFlag
+	err := walkConfigFields(reflect.ValueOf(config), func(f configField) error {
+		value := &fieldFlag{field: f}
+		short := f.Field.Tag.Get("short")
+		if len(short) > 1 {
+			return fmt.Errorf("field %s: short flag %q must be a single letter", strings.Join(f.Path, "."), short)
+		}
+		if fs.Lookup(f.Flag) != nil || (short != "" && fs.ShorthandLookup(short) != nil) {
+			return fmt.Errorf("field %s: flag --%s or -%s redefined", strings.Join(f.Path, "."), f.Flag, short)
+		}
+		pf := fs.VarPF(value, f.Flag, short, flagUsage(f))
+		if def, ok := f.Field.Tag.Lookup("default"); ok {
+			if cur := f.peek(); !cur.IsValid() || cur.IsZero() {
+				pf.DefValue = def
 			}
-			flag.Float64Var(val.Field(i).Addr().Interface().(*float64), fieldName, 0, "usage")
-		default:
-			return fmt.Errorf("unsupported field type: %s for field: %s", fieldType, fieldName)
 		}
+		if value.IsBoolFlag() {
+			pf.NoOptDefVal = "true"
+		}
+		flags = append(flags, value)
+		return nil
+	})
+	return flags, err
+}
+
+// flagUsage usage 标签加上对应的环境变量名
+func flagUsage(f configField) string {
+	usage := f.Field.Tag.Get("usage")
+	env := "env " + f.Env
+	if usage == "" {
+		return "(" + env + ")"
+	}
+	return usage + " (" + env + ")"
+}
+
+// normalizeFlagName 兼容旧版本的参数名：--MysqlHost、--mysql_host 都视为 --mysql-host
+func normalizeFlagName(_ *pflag.FlagSet, name string) pflag.NormalizedName {
+	return pflag.NormalizedName(kebabPath(name))
+}
+
+// kebabCase MysqlHost -> mysql-host，HTTPPort -> http-port
+func kebabCase(name string) string {
+	return strings.ToLower(strings.ReplaceAll(upperSnake(name), "_", "-"))
+}
+
+// kebabPath 对点分路径的每一段做 kebabCase
+func kebabPath(name string) string {
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		parts[i] = kebabCase(strings.ReplaceAll(p, "-", "_"))
+	}
+	return strings.Join(parts, ".")
+}
+
+// fieldFlag 把配置字段适配为 pflag.Value
+type fieldFlag struct {
+	field configField
+	set   bool
+}
+
+func (f *fieldFlag) String() string {
+	if f == nil || f.field.peek == nil {
+		return ""
+	}
+	return fieldToString(f.field.peek())
+}
+
+// Set 切片类型的参数重复出现时追加，其它类型后出现的覆盖先出现的
+func (f *fieldFlag) Set(s string) error {
+	v := f.field.value()
+	if f.set && v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		old := reflect.ValueOf(v.Interface())
+		if err := setFieldFromString(v, s); err != nil {
+			return err
+		}
+		v.Set(reflect.AppendSlice(old, v))
+		return nil
+	}
+	f.set = true
+	return setFieldFromString(v, s)
+}
+
+// Type 帮助信息中展示的参数类型
+func (f *fieldFlag) Type() string {
+	t := f.field.Field.Type
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == durationType:
+		return "duration"
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return "bytes"
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return "list"
+	case t.Kind() == reflect.Map:
+		return "map"
+	case reflect.PointerTo(t).Implements(textUnmarshalerType), t == timeType:
+		return "string"
 	}
+	return t.Kind().String()
+}
 
-	// 解析命令行参数
-	if !flag.Parsed() {
-		flag.Parse()
+// IsBoolFlag bool 字段可以只写 --debug 表示 true
+func (f *fieldFlag) IsBoolFlag() bool {
+	t := f.field.Field.Type
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
 	}
+	return t.Kind() == reflect.Bool
+}
 
-	return nil
+// Command 子命令，每个子命令有自己的配置结构体和参数
+type Command struct {
+	Name string
+	// Usage 一行说明，在命令列表中展示
+	Usage string
+	// Config 配置结构体指针，可以为 nil
+	Config any
+	// Run 参数解析后执行，args 为剩余的位置参数
+	Run func(args []string) error
+}
+
+// Commands 子命令集合，命令行形如 app <command> [flags] [args]
+type Commands struct {
+	Name     string
+	Output   io.Writer
+	commands map[string]*Command
+}
+
+// NewCommands 创建子命令集合，name 为程序名，为空时使用 os.Args[0]
+func NewCommands(name string) *Commands {
+	if name == "" {
+		name = commandName()
+	}
+	return &Commands{Name: name, Output: os.Stderr, commands: make(map[string]*Command)}
+}
+
+// Add 添加子命令，名字重复时 panic
+func (c *Commands) Add(cmd *Command) *Commands {
+	if _, ok := c.commands[cmd.Name]; ok {
+		panic("conf: command " + cmd.Name + " redefined")
+	}
+	c.commands[cmd.Name] = cmd
+	return c
+}
+
+// Run 根据 args[0] 选择子命令，解析它的参数后执行。没有子命令或为 help、-h、--help 时输出命令列表并返回 ErrHelp
+func (c *Commands) Run(args []string) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		c.usage()
+		return ErrHelp
+	}
+	cmd, ok := c.commands[args[0]]
+	if !ok {
+		c.usage()
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	fs := pflag.NewFlagSet(c.Name+" "+cmd.Name, pflag.ContinueOnError)
+	if cmd.Config != nil {
+		var err error
+		if fs, _, err = newConfigFlagSet(c.Name+" "+cmd.Name, cmd.Config); err != nil {
+			return err
+		}
+	}
+	fs.SetOutput(c.Output)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if cmd.Run == nil {
+		return nil
+	}
+	return cmd.Run(fs.Args())
+}
+
+func (c *Commands) usage() {
+	names := make([]string, 0, len(c.commands))
+	width := 0
+	for name := range c.commands {
+		names = append(names, name)
+		width = max(width, len(name))
+	}
+	sort.Strings(names)
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "Usage: %s <command> [flags]\n\nCommands:\n", c.Name)
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %-*s  %s\n", width, name, c.commands[name].Usage)
+	}
+	fmt.Fprintf(&b, "\nRun '%s <command> --help' for the flags of a command.\n", c.Name)
+	_, _ = c.Output.Write(b.Bytes())
+}
 
+// IsHelp 是否为 -h/--help 返回的错误
+func IsHelp(err error) bool {
+	return errors.Is(err, ErrHelp)
 }
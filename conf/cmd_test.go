@@ -0,0 +1,106 @@
+package conf
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type cmdConf struct {
+	RedisHost string `usage:"redis address" default:"127.0.0.1:6379"`
+	Port      int    `short:"p" usage:"listen port"`
+	Verbose   bool   `short:"v"`
+	Secret    string `flag:"-"`
+	Mysql     struct {
+		MaxConn int `usage:"max open connections"`
+	}
+}
+
+func TestLoadConfFromArgs(t *testing.T) {
+	conf := &cmdConf{Port: 80}
+	err := LoadConfFromArgs(conf, []string{"--redis-host", "redis:6379", "-p", "8080", "-v", "--mysql.max-conn=20"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.RedisHost != "redis:6379" || conf.Port != 8080 || !conf.Verbose || conf.Mysql.MaxConn != 20 {
+		t.Errorf("命令行解析错误: %+v", conf)
+	}
+
+	if err = LoadConfFromArgs(&cmdConf{}, []string{"--RedisHost", "a:1", "--mysql.max_conn", "1"}); err != nil {
+		t.Errorf("应兼容旧的字段名参数: %v", err)
+	}
+	if err = LoadConfFromArgs(&cmdConf{}, []string{"--unknown", "1"}); err == nil {
+		t.Errorf("不认识的参数应返回错误")
+	}
+	if err = LoadConfFromArgs(&cmdConf{}, []string{"--secret", "x"}); err == nil {
+		t.Errorf("flag:\"-\" 的字段不应注册参数")
+	}
+}
+
+func TestConfigFlagSet_Help(t *testing.T) {
+	fs, _, err := newConfigFlagSet("app", &cmdConf{Port: 80})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	fs.SetOutput(&out)
+	if err = fs.Parse([]string{"--help"}); !IsHelp(err) {
+		t.Fatalf("--help 应返回 ErrHelp: %v", err)
+	}
+	help := out.String()
+	for _, want := range []string{
+		"Usage of app:",
+		"--redis-host string",
+		"redis address (env REDIS_HOST) (default \"127.0.0.1:6379\")",
+		"-p, --port int",
+		"(env PORT) (default 80)",
+		"-v, --verbose",
+		"--mysql.max-conn int",
+		"(env MYSQL_MAX_CONN)",
+	} {
+		if !strings.Contains(help, want) {
+			t.Errorf("帮助信息缺少 %q:\n%s", want, help)
+		}
+	}
+}
+
+func TestCommands(t *testing.T) {
+	serve := &cmdConf{}
+	var gotArgs []string
+	var out bytes.Buffer
+	cmds := NewCommands("app")
+	cmds.Output = &out
+	cmds.Add(&Command{Name: "serve", Usage: "start the server", Config: serve, Run: func(args []string) error {
+		gotArgs = args
+		return nil
+	}}).Add(&Command{Name: "version", Usage: "print version"})
+
+	if err := cmds.Run([]string{"serve", "-p", "9000", "extra"}); err != nil {
+		t.Fatal(err)
+	}
+	if serve.Port != 9000 || len(gotArgs) != 1 || gotArgs[0] != "extra" {
+		t.Errorf("子命令参数解析错误: %+v %v", serve, gotArgs)
+	}
+
+	if err := cmds.Run(nil); !errors.Is(err, ErrHelp) || !strings.Contains(out.String(), "serve    start the server") {
+		t.Errorf("没有子命令时应输出命令列表: %v\n%s", err, out.String())
+	}
+	if err := cmds.Run([]string{"nope"}); err == nil {
+		t.Errorf("不认识的子命令应返回错误")
+	}
+	if err := cmds.Run([]string{"version", "--port", "1"}); err == nil {
+		t.Errorf("没有配置的子命令不应接受参数")
+	}
+}
+
+func TestCmdSource_IgnoreUnknown(t *testing.T) {
+	conf := &cmdConf{}
+	paths, err := (&CmdSource{Args: []string{"-test.v", "-port=81", "--help", "-v", "pos"}}).Load(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Port != 81 || !conf.Verbose || len(paths) != 2 {
+		t.Errorf("CmdSource 解析错误: %+v %v", conf, paths)
+	}
+}
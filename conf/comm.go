@@ -1,22 +1,27 @@
 package conf
 
-import (
-	"sync"
-)
-
-var once sync.Once
-
 type Config struct {
-	Env          string `yaml:"env" json:"env" env:"env"`
+	Env          string `yaml:"env" json:"env" env:"env" default:"dev" validate:"profile"`
 	Version      int    `yaml:"version" json:"version" env:"version"`
 	RedisName    string `yaml:"redis_name" json:"redis_name" env:"redis_name"`
-	RedisHost    string `yaml:"redis_host" json:"redis_host" env:"redis_host"`
-	RedisAuth    string `yaml:"redis_auth" json:"redis_auth" env:"redis_auth"`
+	RedisHost    string `yaml:"redis_host" json:"redis_host" env:"redis_host" validate:"hostport"`
+	RedisAuth    string `yaml:"redis_auth" json:"redis_auth" env:"redis_auth" secret:"true"`
 	MysqlName    string `yaml:"mysql_name" json:"mysql_name" env:"mysql_name"`
-	MysqlHost    string `yaml:"mysql_host" json:"mysql_host" env:"mysql_host"`
+	MysqlHost    string `yaml:"mysql_host" json:"mysql_host" env:"mysql_host" validate:"hostport"`
 	MysqlUser    string `yaml:"mysql_user" json:"mysql_user" env:"mysql_user"`
-	MysqlPass    string `yaml:"mysql_pass" json:"mysql_pass" env:"mysql_pass"`
+	MysqlPass    string `yaml:"mysql_pass" json:"mysql_pass" env:"mysql_pass" secret:"true"`
 	MysqlDb      string `yaml:"mysql_db" json:"mysql_db" env:"mysql_db"`
-	MysqlCharSet string `yaml:"mysql_charset" json:"mysql_charset" env:"mysql_charset"`
+	MysqlCharSet string `yaml:"mysql_charset" json:"mysql_charset" env:"mysql_charset" default:"utf8mb4"`
 	LogFileName  string `yaml:"log_file_name" json:"log_file_name" env:"log_file_name"`
 }
+
+// String 输出配置，密码等敏感字段被屏蔽
+func (c Config) String() string {
+	return Dump(&c)
+}
+
+// Profile 配置中的运行环境，没有注册的环境视为 dev
+func (c Config) Profile() Profile {
+	p, _ := ParseProfile(c.Env)
+	return p
+}
@@ -1,65 +1,130 @@
 package conf
 
 import (
+	"errors"
 	"fmt"
-	"github.com/Chairou/toolbox/util/structtool"
-	"github.com/jinzhu/copier"
 	"log"
-	"os"
+	"reflect"
+	"strings"
+	"sync"
 )
 
-func mergeConfig[T any](conf, cmd, file, env *T) {
-	_ = copier.CopyWithOption(conf, file, copier.Option{IgnoreEmpty: true})
-	_ = copier.CopyWithOption(conf, env, copier.Option{IgnoreEmpty: true})
-	_ = copier.CopyWithOption(conf, cmd, copier.Option{IgnoreEmpty: true})
-	fmt.Printf("FINIAL: %#v\n", conf)
+// Loader 按来源链加载配置，后面的来源优先级更高
+type Loader[T any] struct {
+	sources    []Source
+	secretKey  SecretKey
+	provenance ProvenanceReport
 }
 
-// LoadAllConf 优先级，命令行，配置文件，环境变量
-func LoadAllConf[T any](conf *T) {
-	cmdConfig := structtool.NewEmptyInstance(conf)
-	fileConfig := structtool.NewEmptyInstance(conf)
-	envConfig := structtool.NewEmptyInstance(conf)
-	once.Do(func() {
-		//读取命令行参数
-		err := LoadConfFromCmd(cmdConfig)
-		if err != nil {
-			log.Println("cmdConfig err: ", err)
-		}
-		fmt.Printf("cmdConfig: %+v\n", cmdConfig)
-		//读取配置文件
-		envStr := os.Getenv("env")
-		if envStr == "" {
-			log.Println("env is empty , set to dev.yaml")
-			envStr = "dev"
-		} else {
-			log.Println("set conf to ", envStr+".yaml")
-		}
-		err = loadConfFromFile(envStr+".yaml", fileConfig)
+// NewLoader 创建配置加载器，sources 按优先级从低到高排列。解密 ENC(...) 的密钥从环境变量读取，见 SecretKeyFromEnv
+func NewLoader[T any](sources ...Source) *Loader[T] {
+	return &Loader[T]{sources: sources, secretKey: SecretKeyFromEnv()}
+}
+
+// WithSecretKey 设置解密 ENC(...) 使用的密钥
+func (l *Loader[T]) WithSecretKey(key SecretKey) *Loader[T] {
+	l.secretKey = key
+	return l
+}
+
+// DefaultSources 默认来源链，优先级从低到高：分层配置文件 base、<env>、local，.env 文件，环境变量，命令行参数。
+// env 为空时使用 CurrentProfile，环境变量 env 设置了未注册的环境时记录日志并使用 dev
+func DefaultSources(env string) []Source {
+	if env == "" {
+		p, err := CurrentProfile()
 		if err != nil {
-			log.Println("confFile err: ", err)
+			log.Printf("conf: %v, use profile %s", err, p)
 		}
-		fmt.Printf("fileConfig: %+v\n", fileConfig)
+		env = string(p)
+	}
+	return []Source{
+		&LayeredSource{Env: env},
+		&FileSource{Path: ".env", Optional: true},
+		&EnvSource{},
+		&CmdSource{},
+	}
+}
 
-		// 读取环境变量
-		err = loadConfFromEnv(envConfig)
+// Load 依次加载每个来源，来源设置过的字段覆盖低优先级来源的值（包括零值），
+// 没有任何来源设置的字段保持 conf 原有的值，仍为零值时使用 default 标签。
+// 合并后解密 ENC(...) 形式的值，最后按 validate 标签校验。某个来源出错时继续加载其它来源，返回全部错误
+func (l *Loader[T]) Load(conf *T) error {
+	provenance := make(ProvenanceReport)
+	var errs []error
+	for _, src := range l.sources {
+		tmp := new(T)
+		paths, err := src.Load(tmp)
 		if err != nil {
-			log.Println("envConfig err: ", err)
+			errs = append(errs, fmt.Errorf("%s: %w", src.Name(), err))
 		}
-		fmt.Printf("env: %+v\n", envConfig)
-		//合并配置
-		mergeConfig(conf, cmdConfig, fileConfig, envConfig)
+		origin, _ := src.(originSource)
+		for _, path := range paths {
+			copyFieldPath(reflect.ValueOf(conf), reflect.ValueOf(tmp), strings.Split(path, "."))
+			provenance[path] = src.Name()
+			if origin != nil {
+				if name := origin.fieldOrigin(path); name != "" {
+					provenance[path] = name
+				}
+			}
+		}
+	}
+	if err := decryptSecrets(conf, l.secretKey); err != nil {
+		errs = append(errs, err)
+	}
+	if err := applyDefaults(conf, provenance); err != nil {
+		errs = append(errs, err)
+	}
+	if err := Validate(conf); err != nil {
+		errs = append(errs, err)
+	}
+	l.provenance = provenance
+	return errors.Join(errs...)
+}
+
+// Provenance 最近一次 Load 中每个字段的来源
+func (l *Loader[T]) Provenance() ProvenanceReport {
+	return l.provenance
+}
 
-	})
+var (
+	lastProvenanceMu sync.RWMutex
+	lastProvenance   ProvenanceReport
+)
+
+// LoadAllConf 使用 DefaultSources 加载配置，优先级：命令行 > 环境变量 > .env > 配置文件 > default 标签。
+// 每次调用都会重新加载，加载和校验错误只记录日志；各字段的来源可以通过 Provenance 查看。
+// 需要在配置无效时退出的服务使用 LoadConf
+func LoadAllConf[T any](conf *T) {
+	loader := NewLoader[T](DefaultSources("")...)
+	if err := loader.Load(conf); err != nil {
+		log.Println("LoadAllConf err: ", err)
+	}
+	lastProvenanceMu.Lock()
+	lastProvenance = loader.Provenance()
+	lastProvenanceMu.Unlock()
 }
 
-func createStruct[T any]() *T {
-	return new(T)
+// Provenance 最近一次 LoadAllConf 中每个字段的来源
+func Provenance() ProvenanceReport {
+	lastProvenanceMu.RLock()
+	defer lastProvenanceMu.RUnlock()
+	return lastProvenance
 }
 
+// LoadConf 严格加载配置：来源链和 LoadAllConf 相同，任何来源出错或校验不通过时立即返回全部错误，
+// 新配置在副本上加载，失败时不会写回 config。环境变量 env 不是已注册的环境时直接返回错误
 func LoadConf[T any](config *T) error {
-	// 先处理YAML文件， 保底处理方式
-	// 然后处理e
-
+	if _, err := CurrentProfile(); err != nil {
+		return err
+	}
+	loader := NewLoader[T](DefaultSources("")...)
+	tmp := *config
+	if err := loader.Load(&tmp); err != nil {
+		return err
+	}
+	*config = tmp
+	lastProvenanceMu.Lock()
+	lastProvenance = loader.Provenance()
+	lastProvenanceMu.Unlock()
 	return nil
 }
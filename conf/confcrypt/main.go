@@ -0,0 +1,153 @@
+// confcrypt 加密配置文件中的敏感值，以及更换配置文件的加密密钥。
+//
+//	confcrypt encrypt -pub public.pem 'my password'         输出 ENC(ecc:...)
+//	confcrypt encrypt -passphrase xxx 'my password'         输出 ENC(aesgcm:...)
+//	confcrypt decrypt -key private.pem -key-pass xxx 'ENC(...)'
+//	confcrypt rotate -key old.pem -new-pub new.pem -in dev.yaml [-out dev.yaml]
+//	confcrypt genkey -out ./keys [-key-pass xxx]
+//
+// 没有指定的密钥参数从环境变量读取，见 conf.SecretKeyFromEnv
+package main
+
+import (
+	"crypto/elliptic"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Chairou/toolbox/conf"
+	"github.com/Chairou/toolbox/util/crypt/ecc"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "encrypt":
+		err = runEncrypt(os.Args[2:])
+	case "decrypt":
+		err = runDecrypt(os.Args[2:])
+	case "rotate":
+		err = runRotate(os.Args[2:])
+	case "genkey":
+		err = runGenKey(os.Args[2:])
+	case "-h", "--help", "help":
+		usage()
+		return
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "confcrypt:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: confcrypt encrypt|decrypt|rotate|genkey [flags] [value]")
+}
+
+// keyFlags 注册密钥参数，prefix 用于 rotate 的新密钥参数
+func keyFlags(fs *flag.FlagSet, prefix string, key *conf.SecretKey) {
+	fs.StringVar(&key.PrivateKeyFile, prefix+"key", key.PrivateKeyFile, "ECC private key file")
+	fs.StringVar(&key.PrivateKeyPass, prefix+"key-pass", key.PrivateKeyPass, "ECC private key password")
+	fs.StringVar(&key.PublicKeyFile, prefix+"pub", key.PublicKeyFile, "ECC public key file")
+	fs.StringVar(&key.Passphrase, prefix+"passphrase", key.Passphrase, "AES passphrase")
+}
+
+func runEncrypt(args []string) error {
+	fs := flag.NewFlagSet("encrypt", flag.ExitOnError)
+	key := conf.SecretKeyFromEnv()
+	keyFlags(fs, "", &key)
+	_ = fs.Parse(args)
+	for _, value := range fs.Args() {
+		enc, err := key.Encrypt(value)
+		if err != nil {
+			return err
+		}
+		fmt.Println(enc)
+	}
+	return nil
+}
+
+func runDecrypt(args []string) error {
+	fs := flag.NewFlagSet("decrypt", flag.ExitOnError)
+	key := conf.SecretKeyFromEnv()
+	keyFlags(fs, "", &key)
+	_ = fs.Parse(args)
+	for _, value := range fs.Args() {
+		plain, err := key.Decrypt(value)
+		if err != nil {
+			return err
+		}
+		fmt.Println(plain)
+	}
+	return nil
+}
+
+func runRotate(args []string) error {
+	fs := flag.NewFlagSet("rotate", flag.ExitOnError)
+	oldKey := conf.SecretKeyFromEnv()
+	var newKey conf.SecretKey
+	keyFlags(fs, "", &oldKey)
+	keyFlags(fs, "new-", &newKey)
+	in := fs.String("in", "", "config file to rotate")
+	out := fs.String("out", "", "output file, default overwrite -in")
+	_ = fs.Parse(args)
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+	if *out == "" {
+		*out = *in
+	}
+	data, err := os.ReadFile(*in)
+	if err != nil {
+		return err
+	}
+	rotated, n, err := conf.RotateSecrets(data, oldKey, newKey)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(*in)
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(*out, rotated, info.Mode().Perm()); err != nil {
+		return err
+	}
+	fmt.Printf("rotated %d secrets in %s\n", n, *out)
+	return nil
+}
+
+func runGenKey(args []string) error {
+	fs := flag.NewFlagSet("genkey", flag.ExitOnError)
+	dir := fs.String("out", ".", "output directory")
+	pass := fs.String("key-pass", "", "encrypt the private key with this password")
+	_ = fs.Parse(args)
+
+	pub, priv, err := ecc.GenerateKeys(elliptic.P521())
+	if err != nil {
+		return err
+	}
+	pubPEM, err := pub.PEM()
+	if err != nil {
+		return err
+	}
+	privPEM, err := priv.PEM(*pass)
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(filepath.Join(*dir, "public.pem"), pubPEM, 0644); err != nil {
+		return err
+	}
+	if err = os.WriteFile(filepath.Join(*dir, "private.pem"), privPEM, 0600); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s and %s\n", filepath.Join(*dir, "public.pem"), filepath.Join(*dir, "private.pem"))
+	return nil
+}
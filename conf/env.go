@@ -4,58 +4,32 @@ import (
 	"fmt"
 	"os"
 	"reflect"
-	"strconv"
+	"strings"
 )
 
-func loadConfFromEnv[T any](config T) error {
-	val := reflect.ValueOf(config).Elem()
-	t := val.Type()
-
-	for i := 0; i < val.NumField(); i++ {
-		field := t.Field(i)
-		envValue := os.Getenv(field.Tag.Get("env"))
-		//fmt.Println("##########", field.Tag.Get("env"))
-		//fmt.Println("##########", envValue)
-		if envValue == "" {
-			continue
+// loadConfFromEnv 从环境变量加载配置。顶层字段有 env 标签时使用标签作为变量名，
+// 否则使用大写下划线形式，嵌套结构体的变量名为 父名_子名，例如 MYSQL_HOST。
+// 切片使用逗号分隔，map 使用 k=v 逗号分隔，time.Duration 使用 1s、500ms 这样的格式。
+// 设置为空字符串的变量（FOO=）同样视为已设置，字段为零值，返回设置过的字段路径。
+func loadConfFromEnv[T any](config T) ([]string, error) {
+	var paths []string
+	err := walkConfigFields(reflect.ValueOf(config), func(f configField) error {
+		envValue, ok := os.LookupEnv(f.Env)
+		if !ok {
+			return nil
 		}
-
-		fieldValue := val.Field(i)
-		if !fieldValue.CanSet() {
-			return fmt.Errorf("cannot set field %s", field.Name)
+		// 已经有值的字段不再从环境变量读取
+		if cur := f.peek(); cur.IsValid() && !cur.IsZero() {
+			return nil
 		}
-
-		switch fieldValue.Kind() {
-
-		case reflect.Float64:
-			if fieldValue.Float() != 0 {
-				continue
-			}
-			floatVal, err := strconv.ParseFloat(envValue, 64)
-			if err != nil {
-				return fmt.Errorf("invalid float64 value for field %s: %s", field.Name, envValue)
-			}
-			fieldValue.SetFloat(floatVal)
-
-		case reflect.Int:
-			if fieldValue.Int() != 0 {
-				continue
-			}
-			intVal, err := strconv.Atoi(envValue)
-			if err != nil {
-				return fmt.Errorf("invalid int value for field %s: %s", field.Name, envValue)
-			}
-			fieldValue.SetInt(int64(intVal))
-
-		case reflect.String:
-			if fieldValue.String() != "" {
-				continue
-			}
-			fieldValue.SetString(envValue)
-
-		default:
-			return fmt.Errorf("unsupported kind: %s", fieldValue.Kind())
+		if envValue == "" {
+			v := f.value()
+			v.Set(reflect.Zero(v.Type()))
+		} else if err := setFieldFromString(f.value(), envValue); err != nil {
+			return fmt.Errorf("invalid value for field %s from env %s: %v", f.Field.Name, f.Env, err)
 		}
-	}
-	return nil
+		paths = append(paths, strings.Join(f.Path, "."))
+		return nil
+	})
+	return paths, err
 }
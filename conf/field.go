@@ -0,0 +1,320 @@
+package conf
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// configField 配置结构体中的一个叶子字段
+type configField struct {
+	// Path 从根结构体到该字段的 Go 字段名
+	Path []string
+	// Flag 命令行参数名，小写短横线形式，嵌套结构体用点分隔：mysql.max-conn；可以用 flag 标签指定
+	Flag string
+	// Env 环境变量名：顶层字段有 env 标签时直接使用标签，否则为大写下划线 MYSQL_HOST
+	Env   string
+	Field reflect.StructField
+	// value 返回可设置的字段值，路径上的 nil 指针会被创建
+	value func() reflect.Value
+	// peek 返回字段当前值，路径上有 nil 指针时返回无效值
+	peek func() reflect.Value
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// walkConfigFields 遍历结构体的全部叶子字段，嵌套结构体（含指针）会展开，
+// time.Time 和实现了 encoding.TextUnmarshaler 的类型视为叶子字段。
+// 自引用的类型（例如 type Node struct{ Next *Node }）在路径上再次出现时跳过该字段
+func walkConfigFields(root reflect.Value, fn func(f configField) error) error {
+	for root.Kind() == reflect.Ptr {
+		root = root.Elem()
+	}
+	if root.Kind() != reflect.Struct {
+		return fmt.Errorf("config must be a struct pointer, got %s", root.Kind())
+	}
+	get := func() reflect.Value { return root }
+	return walkStruct(root.Type(), get, get, nil, "", "", map[reflect.Type]bool{}, fn)
+}
+
+// walkStruct 展开结构体 t，visiting 为当前路径上正在展开的结构体类型
+func walkStruct(t reflect.Type, get, peek func() reflect.Value, path []string, flagPrefix, envPrefix string,
+	visiting map[reflect.Type]bool, fn func(f configField) error) error {
+	visiting[t] = true
+	defer delete(visiting, t)
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		index := i
+		fieldPath := append(append([]string{}, path...), sf.Name)
+		flagName := sf.Tag.Get("flag")
+		if flagName == "-" {
+			continue
+		}
+		if flagName == "" {
+			flagName = kebabCase(sf.Name)
+		}
+		if flagPrefix != "" {
+			flagName = flagPrefix + "." + flagName
+		}
+		envTag := sf.Tag.Get("env")
+		if envTag == "-" {
+			continue
+		}
+		envName := envTag
+		if envName == "" {
+			envName = upperSnake(sf.Name)
+		}
+		if envPrefix != "" {
+			envName = envPrefix + "_" + strings.ToUpper(envName)
+		} else if envTag == "" {
+			envName = strings.ToUpper(envName)
+		}
+
+		fieldGet := func() reflect.Value { return get().Field(index) }
+		fieldPeek := func() reflect.Value {
+			v := peek()
+			if !v.IsValid() {
+				return v
+			}
+			return v.Field(index)
+		}
+
+		ft := sf.Type
+		if isNestedStruct(ft) {
+			if visiting[ft] || ft.Kind() == reflect.Ptr && visiting[ft.Elem()] {
+				continue
+			}
+			if ft.Kind() == reflect.Ptr {
+				elemGet := func() reflect.Value {
+					v := fieldGet()
+					if v.IsNil() {
+						v.Set(reflect.New(ft.Elem()))
+					}
+					return v.Elem()
+				}
+				elemPeek := func() reflect.Value {
+					v := fieldPeek()
+					if !v.IsValid() || v.IsNil() {
+						return reflect.Value{}
+					}
+					return v.Elem()
+				}
+				if err := walkStruct(ft.Elem(), elemGet, elemPeek, fieldPath, flagName, strings.ToUpper(envName), visiting, fn); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := walkStruct(ft, fieldGet, fieldPeek, fieldPath, flagName, strings.ToUpper(envName), visiting, fn); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := fn(configField{
+			Path:  fieldPath,
+			Flag:  flagName,
+			Env:   envName,
+			Field: sf,
+			value: fieldGet,
+			peek:  fieldPeek,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isNestedStruct 结构体或结构体指针，且不是 time.Time、TextUnmarshaler 这类按字符串解析的类型
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType {
+		return false
+	}
+	return !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// upperSnake MysqlHost -> MYSQL_HOST，HTTPPort -> HTTP_PORT
+func upperSnake(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prevLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// setFieldFromString 把字符串解析为字段类型并赋值：
+// 全部标量类型、time.Duration、TextUnmarshaler、指针、逗号分隔的切片/数组、k=v 逗号分隔的 map
+func setFieldFromString(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setFieldFromString(v.Elem(), s)
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(strings.TrimSpace(s))
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(s))
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(s), 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(strings.TrimSpace(s), 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Complex64, reflect.Complex128:
+		c, err := strconv.ParseComplex(strings.TrimSpace(s), v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetComplex(c)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes([]byte(s))
+			return nil
+		}
+		items := splitList(s)
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setFieldFromString(slice.Index(i), item); err != nil {
+				return fmt.Errorf("item %d: %v", i, err)
+			}
+		}
+		v.Set(slice)
+	case reflect.Array:
+		items := splitList(s)
+		if len(items) > v.Len() {
+			return fmt.Errorf("too many items: %d > %d", len(items), v.Len())
+		}
+		for i, item := range items {
+			if err := setFieldFromString(v.Index(i), item); err != nil {
+				return fmt.Errorf("item %d: %v", i, err)
+			}
+		}
+	case reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		for _, item := range splitList(s) {
+			kv := strings.SplitN(item, "=", 2)
+			if len(kv) != 2 {
+				return fmt.Errorf("invalid map item %q, want key=value", item)
+			}
+			key := reflect.New(v.Type().Key()).Elem()
+			if err := setFieldFromString(key, strings.TrimSpace(kv[0])); err != nil {
+				return fmt.Errorf("map key %q: %v", kv[0], err)
+			}
+			val := reflect.New(v.Type().Elem()).Elem()
+			if err := setFieldFromString(val, strings.TrimSpace(kv[1])); err != nil {
+				return fmt.Errorf("map value %q: %v", kv[1], err)
+			}
+			m.SetMapIndex(key, val)
+		}
+		v.Set(m)
+	default:
+		return fmt.Errorf("unsupported kind: %s", v.Kind())
+	}
+	return nil
+}
+
+// splitList 按逗号分隔并去掉空白，空字符串返回空列表
+func splitList(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return []string{}
+	}
+	parts := strings.Split(s, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return parts
+}
+
+// fieldToString 字段值转为 setFieldFromString 可以解析的字符串，用于命令行默认值展示
+func fieldToString(v reflect.Value) string {
+	if !v.IsValid() {
+		return ""
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		b, err := m.MarshalText()
+		if err == nil {
+			return string(b)
+		}
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes())
+		}
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = fieldToString(v.Index(i))
+		}
+		return strings.Join(items, ",")
+	case reflect.Map:
+		items := make([]string, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			items = append(items, fieldToString(iter.Key())+"="+fieldToString(iter.Value()))
+		}
+		sort.Strings(items)
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,165 @@
+package conf
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type mysqlConf struct {
+	Host    string
+	User    string
+	Timeout time.Duration
+}
+
+type serviceConf struct {
+	Name    string `env:"service_name"`
+	Debug   bool
+	Port    uint16
+	Ratio   float32
+	Hosts   []string
+	Ports   []int
+	Labels  map[string]string
+	Mysql   mysqlConf
+	Redis   *mysqlConf
+	MaxConn *int
+	IP      net.IP
+	Skip    string `env:"-"`
+}
+
+func TestWalkConfigFields_Names(t *testing.T) {
+	want := map[string]string{
+		"name":          "service_name",
+		"debug":         "DEBUG",
+		"mysql.host":    "MYSQL_HOST",
+		"mysql.timeout": "MYSQL_TIMEOUT",
+		"redis.user":    "REDIS_USER",
+		"max-conn":      "MAX_CONN",
+		"ip":            "IP",
+	}
+	got := map[string]string{}
+	conf := &serviceConf{}
+	err := walkConfigFields(reflect.ValueOf(conf), func(f configField) error {
+		got[f.Flag] = f.Env
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("参数 %s 的环境变量名期望 %s, 实际 %s", k, v, got[k])
+		}
+	}
+	if _, ok := got["skip"]; ok {
+		t.Errorf("env:\"-\" 的字段应被忽略")
+	}
+	if conf.Redis != nil {
+		t.Errorf("遍历字段不应创建 nil 指针")
+	}
+}
+
+func TestUpperSnake(t *testing.T) {
+	cases := map[string]string{"MysqlHost": "MYSQL_HOST", "HTTPPort": "HTTP_PORT", "IP": "IP", "Db2Name": "DB2_NAME"}
+	for in, want := range cases {
+		if got := upperSnake(in); got != want {
+			t.Errorf("upperSnake(%s) 期望 %s, 实际 %s", in, want, got)
+		}
+	}
+}
+
+func TestLoadConfFromEnv_Nested(t *testing.T) {
+	t.Setenv("service_name", "order")
+	t.Setenv("DEBUG", "true")
+	t.Setenv("PORT", "8080")
+	t.Setenv("RATIO", "0.5")
+	t.Setenv("HOSTS", "a.com, b.com")
+	t.Setenv("PORTS", "1,2,3")
+	t.Setenv("LABELS", "team=infra,zone=sz")
+	t.Setenv("MYSQL_HOST", "127.0.0.1:3306")
+	t.Setenv("MYSQL_TIMEOUT", "1m30s")
+	t.Setenv("REDIS_USER", "root")
+	t.Setenv("MAX_CONN", "10")
+	t.Setenv("IP", "10.0.0.1")
+
+	conf := &serviceConf{}
+	if _, err := loadConfFromEnv(conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Name != "order" || !conf.Debug || conf.Port != 8080 || conf.Ratio != 0.5 {
+		t.Errorf("标量字段解析错误: %+v", conf)
+	}
+	if !reflect.DeepEqual(conf.Hosts, []string{"a.com", "b.com"}) || !reflect.DeepEqual(conf.Ports, []int{1, 2, 3}) {
+		t.Errorf("切片解析错误: %v %v", conf.Hosts, conf.Ports)
+	}
+	if conf.Labels["team"] != "infra" || conf.Labels["zone"] != "sz" {
+		t.Errorf("map 解析错误: %v", conf.Labels)
+	}
+	if conf.Mysql.Host != "127.0.0.1:3306" || conf.Mysql.Timeout != 90*time.Second {
+		t.Errorf("嵌套结构体解析错误: %+v", conf.Mysql)
+	}
+	if conf.Redis == nil || conf.Redis.User != "root" {
+		t.Errorf("嵌套指针结构体解析错误: %+v", conf.Redis)
+	}
+	if conf.MaxConn == nil || *conf.MaxConn != 10 {
+		t.Errorf("指针字段解析错误")
+	}
+	if conf.IP.String() != "10.0.0.1" {
+		t.Errorf("TextUnmarshaler 字段解析错误: %v", conf.IP)
+	}
+}
+
+func TestLoadConfFromEnv_InvalidValue(t *testing.T) {
+	t.Setenv("PORT", "70000")
+	conf := &serviceConf{}
+	if _, err := loadConfFromEnv(conf); err == nil {
+		t.Errorf("超出 uint16 范围应返回错误")
+	}
+}
+
+func TestRegisterConfigFlags(t *testing.T) {
+	conf := &serviceConf{Name: "preset"}
+	fs, _, err := newConfigFlagSet("test", conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = fs.Parse([]string{
+		"--mysql.host", "db:3306", "--debug", "--Port=9000",
+		"--hosts", "a,b", "--hosts", "c", "--mysql.timeout", "2s", "--redis.user", "admin",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Mysql.Host != "db:3306" || !conf.Debug || conf.Port != 9000 || conf.Mysql.Timeout != 2*time.Second {
+		t.Errorf("命令行解析错误: %+v", conf)
+	}
+	if !reflect.DeepEqual(conf.Hosts, []string{"a", "b", "c"}) {
+		t.Errorf("重复的切片参数应追加: %v", conf.Hosts)
+	}
+	if conf.Redis == nil || conf.Redis.User != "admin" {
+		t.Errorf("嵌套指针结构体解析错误")
+	}
+	if f := fs.Lookup("name"); f == nil || f.DefValue != "preset" {
+		t.Errorf("已有值的字段也应注册命令行参数, 并以当前值作为默认值")
+	}
+}
+
+func TestWalkConfigFields_Recursive(t *testing.T) {
+	type node struct {
+		Name string
+		Next *node
+	}
+	var flags []string
+	conf := &node{}
+	err := walkConfigFields(reflect.ValueOf(conf), func(f configField) error {
+		flags = append(flags, f.Flag)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(flags, []string{"name"}) {
+		t.Errorf("自引用的字段应跳过: %v", flags)
+	}
+}
@@ -1,30 +1,433 @@
 package conf
 
 import (
-	"io"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 
+	"github.com/pelletier/go-toml/v2"
 	"gopkg.in/yaml.v2"
 )
 
-func loadConfFromFile[T any](fileName string, config T) error {
-	fd, err := os.OpenFile(fileName, os.O_RDONLY, 0666)
+// includeKey 配置文件中的 include 指令，值为文件路径或路径列表，相对路径相对于当前文件所在目录，
+// 支持通配符。被包含的文件先加载，当前文件中的配置覆盖被包含文件中的配置
+const includeKey = "include"
+
+// configExts 支持的配置文件扩展名，按查找顺序排列
+var configExts = []string{".yaml", ".yml", ".json", ".toml", ".env"}
+
+// envLeaf .env 文件中的值，字符串按字段类型解析，解码时单独处理
+type envLeaf struct {
+	path  string
+	value string
+}
+
+// fileLayer 一个配置文件规范化后的内容
+type fileLayer struct {
+	path string
+	// tree 以 yaml 字段名为 key，可以直接交给 yaml 解码
+	tree  map[interface{}]interface{}
+	paths []string
+}
+
+// loadConfFiles 按顺序加载配置文件并深度合并后写入 config，后面的文件覆盖前面的文件。
+// 返回每个字段来自哪个文件，以及实际读取的全部文件（包含 include 的文件）
+func loadConfFiles(config any, paths []string) (map[string]string, []string, error) {
+	t := reflect.TypeOf(config)
+	var layers []fileLayer
+	for _, path := range paths {
+		ls, err := expandIncludes(t, path, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		layers = append(layers, ls...)
+	}
+
+	origins := make(map[string]string)
+	files := make([]string, 0, len(layers))
+	merged := make(map[interface{}]interface{})
+	for _, l := range layers {
+		deepMerge(merged, l.tree)
+		for _, p := range l.paths {
+			origins[p] = l.path
+		}
+		files = append(files, l.path)
+	}
+	if err := decodeTree(config, merged); err != nil {
+		return nil, nil, err
+	}
+	return origins, files, nil
+}
+
+// expandIncludes 读取文件，返回它 include 的文件和它自己，stack 用于检测循环包含
+func expandIncludes(t reflect.Type, path string, stack []string) ([]fileLayer, error) {
+	abs, err := filepath.Abs(path)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	for _, p := range stack {
+		if p == abs {
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(append(stack, abs), " -> "))
+		}
+	}
+	stack = append(stack, abs)
+
+	raw, err := readConfigFile(t, path)
+	if err != nil {
+		return nil, err
 	}
-	defer func(fd *os.File) {
-		err := fd.Close()
+	var layers []fileLayer
+	if inc, ok := raw[includeKey]; ok {
+		delete(raw, includeKey)
+		includes, err := includePaths(filepath.Dir(path), inc)
 		if err != nil {
-			panic("close file err:" + err.Error())
+			return nil, fmt.Errorf("%s: %v", path, err)
 		}
-	}(fd)
-	dataBytes, err := io.ReadAll(fd)
+		for _, p := range includes {
+			ls, err := expandIncludes(t, p, stack)
+			if err != nil {
+				return nil, err
+			}
+			layers = append(layers, ls...)
+		}
+	}
+	tree, paths := normalizeTree(t, raw, "")
+	return append(layers, fileLayer{path: path, tree: tree, paths: paths}), nil
+}
+
+// includePaths 解析 include 的值，通配符没有匹配到文件时报错
+func includePaths(dir string, inc any) ([]string, error) {
+	var patterns []string
+	switch v := inc.(type) {
+	case string:
+		patterns = []string{v}
+	case []any:
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid include %v", item)
+			}
+			patterns = append(patterns, s)
+		}
+	default:
+		return nil, fmt.Errorf("invalid include %v", inc)
+	}
+	var paths []string
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+		if !strings.ContainsAny(pattern, "*?[") {
+			paths = append(paths, pattern)
+			continue
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("include %s matches no file", pattern)
+		}
+		paths = append(paths, matches...)
+	}
+	return paths, nil
+}
+
+// readConfigFile 按扩展名解析配置文件，key 统一为字符串
+func readConfigFile(t reflect.Type, path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := parseConfigData(t, data, filepath.Ext(path))
+	if err != nil {
+		return nil, fmt.Errorf("parse %s err: %v", path, err)
+	}
+	return raw, nil
+}
+
+// parseConfigData 按格式解析配置内容，ext 为 .yaml、.yml、.json、.toml、.env 之一
+func parseConfigData(t reflect.Type, data []byte, ext string) (map[string]any, error) {
+	raw := make(map[string]any)
+	var err error
+	switch strings.ToLower(ext) {
+	case ".yaml", ".yml":
+		var m map[interface{}]interface{}
+		err = yaml.Unmarshal(data, &m)
+		if err == nil && m != nil {
+			raw = stringKeys(m).(map[string]any)
+		}
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var m map[string]any
+		err = dec.Decode(&m)
+		if err == nil && m != nil {
+			raw = stringKeys(m).(map[string]any)
+		}
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	case ".env":
+		raw, err = dotEnvTree(t, data)
+	default:
+		return nil, fmt.Errorf("unsupported config format %q", ext)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// stringKeys 把 yaml 解析出的 map[interface{}]interface{} 转为 map[string]any，
+// json.Number 转为 int64 或 float64
+func stringKeys(v any) any {
+	switch x := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]any, len(x))
+		for k, item := range x {
+			m[fmt.Sprint(k)] = stringKeys(item)
+		}
+		return m
+	case map[string]any:
+		for k, item := range x {
+			x[k] = stringKeys(item)
+		}
+		return x
+	case []any:
+		for i, item := range x {
+			x[i] = stringKeys(item)
+		}
+		return x
+	case json.Number:
+		if n, err := x.Int64(); err == nil {
+			return n
+		}
+		f, _ := x.Float64()
+		return f
+	}
+	return v
+}
+
+// dotEnvTree 解析 .env 文件，变量名规则和 loadConfFromEnv 相同，
+// 返回以 Go 字段名为 key 的树，叶子为 envLeaf
+func dotEnvTree(t reflect.Type, data []byte) (map[string]any, error) {
+	vars, err := parseDotEnv(data)
+	if err != nil {
+		return nil, err
+	}
+	tree := make(map[string]any)
+	err = walkConfigFields(reflect.New(derefType(t)), func(f configField) error {
+		value, ok := vars[f.Env]
+		if !ok {
+			return nil
+		}
+		node := tree
+		for _, name := range f.Path[:len(f.Path)-1] {
+			sub, ok := node[name].(map[string]any)
+			if !ok {
+				sub = make(map[string]any)
+				node[name] = sub
+			}
+			node = sub
+		}
+		node[f.Path[len(f.Path)-1]] = envLeaf{path: strings.Join(f.Path, "."), value: value}
+		return nil
+	})
+	return tree, err
+}
+
+// parseDotEnv 解析 KEY=VALUE 格式，支持 # 注释、export 前缀和引号
+func parseDotEnv(data []byte) (map[string]string, error) {
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+	for scanner.Scan() {
+		line++
+		s := strings.TrimSpace(scanner.Text())
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		s = strings.TrimPrefix(s, "export ")
+		key, value, ok := strings.Cut(s, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: missing =", line)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			if value[0] == '"' {
+				unquoted, err := strconv.Unquote(value)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %v", line, err)
+				}
+				value = unquoted
+			} else {
+				value = value[1 : len(value)-1]
+			}
+		} else if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		vars[key] = value
+	}
+	return vars, scanner.Err()
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// normalizeTree 把配置文件的 key 规范为 yaml 字段名并返回设置过的字段路径。
+// key 不区分大小写，可以是 yaml、json、toml 标签名或 Go 字段名，不认识的 key 被忽略
+func normalizeTree(t reflect.Type, raw map[string]any, prefix string) (map[interface{}]interface{}, []string) {
+	t = derefType(t)
+	tree := make(map[interface{}]interface{})
+	var paths []string
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, inline := yamlFieldName(sf)
+		if name == "-" {
+			continue
+		}
+		if inline {
+			sub, subPaths := normalizeTree(sf.Type, raw, prefix)
+			for k, v := range sub {
+				tree[k] = v
+			}
+			paths = append(paths, subPaths...)
+			continue
+		}
+		v, ok := lookupKey(raw, sf, name)
+		if !ok {
+			continue
+		}
+		path := sf.Name
+		if prefix != "" {
+			path = prefix + "." + sf.Name
+		}
+		if sub, isMap := v.(map[string]any); isMap && isNestedStruct(sf.Type) {
+			subTree, subPaths := normalizeTree(sf.Type, sub, path)
+			tree[name] = subTree
+			paths = append(paths, subPaths...)
+			continue
+		}
+		tree[name] = yamlValue(v)
+		paths = append(paths, path)
+	}
+	return tree, paths
+}
+
+// lookupKey 按 yaml 名、json/toml 标签名、Go 字段名查找 key，不区分大小写
+func lookupKey(raw map[string]any, sf reflect.StructField, yamlName string) (any, bool) {
+	names := []string{yamlName, sf.Name}
+	for _, tag := range []string{"json", "toml"} {
+		if n, _, _ := strings.Cut(sf.Tag.Get(tag), ","); n != "" && n != "-" {
+			names = append(names, n)
+		}
+	}
+	for _, n := range names {
+		if v, ok := raw[n]; ok {
+			return v, true
+		}
+	}
+	for k, v := range raw {
+		for _, n := range names {
+			if strings.EqualFold(k, n) {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// yamlValue 把 map[string]any 转回 yaml 使用的 map[interface{}]interface{}
+func yamlValue(v any) any {
+	switch x := v.(type) {
+	case map[string]any:
+		m := make(map[interface{}]interface{}, len(x))
+		for k, item := range x {
+			m[k] = yamlValue(item)
+		}
+		return m
+	case []any:
+		s := make([]any, len(x))
+		for i, item := range x {
+			s[i] = yamlValue(item)
+		}
+		return s
+	}
+	return v
+}
+
+// deepMerge 把 src 合并到 dst，两边都是 map 时递归合并，否则 src 覆盖 dst
+func deepMerge(dst, src map[interface{}]interface{}) {
+	for k, v := range src {
+		if sm, ok := v.(map[interface{}]interface{}); ok {
+			if dm, ok := dst[k].(map[interface{}]interface{}); ok {
+				deepMerge(dm, sm)
+				continue
+			}
+			cp := make(map[interface{}]interface{}, len(sm))
+			deepMerge(cp, sm)
+			dst[k] = cp
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+// decodeTree 把合并后的树写入 config，.env 中的值按字段类型解析
+func decodeTree(config any, tree map[interface{}]interface{}) error {
+	leaves := make(map[string]string)
+	extractEnvLeaves(tree, leaves)
+	data, err := yaml.Marshal(tree)
 	if err != nil {
 		return err
 	}
-	err = yaml.Unmarshal(dataBytes, config)
+	if err = yaml.Unmarshal(data, config); err != nil {
+		return err
+	}
+	if len(leaves) == 0 {
+		return nil
+	}
+	var errs []error
+	err = walkConfigFields(reflect.ValueOf(config), func(f configField) error {
+		path := strings.Join(f.Path, ".")
+		value, ok := leaves[path]
+		if !ok {
+			return nil
+		}
+		if err := setFieldFromString(f.value(), value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid value for field %s from env %s: %v", path, f.Env, err))
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
-	return nil
+	return errors.Join(errs...)
+}
+
+func extractEnvLeaves(tree map[interface{}]interface{}, leaves map[string]string) {
+	for k, v := range tree {
+		switch x := v.(type) {
+		case envLeaf:
+			leaves[x.path] = x.value
+			delete(tree, k)
+		case map[interface{}]interface{}:
+			extractEnvLeaves(x, leaves)
+		}
+	}
 }
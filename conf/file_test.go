@@ -0,0 +1,118 @@
+package conf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fileConf struct {
+	Name    string        `yaml:"name" json:"name"`
+	Port    int           `yaml:"port" json:"port"`
+	Timeout time.Duration `yaml:"timeout"`
+	Hosts   []string      `yaml:"hosts"`
+	Mysql   struct {
+		Host string `yaml:"host"`
+		User string `yaml:"user"`
+	} `yaml:"mysql" json:"mysql"`
+	Redis struct {
+		Host string `yaml:"host"`
+	} `yaml:"redis" json:"redis"`
+}
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestFileSource_Formats(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.json": `{"name": "json", "port": 8080, "hosts": ["a", "b"], "mysql": {"host": "db:3306"}}`,
+		"a.toml": "Name = \"toml\"\nport = 9090\ntimeout = \"2s\"\n[mysql]\nhost = \"db:3307\"\n",
+		"a.env":  "# comment\nNAME=\"dot env\"\nexport PORT=7070\nHOSTS=x, y\nMYSQL_HOST=db:3308 # inline\n",
+	})
+	cases := map[string]func(c *fileConf) bool{
+		"a.json": func(c *fileConf) bool {
+			return c.Name == "json" && c.Port == 8080 && len(c.Hosts) == 2 && c.Mysql.Host == "db:3306"
+		},
+		"a.toml": func(c *fileConf) bool {
+			return c.Name == "toml" && c.Port == 9090 && c.Timeout == 2*time.Second && c.Mysql.Host == "db:3307"
+		},
+		"a.env": func(c *fileConf) bool {
+			return c.Name == "dot env" && c.Port == 7070 && strings.Join(c.Hosts, ",") == "x,y" && c.Mysql.Host == "db:3308"
+		},
+	}
+	for name, check := range cases {
+		conf := &fileConf{}
+		src := &FileSource{Path: filepath.Join(dir, name)}
+		paths, err := src.Load(conf)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !check(conf) {
+			t.Errorf("%s 解析错误: %+v", name, conf)
+		}
+		if len(paths) == 0 {
+			t.Errorf("%s 应返回设置过的字段", name)
+		}
+	}
+}
+
+func TestLayeredSource(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"conf/base.yaml":         "include: shared/*.yaml\nname: base\nport: 80\nmysql:\n  host: base-db\n  user: base-user\n",
+		"conf/shared/redis.yaml": "redis:\n  host: redis:6379\n",
+		"conf/shared/mysql.yaml": "mysql:\n  host: shared-db\n  user: shared-user\n",
+		"conf/test.json":         `{"port": 81, "mysql": {"host": "test-db"}}`,
+		"local.toml":             "name = \"local\"\n",
+		"conf/local.yaml":        "name: ignored\n",
+	})
+	src := &LayeredSource{Env: "test", SearchPaths: []string{dir, filepath.Join(dir, "conf")}}
+	loader := NewLoader[fileConf](src)
+	conf := &fileConf{}
+	if err := loader.Load(conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Name != "local" || conf.Port != 81 || conf.Mysql.Host != "test-db" || conf.Mysql.User != "base-user" ||
+		conf.Redis.Host != "redis:6379" {
+		t.Errorf("分层合并错误: %+v", conf)
+	}
+	p := loader.Provenance()
+	want := map[string]string{
+		"Name":       "file:" + filepath.Join(dir, "local.toml"),
+		"Port":       "file:" + filepath.Join(dir, "conf", "test.json"),
+		"Mysql.User": "file:" + filepath.Join(dir, "conf", "base.yaml"),
+		"Redis.Host": "file:" + filepath.Join(dir, "conf", "shared", "redis.yaml"),
+	}
+	for k, v := range want {
+		if p[k] != v {
+			t.Errorf("%s 的来源期望 %s, 实际 %s", k, v, p[k])
+		}
+	}
+	if len(src.loaded()) != 5 {
+		t.Errorf("应读取 5 个文件: %v", src.loaded())
+	}
+}
+
+func TestFileSource_IncludeCycle(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.yaml": "include: b.yaml\nname: a\n",
+		"b.yaml": "include: [a.yaml]\nport: 1\n",
+	})
+	_, err := (&FileSource{Path: filepath.Join(dir, "a.yaml")}).Load(&fileConf{})
+	if err == nil || !strings.Contains(err.Error(), "include cycle") {
+		t.Errorf("循环 include 应返回错误: %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package conf
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Profile 运行环境，决定加载哪一层配置文件以及 gin、logger 的默认行为。
+// 内置 dev、test、release，其它环境用 RegisterProfile 注册
+type Profile string
+
+const (
+	ProfileDev     Profile = "dev"
+	ProfileTest    Profile = "test"
+	ProfileRelease Profile = "release"
+)
+
+// ProfileEnvKey 指定当前环境的环境变量名
+const ProfileEnvKey = "env"
+
+// ProfileDefaults 与环境相关的默认值，conf、gin、logger 统一从这里读取
+type ProfileDefaults struct {
+	// GinMode gin 运行模式：debug、test、release
+	GinMode string
+	// LogLevel 日志级别：trace、debug、info、warn、error、fatal
+	LogLevel string
+	// LogConsole 日志是否同时输出到控制台
+	LogConsole bool
+}
+
+var profileMap sync.Map
+
+func init() {
+	profileMap.Store(ProfileDev, ProfileDefaults{GinMode: "debug", LogLevel: "debug", LogConsole: true})
+	profileMap.Store(ProfileTest, ProfileDefaults{GinMode: "test", LogLevel: "debug"})
+	profileMap.Store(ProfileRelease, ProfileDefaults{GinMode: "release", LogLevel: "info"})
+}
+
+// RegisterProfile 注册环境或修改内置环境的默认值，例如增加 staging
+func RegisterProfile(p Profile, d ProfileDefaults) error {
+	if p == "" || strings.ToLower(string(p)) != string(p) {
+		return fmt.Errorf("conf: invalid profile name %q, must be lower case", p)
+	}
+	switch d.GinMode {
+	case "debug", "test", "release":
+	default:
+		return fmt.Errorf("conf: invalid gin mode %q for profile %s", d.GinMode, p)
+	}
+	switch d.LogLevel {
+	case "trace", "debug", "info", "warn", "error", "fatal":
+	default:
+		return fmt.Errorf("conf: invalid log level %q for profile %s", d.LogLevel, p)
+	}
+	profileMap.Store(p, d)
+	return nil
+}
+
+// Profiles 全部已注册的环境，按名字排序
+func Profiles() []Profile {
+	var list []Profile
+	profileMap.Range(func(key, _ any) bool {
+		list = append(list, key.(Profile))
+		return true
+	})
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list
+}
+
+// ParseProfile 解析环境名，不区分大小写，没有注册的环境返回错误
+func ParseProfile(s string) (Profile, error) {
+	p := Profile(strings.ToLower(strings.TrimSpace(s)))
+	if !p.Valid() {
+		return ProfileDev, fmt.Errorf("conf: unknown profile %q, want one of %v", s, Profiles())
+	}
+	return p, nil
+}
+
+// CurrentProfile 从环境变量 env 读取当前环境，没有设置时为 dev；
+// 设置了没有注册的环境时返回 dev 和错误，由调用方决定是否继续
+func CurrentProfile() (Profile, error) {
+	s := os.Getenv(ProfileEnvKey)
+	if s == "" {
+		return ProfileDev, nil
+	}
+	return ParseProfile(s)
+}
+
+// Valid 环境是否已注册
+func (p Profile) Valid() bool {
+	_, ok := profileMap.Load(p)
+	return ok
+}
+
+// Defaults 环境的默认值，没有注册的环境使用 dev 的默认值
+func (p Profile) Defaults() ProfileDefaults {
+	if d, ok := profileMap.Load(p); ok {
+		return d.(ProfileDefaults)
+	}
+	d, _ := profileMap.Load(ProfileDev)
+	return d.(ProfileDefaults)
+}
+
+// IsRelease 是否为生产环境
+func (p Profile) IsRelease() bool {
+	return p == ProfileRelease
+}
+
+func (p Profile) String() string {
+	return string(p)
+}
@@ -0,0 +1,146 @@
+package conf
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProfile(t *testing.T) {
+	t.Setenv(ProfileEnvKey, "")
+	if p, err := CurrentProfile(); p != ProfileDev || err != nil {
+		t.Errorf("没有设置环境变量时应为 dev: %s %v", p, err)
+	}
+	t.Setenv(ProfileEnvKey, "Release")
+	p, err := CurrentProfile()
+	if p != ProfileRelease || err != nil || !p.IsRelease() {
+		t.Errorf("环境名不区分大小写: %s %v", p, err)
+	}
+	if d := p.Defaults(); d.LogLevel != "info" || d.LogConsole || d.GinMode != "release" {
+		t.Errorf("release 默认值错误: %+v", d)
+	}
+	t.Setenv(ProfileEnvKey, "prod")
+	if p, err = CurrentProfile(); p != ProfileDev || err == nil {
+		t.Errorf("未注册的环境应返回 dev 和错误: %s %v", p, err)
+	}
+	if err = LoadConf(&Config{}); err == nil {
+		t.Errorf("未注册的环境 LoadConf 应返回错误")
+	}
+
+	if err = RegisterProfile("staging", ProfileDefaults{GinMode: "prod", LogLevel: "info"}); err == nil {
+		t.Errorf("无效的 gin 模式应返回错误")
+	}
+	if err = RegisterProfile("staging", ProfileDefaults{GinMode: "release", LogLevel: "info"}); err != nil {
+		t.Fatal(err)
+	}
+	if p, err = ParseProfile("staging"); err != nil || p.Defaults().GinMode != "release" {
+		t.Errorf("注册的环境应可以使用: %s %v", p, err)
+	}
+	if err = Validate(&Config{Env: "staging"}); err != nil {
+		t.Errorf("注册的环境应通过校验: %v", err)
+	}
+	if err = Validate(&Config{Env: "prod"}); err == nil {
+		t.Errorf("未注册的环境应校验失败")
+	}
+	if (Config{Env: "staging"}).Profile() != "staging" || (Config{}).Profile() != ProfileDev {
+		t.Errorf("Config.Profile 错误")
+	}
+}
+
+type schemaConf struct {
+	Env     string        `yaml:"env" default:"dev" validate:"profile"`
+	Mode    string        `yaml:"mode" validate:"oneof=a|b"`
+	Port    int           `yaml:"port" default:"8080" validate:"min=1,max=65535" usage:"监听端口"`
+	Timeout time.Duration `yaml:"timeout" default:"3s"`
+	Tags    []string      `yaml:"tags" validate:"max=3"`
+	Labels  map[string]int
+	Mysql   struct {
+		Host     string `yaml:"host" validate:"required,hostport"`
+		Password string `yaml:"password"`
+	} `yaml:"mysql"`
+	Redis *struct {
+		Host string `yaml:"host" validate:"required"`
+	} `yaml:"redis"`
+}
+
+func TestSchema(t *testing.T) {
+	s := Schema[schemaConf]()
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err = json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["$schema"] != JSONSchemaDraft || doc["title"] != "schemaConf" {
+		t.Errorf("缺少 $schema 或 title: %s", b)
+	}
+	props := s.Properties
+	if p := props["port"]; p.Type != "integer" || p.Default != 8080 || *p.Minimum != 1 || *p.Maximum != 65535 || p.Description != "监听端口" {
+		t.Errorf("port 错误: %+v", p)
+	}
+	if p := props["timeout"]; p.Type != "string" || p.Default != "3s" || p.Pattern == "" {
+		t.Errorf("timeout 错误: %+v", p)
+	}
+	if p := props["mode"]; !reflect.DeepEqual(p.Enum, []any{"a", "b"}) {
+		t.Errorf("mode 错误: %+v", p)
+	}
+	if p := props["env"]; len(p.Enum) < 3 || p.Default != "dev" {
+		t.Errorf("env 应列出已注册的环境: %+v", p)
+	}
+	if p := props["tags"]; p.Type != "array" || p.Items.Type != "string" || *p.MaxItems != 3 {
+		t.Errorf("tags 错误: %+v", p)
+	}
+	if p := props["labels"]; p.Type != "object" || p.AdditionalProperties.Type != "integer" {
+		t.Errorf("labels 错误: %+v", p)
+	}
+	mysql := props["mysql"]
+	if !reflect.DeepEqual(mysql.Required, []string{"host"}) || mysql.Properties["host"].Pattern == "" || !mysql.Properties["password"].WriteOnly {
+		t.Errorf("mysql 错误: %+v", mysql)
+	}
+	if !reflect.DeepEqual(s.Required, []string{"mysql"}) {
+		t.Errorf("只有非指针的嵌套结构体随必填字段必填: %v", s.Required)
+	}
+	if props["redis"].Properties["host"] == nil {
+		t.Errorf("指针结构体应展开: %+v", props["redis"])
+	}
+}
+
+// treeConf 自引用的配置类型
+type treeConf struct {
+	Name     string      `yaml:"name"`
+	Next     *treeConf   `yaml:"next"`
+	Children []*treeConf `yaml:"children"`
+	Root     *treeRoot   `yaml:"root"`
+}
+
+type treeRoot struct {
+	Tree *treeConf `yaml:"tree"`
+}
+
+func TestSchema_Recursive(t *testing.T) {
+	s := Schema[treeConf]()
+	if _, err := json.Marshal(s); err != nil {
+		t.Fatal(err)
+	}
+	if p := s.Properties["next"]; p.Ref != "#" || p.Type != "" {
+		t.Errorf("自引用字段应输出 $ref: %+v", p)
+	}
+	if p := s.Properties["children"]; p.Type != "array" || p.Items.Ref != "#" {
+		t.Errorf("自引用切片应输出 $ref: %+v", p)
+	}
+	root := s.Properties["root"]
+	if p := root.Properties["tree"]; p.Ref != "#" {
+		t.Errorf("间接自引用应输出 $ref: %+v", p)
+	}
+	// 同一个类型在不同分支出现时各自展开
+	s = Schema[struct {
+		A treeRoot `yaml:"a"`
+		B treeRoot `yaml:"b"`
+	}]()
+	if p := s.Properties["b"].Properties["tree"].Properties["next"]; p.Ref != "#/properties/b/properties/tree" {
+		t.Errorf("$ref 应指向最近的同类型 schema: %+v", p)
+	}
+}
@@ -0,0 +1,286 @@
+package conf
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Chairou/toolbox/util/redis"
+)
+
+// RemoteStore 远程配置存储，*redis.RdPool 直接满足该接口。Get、HGet 在 key 不存在时需返回 redis.ErrNil
+type RemoteStore interface {
+	Get(key string) (string, error)
+	HGet(key string, subKey string) (string, error)
+	HSet(key string, subKey string, val string) (int64, error)
+	HIncrBy(key, field string, increment int64) (int64, error)
+	Publish(channel string, message string) (int64, error)
+	Subscribe(ctx context.Context, channel string, fn func(message string)) error
+}
+
+// 带版本的远程配置保存在 hash 中：current 为当前版本，latest 为最大版本，v<N> 为各版本的内容
+const (
+	remoteFieldCurrent  = "current"
+	remoteFieldLatest   = "latest"
+	remoteVersionPrefix = "v"
+)
+
+// remoteRetryMax 订阅断开后重试的最大间隔
+const remoteRetryMax = 30 * time.Second
+
+// RedisSource redis 远程配置来源，配置内容为一整段 YAML 或 JSON。
+// 默认读取 Publish 写入的带版本的 hash，Plain 为 true 时直接读取字符串 key。
+// 一般放在来源链的最前面，被本地文件和环境变量覆盖，见 DefaultSourcesWithRemote。
+// 设置 CacheFile 后每次读取成功都会写入缓存文件，redis 不可用时从缓存文件加载
+type RedisSource struct {
+	Store RemoteStore
+	Key   string
+	// Plain 为 true 时使用 GET Key 读取，没有版本
+	Plain bool
+	// Format 配置格式 yaml 或 json，默认 yaml
+	Format string
+	// Channel 变化通知的 pub/sub 频道，默认与 Key 相同
+	Channel string
+	// PollInterval 大于 0 时 Watcher 还会定期检查版本，弥补丢失的通知
+	PollInterval time.Duration
+	// CacheFile 本地缓存文件
+	CacheFile string
+
+	mu        sync.Mutex
+	version   int64
+	data      string
+	fromCache bool
+}
+
+// remoteCache 缓存文件内容
+type remoteCache struct {
+	Key     string `json:"key"`
+	Version int64  `json:"version"`
+	Data    string `json:"data"`
+}
+
+// Name 来源名字
+func (s *RedisSource) Name() string {
+	return "redis:" + s.Key
+}
+
+// Version 最近一次加载的版本，Plain 模式或没有远程配置时为 0
+func (s *RedisSource) Version() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.version
+}
+
+// FromCache 最近一次加载是否来自本地缓存文件
+func (s *RedisSource) FromCache() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fromCache
+}
+
+// Load 读取远程配置，key 不存在时视为没有远程配置
+func (s *RedisSource) Load(config any) ([]string, error) {
+	data, version, err := s.fetch()
+	fromCache := false
+	switch {
+	case errors.Is(err, redis.ErrNil):
+		s.record("", 0, false)
+		return nil, nil
+	case err != nil:
+		if s.CacheFile == "" {
+			return nil, err
+		}
+		cache, cacheErr := s.readCache()
+		if cacheErr != nil {
+			return nil, errors.Join(err, cacheErr)
+		}
+		log.Printf("conf: redis %s unavailable, use cache %s version %d: %v", s.Key, s.CacheFile, cache.Version, err)
+		data, version, fromCache = cache.Data, cache.Version, true
+	case s.CacheFile != "":
+		if err = s.writeCache(data, version); err != nil {
+			log.Println("conf: write remote config cache err: ", err)
+		}
+	}
+
+	raw, err := parseConfigData(reflect.TypeOf(config), []byte(data), "."+s.format())
+	if err != nil {
+		return nil, fmt.Errorf("parse %s version %d err: %v", s.Name(), version, err)
+	}
+	delete(raw, includeKey)
+	tree, paths := normalizeTree(reflect.TypeOf(config), raw, "")
+	if err = decodeTree(config, tree); err != nil {
+		return nil, err
+	}
+	s.record(data, version, fromCache)
+	return paths, nil
+}
+
+func (s *RedisSource) record(data string, version int64, fromCache bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data, s.version, s.fromCache = data, version, fromCache
+}
+
+func (s *RedisSource) format() string {
+	if s.Format == "" {
+		return "yaml"
+	}
+	return s.Format
+}
+
+func (s *RedisSource) channel() string {
+	if s.Channel == "" {
+		return s.Key
+	}
+	return s.Channel
+}
+
+// fetch 读取当前版本的配置内容
+func (s *RedisSource) fetch() (string, int64, error) {
+	if s.Plain {
+		data, err := s.Store.Get(s.Key)
+		return data, 0, err
+	}
+	version, err := s.Current()
+	if err != nil {
+		return "", 0, err
+	}
+	data, err := s.Store.HGet(s.Key, remoteVersionPrefix+strconv.FormatInt(version, 10))
+	return data, version, err
+}
+
+// Current 远程当前版本
+func (s *RedisSource) Current() (int64, error) {
+	cur, err := s.Store.HGet(s.Key, remoteFieldCurrent)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(cur, 10, 64)
+}
+
+// Publish 发布新版本的配置并通知订阅者，返回新版本号。旧版本保留，可以用 Rollback 回滚
+func (s *RedisSource) Publish(data string) (int64, error) {
+	if s.Plain {
+		return 0, errors.New("conf: plain redis source has no versions")
+	}
+	version, err := s.Store.HIncrBy(s.Key, remoteFieldLatest, 1)
+	if err != nil {
+		return 0, err
+	}
+	if _, err = s.Store.HSet(s.Key, remoteVersionPrefix+strconv.FormatInt(version, 10), data); err != nil {
+		return 0, err
+	}
+	return version, s.activate(version)
+}
+
+// Rollback 把当前版本切换为 version 并通知订阅者
+func (s *RedisSource) Rollback(version int64) error {
+	if s.Plain {
+		return errors.New("conf: plain redis source has no versions")
+	}
+	if _, err := s.Store.HGet(s.Key, remoteVersionPrefix+strconv.FormatInt(version, 10)); err != nil {
+		if errors.Is(err, redis.ErrNil) {
+			return fmt.Errorf("conf: version %d of %s not found", version, s.Key)
+		}
+		return err
+	}
+	return s.activate(version)
+}
+
+func (s *RedisSource) activate(version int64) error {
+	v := strconv.FormatInt(version, 10)
+	if _, err := s.Store.HSet(s.Key, remoteFieldCurrent, v); err != nil {
+		return err
+	}
+	_, err := s.Store.Publish(s.channel(), v)
+	return err
+}
+
+func (s *RedisSource) readCache() (*remoteCache, error) {
+	b, err := os.ReadFile(s.CacheFile)
+	if err != nil {
+		return nil, err
+	}
+	cache := &remoteCache{}
+	if err = json.Unmarshal(b, cache); err != nil {
+		return nil, fmt.Errorf("parse cache %s err: %v", s.CacheFile, err)
+	}
+	if cache.Key != s.Key {
+		return nil, fmt.Errorf("cache %s is for key %s", s.CacheFile, cache.Key)
+	}
+	return cache, nil
+}
+
+// writeCache 先写临时文件再重命名，避免进程退出时留下不完整的缓存
+func (s *RedisSource) writeCache(data string, version int64) error {
+	b, err := json.Marshal(&remoteCache{Key: s.Key, Version: version, Data: data})
+	if err != nil {
+		return err
+	}
+	tmp := s.CacheFile + ".tmp"
+	if err = os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.CacheFile)
+}
+
+// watchChanges 订阅变化通知，断线后按指数退避重连，重连后补发一次通知；
+// PollInterval 大于 0 时同时轮询
+func (s *RedisSource) watchChanges(ctx context.Context, notify func()) {
+	if s.PollInterval > 0 {
+		go s.poll(ctx, notify)
+	}
+	retry := time.Second
+	for {
+		start := time.Now()
+		err := s.Store.Subscribe(ctx, s.channel(), func(string) { notify() })
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(start) > remoteRetryMax {
+			retry = time.Second
+		}
+		log.Printf("conf: subscribe %s err: %v, retry in %s", s.channel(), err, retry)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+		retry = min(retry*2, remoteRetryMax)
+		notify()
+	}
+}
+
+func (s *RedisSource) poll(ctx context.Context, notify func()) {
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			data, version, err := s.fetch()
+			if err != nil && !errors.Is(err, redis.ErrNil) {
+				continue
+			}
+			s.mu.Lock()
+			changed := data != s.data || version != s.version || s.fromCache
+			s.mu.Unlock()
+			if changed {
+				notify()
+			}
+		}
+	}
+}
+
+// DefaultSourcesWithRemote 远程配置加上 DefaultSources，优先级从低到高：远程配置、本地文件、.env、环境变量、命令行参数
+func DefaultSourcesWithRemote(env string, remote Source) []Source {
+	return append([]Source{remote}, DefaultSources(env)...)
+}
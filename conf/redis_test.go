@@ -0,0 +1,204 @@
+package conf
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/Chairou/toolbox/util/redis"
+)
+
+// memRemoteStore 内存版的 RemoteStore，down 为 true 时模拟 redis 不可用
+type memRemoteStore struct {
+	mu     sync.Mutex
+	kv     map[string]string
+	hash   map[string]map[string]string
+	subs   map[string][]func(string)
+	down   bool
+	subbed chan struct{}
+}
+
+var errRedisDown = errors.New("dial tcp: connection refused")
+
+func newMemRemoteStore() *memRemoteStore {
+	return &memRemoteStore{
+		kv:     map[string]string{},
+		hash:   map[string]map[string]string{},
+		subs:   map[string][]func(string){},
+		subbed: make(chan struct{}, 1),
+	}
+}
+
+func (m *memRemoteStore) Get(key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.down {
+		return "", errRedisDown
+	}
+	v, ok := m.kv[key]
+	if !ok {
+		return "", redis.ErrNil
+	}
+	return v, nil
+}
+
+func (m *memRemoteStore) HGet(key, field string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.down {
+		return "", errRedisDown
+	}
+	v, ok := m.hash[key][field]
+	if !ok {
+		return "", redis.ErrNil
+	}
+	return v, nil
+}
+
+func (m *memRemoteStore) HSet(key, field, val string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.hash[key] == nil {
+		m.hash[key] = map[string]string{}
+	}
+	m.hash[key][field] = val
+	return 1, nil
+}
+
+func (m *memRemoteStore) HIncrBy(key, field string, increment int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.hash[key] == nil {
+		m.hash[key] = map[string]string{}
+	}
+	n, _ := strconv.ParseInt(m.hash[key][field], 10, 64)
+	n += increment
+	m.hash[key][field] = strconv.FormatInt(n, 10)
+	return n, nil
+}
+
+func (m *memRemoteStore) Publish(channel, message string) (int64, error) {
+	m.mu.Lock()
+	subs := append([]func(string){}, m.subs[channel]...)
+	m.mu.Unlock()
+	for _, fn := range subs {
+		fn(message)
+	}
+	return int64(len(subs)), nil
+}
+
+func (m *memRemoteStore) Subscribe(ctx context.Context, channel string, fn func(string)) error {
+	m.mu.Lock()
+	m.subs[channel] = append(m.subs[channel], fn)
+	m.mu.Unlock()
+	m.subbed <- struct{}{}
+	<-ctx.Done()
+	return nil
+}
+
+func TestRedisSource_Versions(t *testing.T) {
+	store := newMemRemoteStore()
+	src := &RedisSource{Store: store, Key: "conf:order"}
+
+	conf := &fileConf{}
+	if paths, err := src.Load(conf); err != nil || paths != nil {
+		t.Fatalf("没有远程配置时应跳过: %v %v", paths, err)
+	}
+
+	if v, err := src.Publish("name: v1\nport: 80\n"); err != nil || v != 1 {
+		t.Fatalf("发布失败: %d %v", v, err)
+	}
+	if v, _ := src.Publish("name: v2\nport: 81\n"); v != 2 {
+		t.Fatalf("版本号应递增: %d", v)
+	}
+	conf = &fileConf{}
+	if _, err := src.Load(conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Name != "v2" || src.Version() != 2 {
+		t.Errorf("应加载当前版本: %+v %d", conf, src.Version())
+	}
+
+	if err := src.Rollback(1); err != nil {
+		t.Fatal(err)
+	}
+	conf = &fileConf{}
+	if _, err := src.Load(conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Name != "v1" || conf.Port != 80 || src.Version() != 1 {
+		t.Errorf("回滚后应加载版本 1: %+v", conf)
+	}
+	if err := src.Rollback(9); err == nil {
+		t.Errorf("回滚到不存在的版本应返回错误")
+	}
+}
+
+func TestRedisSource_LayeringAndCache(t *testing.T) {
+	store := newMemRemoteStore()
+	cache := filepath.Join(t.TempDir(), "remote.json")
+	remote := &RedisSource{Store: store, Key: "conf:json", Format: "json", CacheFile: cache}
+	if _, err := remote.Publish(`{"name": "remote", "port": 80, "mysql": {"host": "remote-db"}}`); err != nil {
+		t.Fatal(err)
+	}
+	path := writeSourceFile(t, "port: 8080\n")
+	t.Setenv("MYSQL_HOST", "env-db")
+
+	load := func() (*fileConf, ProvenanceReport) {
+		loader := NewLoader[fileConf](remote, &FileSource{Path: path}, &EnvSource{})
+		conf := &fileConf{}
+		if err := loader.Load(conf); err != nil {
+			t.Fatal(err)
+		}
+		return conf, loader.Provenance()
+	}
+	conf, p := load()
+	if conf.Name != "remote" || conf.Port != 8080 || conf.Mysql.Host != "env-db" {
+		t.Errorf("远程配置应被本地文件和环境变量覆盖: %+v", conf)
+	}
+	if p["Name"] != "redis:conf:json" {
+		t.Errorf("来源错误: %v", p)
+	}
+
+	store.mu.Lock()
+	store.down = true
+	store.mu.Unlock()
+	conf, _ = load()
+	if conf.Name != "remote" || !remote.FromCache() || remote.Version() != 1 {
+		t.Errorf("redis 不可用时应使用缓存: %+v", conf)
+	}
+
+	other := &RedisSource{Store: store, Key: "conf:other", CacheFile: cache}
+	if _, err := other.Load(&fileConf{}); err == nil {
+		t.Errorf("其它 key 的缓存不应被使用")
+	}
+}
+
+func TestWatcher_RemoteChange(t *testing.T) {
+	store := newMemRemoteStore()
+	src := &RedisSource{Store: store, Key: "conf:watch"}
+	if _, err := src.Publish("name: v1\n"); err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWatcher[fileConf](nil, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	waitFor(t, store.subbed, "应订阅变化通知")
+
+	changed := make(chan struct{}, 1)
+	w.OnChange(func(old, new *fileConf) {
+		changed <- struct{}{}
+	})
+	if _, err = src.Publish("name: v2\n"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, changed, "远程配置发布后应重新加载")
+	if w.Get().Name != "v2" {
+		t.Errorf("新配置未生效: %+v", w.Get())
+	}
+}
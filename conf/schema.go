@@ -0,0 +1,215 @@
+package conf
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// JSONSchemaDraft 导出的 schema 使用的 JSON Schema 版本
+const JSONSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// durationPattern time.Duration 字符串的格式，例如 1h30m、500ms
+const durationPattern = `^-?([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$|^0$`
+
+// JSONSchema JSON Schema 对象（常用子集）
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Enum                 []any                  `json:"enum,omitempty"`
+	Default              any                    `json:"default,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+	MinLength            *int                   `json:"minLength,omitempty"`
+	MaxLength            *int                   `json:"maxLength,omitempty"`
+	MinItems             *int                   `json:"minItems,omitempty"`
+	MaxItems             *int                   `json:"maxItems,omitempty"`
+	MinProperties        *int                   `json:"minProperties,omitempty"`
+	MaxProperties        *int                   `json:"maxProperties,omitempty"`
+	WriteOnly            bool                   `json:"writeOnly,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+}
+
+// Schema 导出配置结构体的 JSON Schema，json.Marshal 后可以交给部署工具在发布前校验 YAML 配置。
+// 属性名为配置文件中的 yaml 字段名，default、validate、usage、secret 标签分别转换为
+// default、enum/pattern/minimum 等约束、description、writeOnly。schema 描述的是合并后的完整配置，
+// 有 required 规则且没有 default 的字段列为必填。自引用的类型再次出现时输出指向第一次出现位置的 $ref
+func Schema[T any]() *JSONSchema {
+	t := derefType(reflect.TypeOf((*T)(nil)).Elem())
+	s := schemaOf(t, "#", map[reflect.Type]string{})
+	s.Schema = JSONSchemaDraft
+	s.Title = t.Name()
+	return s
+}
+
+// schemaOf 类型 t 的 schema，ptr 为该 schema 在文档中的 JSON Pointer，
+// visiting 为当前路径上正在展开的结构体类型及其 JSON Pointer
+func schemaOf(t reflect.Type, ptr string, visiting map[reflect.Type]string) *JSONSchema {
+	t = derefType(t)
+	switch {
+	case t == durationType:
+		return &JSONSchema{Type: "string", Pattern: durationPattern}
+	case t == timeType:
+		return &JSONSchema{Type: "string", Format: "date-time"}
+	case reflect.PointerTo(t).Implements(textUnmarshalerType):
+		return &JSONSchema{Type: "string"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &JSONSchema{Type: "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		zero := 0.0
+		return &JSONSchema{Type: "integer", Minimum: &zero}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}
+	case reflect.String:
+		return &JSONSchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &JSONSchema{Type: "string"}
+		}
+		return &JSONSchema{Type: "array", Items: schemaOf(t.Elem(), ptr+"/items", visiting)}
+	case reflect.Map:
+		return &JSONSchema{Type: "object", AdditionalProperties: schemaOf(t.Elem(), ptr+"/additionalProperties", visiting)}
+	case reflect.Struct:
+		if ref, ok := visiting[t]; ok {
+			return &JSONSchema{Ref: ref}
+		}
+		visiting[t] = ptr
+		defer delete(visiting, t)
+		s := &JSONSchema{Type: "object", Properties: make(map[string]*JSONSchema)}
+		addSchemaFields(s, t, ptr, visiting)
+		return s
+	}
+	// interface 等无法确定类型，空 schema 表示任意值
+	return &JSONSchema{}
+}
+
+// addSchemaFields 把结构体字段加入 properties，yaml inline 的字段展开到父结构体，
+// 自引用的 inline 字段跳过
+func addSchemaFields(s *JSONSchema, t reflect.Type, ptr string, visiting map[reflect.Type]string) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, inline := yamlFieldName(sf)
+		if name == "-" {
+			continue
+		}
+		if inline {
+			it := derefType(sf.Type)
+			if _, ok := visiting[it]; !ok {
+				visiting[it] = ptr
+				addSchemaFields(s, it, ptr, visiting)
+				delete(visiting, it)
+			}
+			continue
+		}
+		prop := schemaOf(sf.Type, ptr+"/properties/"+jsonPointerEscape(name), visiting)
+		prop.Description = sf.Tag.Get("usage")
+		prop.WriteOnly = !isNestedStruct(sf.Type) && isSecretField(sf)
+		required := applySchemaRules(prop, sf)
+		if def, ok := sf.Tag.Lookup("default"); ok {
+			prop.Default = schemaDefault(sf.Type, def)
+			required = false
+		}
+		// 非指针的嵌套结构体中有必填字段时，结构体本身也必填
+		if isNestedStruct(sf.Type) && sf.Type.Kind() != reflect.Ptr && len(prop.Required) > 0 {
+			required = true
+		}
+		if required {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = prop
+	}
+}
+
+// jsonPointerEscape 转义 JSON Pointer 中的一段
+func jsonPointerEscape(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+// applySchemaRules 把 validate 标签转换为 schema 约束，返回字段是否有 required 规则
+func applySchemaRules(s *JSONSchema, sf reflect.StructField) bool {
+	required := false
+	ft := derefType(sf.Type)
+	for _, rule := range strings.Split(sf.Tag.Get("validate"), ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "required":
+			required = true
+		case "hostport":
+			s.Pattern = `^.+:[0-9]{1,5}$`
+		case "oneof":
+			for _, opt := range strings.Split(arg, "|") {
+				s.Enum = append(s.Enum, schemaDefault(ft, opt))
+			}
+		case "profile":
+			for _, p := range Profiles() {
+				s.Enum = append(s.Enum, string(p))
+			}
+		case "min", "max":
+			limit, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				continue
+			}
+			applySchemaLimit(s, ft, name == "min", limit)
+		}
+	}
+	return required
+}
+
+// applySchemaLimit min/max 对数字是取值范围，对字符串、数组、map 是长度范围
+func applySchemaLimit(s *JSONSchema, t reflect.Type, isMin bool, limit float64) {
+	n := int(limit)
+	switch t.Kind() {
+	case reflect.String:
+		if isMin {
+			s.MinLength = &n
+		} else {
+			s.MaxLength = &n
+		}
+	case reflect.Slice, reflect.Array:
+		if isMin {
+			s.MinItems = &n
+		} else {
+			s.MaxItems = &n
+		}
+	case reflect.Map:
+		if isMin {
+			s.MinProperties = &n
+		} else {
+			s.MaxProperties = &n
+		}
+	default:
+		if isMin {
+			s.Minimum = &limit
+		} else {
+			s.Maximum = &limit
+		}
+	}
+}
+
+// schemaDefault 把标签中的字符串按字段类型转换为 JSON 值，time.Duration 等按字符串配置的类型保持字符串
+func schemaDefault(t reflect.Type, s string) any {
+	t = derefType(t)
+	if t == durationType || t == timeType || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return s
+	}
+	v := reflect.New(t).Elem()
+	if err := setFieldFromString(v, s); err != nil {
+		return s
+	}
+	return v.Interface()
+}
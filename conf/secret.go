@@ -0,0 +1,294 @@
+package conf
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Chairou/toolbox/util/crypt/ecc"
+)
+
+// 密钥相关的环境变量
+const (
+	// EnvSecretKeyFile ECC 私钥文件，用于解密
+	EnvSecretKeyFile = "CONF_SECRET_KEY_FILE"
+	// EnvSecretKeyPass ECC 私钥文件的密码
+	EnvSecretKeyPass = "CONF_SECRET_KEY_PASS"
+	// EnvSecretPubFile ECC 公钥文件，用于加密
+	EnvSecretPubFile = "CONF_SECRET_PUB_FILE"
+	// EnvSecretPassphrase AES 口令，没有配置 ECC 密钥时使用
+	EnvSecretPassphrase = "CONF_SECRET_PASSPHRASE"
+)
+
+const (
+	secretSchemeECC = "ecc"
+	// secretSchemeAESGCM 口令经 PBKDF2-SHA256 派生 AES-256 密钥，AES-GCM 加密，
+	// 密文为 base64(salt | nonce | 密文和认证标签)，salt 和 nonce 每次加密随机生成
+	secretSchemeAESGCM = "aesgcm"
+	// MaskedValue 敏感字段在配置输出中的替代值
+	MaskedValue = "******"
+)
+
+// AES-GCM 格式的参数
+const (
+	secretSaltSize  = 16
+	secretKDFRounds = 600000
+)
+
+// encPattern 匹配 ENC(...)，括号内为 ecc:密文、aesgcm:密文 或不带前缀的密文
+var encPattern = regexp.MustCompile(`ENC\(([^()\s]*)\)`)
+
+// ErrNoSecretKey 配置中有加密值，但没有配置密钥
+var ErrNoSecretKey = errors.New("no secret key configured")
+
+// SecretKey 配置加密使用的密钥。配置了 ECC 密钥时使用 ECC，否则使用 AES 口令
+type SecretKey struct {
+	// PrivateKeyFile ECC 私钥 PEM 文件，解密使用
+	PrivateKeyFile string
+	// PrivateKeyPass 私钥文件的密码，私钥没有加密时为空
+	PrivateKeyPass string
+	// PublicKeyFile ECC 公钥 PEM 文件，加密使用
+	PublicKeyFile string
+	// Passphrase AES 口令
+	Passphrase string
+}
+
+// SecretKeyFromEnv 从环境变量读取密钥
+func SecretKeyFromEnv() SecretKey {
+	return SecretKey{
+		PrivateKeyFile: os.Getenv(EnvSecretKeyFile),
+		PrivateKeyPass: os.Getenv(EnvSecretKeyPass),
+		PublicKeyFile:  os.Getenv(EnvSecretPubFile),
+		Passphrase:     os.Getenv(EnvSecretPassphrase),
+	}
+}
+
+// IsEncrypted 是否为 ENC(...) 形式的加密值
+func IsEncrypted(value string) bool {
+	m := encPattern.FindStringIndex(value)
+	return m != nil && m[0] == 0 && m[1] == len(value)
+}
+
+// Encrypt 加密明文，返回 ENC(ecc:...) 或 ENC(aesgcm:...)。同一个明文每次加密的结果都不同
+func (k SecretKey) Encrypt(plaintext string) (string, error) {
+	switch {
+	case k.PublicKeyFile != "":
+		cipher, err := ecc.EncryptStringFromEccFile(k.PublicKeyFile, plaintext)
+		if err != nil {
+			return "", err
+		}
+		return "ENC(" + secretSchemeECC + ":" + cipher + ")", nil
+	case k.Passphrase != "":
+		cipher, err := aesGCMEncrypt(plaintext, k.Passphrase)
+		if err != nil {
+			return "", err
+		}
+		return "ENC(" + secretSchemeAESGCM + ":" + cipher + ")", nil
+	}
+	return "", ErrNoSecretKey
+}
+
+// Decrypt 解密 ENC(...)，不是加密值时原样返回
+func (k SecretKey) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	body := value[len("ENC(") : len(value)-1]
+	scheme, cipher, ok := strings.Cut(body, ":")
+	if !ok {
+		scheme, cipher = "", body
+		if k.PrivateKeyFile != "" {
+			scheme = secretSchemeECC
+		} else if k.Passphrase != "" {
+			scheme = secretSchemeAESGCM
+		}
+	}
+	switch scheme {
+	case secretSchemeECC:
+		if k.PrivateKeyFile == "" {
+			return "", ErrNoSecretKey
+		}
+		return ecc.DecryptStringFromEccFile(k.PrivateKeyFile, k.PrivateKeyPass, cipher)
+	case secretSchemeAESGCM:
+		if k.Passphrase == "" {
+			return "", ErrNoSecretKey
+		}
+		return aesGCMDecrypt(cipher, k.Passphrase)
+	case "":
+		return "", ErrNoSecretKey
+	}
+	return "", fmt.Errorf("unknown secret scheme %q", scheme)
+}
+
+// secretAEAD 用口令和 salt 派生 AES-256 密钥
+func secretAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, secretKDFRounds, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// aesGCMEncrypt 加密为 secretSchemeAESGCM 格式的密文
+func aesGCMEncrypt(plaintext, passphrase string) (string, error) {
+	salt := make([]byte, secretSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	aead, err := secretAEAD(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+	out := append(salt, nonce...)
+	out = aead.Seal(out, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+// aesGCMDecrypt 解密 secretSchemeAESGCM 格式的密文，口令错误或密文被修改时返回错误
+func aesGCMDecrypt(ciphertext, passphrase string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("aes-gcm decrypt failed: %w", err)
+	}
+	if len(data) < secretSaltSize {
+		return "", errors.New("aes-gcm decrypt failed: ciphertext too short")
+	}
+	aead, err := secretAEAD(passphrase, data[:secretSaltSize])
+	if err != nil {
+		return "", err
+	}
+	data = data[secretSaltSize:]
+	if len(data) < aead.NonceSize()+aead.Overhead() {
+		return "", errors.New("aes-gcm decrypt failed: ciphertext too short")
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("aes-gcm decrypt failed: %w", err)
+	}
+	return string(plain), nil
+}
+
+// RotateSecrets 用 newKey 重新加密 data 中全部 ENC(...) 值，其余内容保持不变，返回替换的数量
+func RotateSecrets(data []byte, oldKey, newKey SecretKey) ([]byte, int, error) {
+	var errs []error
+	count := 0
+	out := encPattern.ReplaceAllFunc(data, func(m []byte) []byte {
+		plain, err := oldKey.Decrypt(string(m))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("decrypt %s: %w", m, err))
+			return m
+		}
+		enc, err := newKey.Encrypt(plain)
+		if err != nil {
+			errs = append(errs, err)
+			return m
+		}
+		count++
+		return []byte(enc)
+	})
+	if len(errs) > 0 {
+		return nil, 0, errors.Join(errs...)
+	}
+	return out, count, nil
+}
+
+// decryptSecrets 解密配置中全部 ENC(...) 形式的字符串，包括字符串切片和 map 的值
+func decryptSecrets(config any, key SecretKey) error {
+	var errs []error
+	decrypt := func(path string, v reflect.Value) {
+		if v.Kind() != reflect.String || !IsEncrypted(v.String()) {
+			return
+		}
+		plain, err := key.Decrypt(v.String())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			return
+		}
+		v.SetString(plain)
+	}
+	err := walkConfigFields(reflect.ValueOf(config), func(f configField) error {
+		v := f.peek()
+		if !v.IsValid() {
+			return nil
+		}
+		for v.Kind() == reflect.Ptr && !v.IsNil() {
+			v = v.Elem()
+		}
+		path := strings.Join(f.Path, ".")
+		switch v.Kind() {
+		case reflect.String:
+			decrypt(path, v)
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < v.Len(); i++ {
+				decrypt(fmt.Sprintf("%s[%d]", path, i), v.Index(i))
+			}
+		case reflect.Map:
+			if v.Type().Elem().Kind() != reflect.String {
+				return nil
+			}
+			iter := v.MapRange()
+			for iter.Next() {
+				item := reflect.New(v.Type().Elem()).Elem()
+				item.Set(iter.Value())
+				decrypt(fmt.Sprintf("%s[%v]", path, iter.Key()), item)
+				v.SetMapIndex(iter.Key(), item)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+// secretNames 字段名包含这些词时视为敏感字段
+var secretNames = []string{"pass", "pwd", "secret", "token", "auth", "credential", "privatekey"}
+
+// isSecretField 有 secret:"true" 标签，或字段名像密码、口令的字段
+func isSecretField(sf reflect.StructField) bool {
+	if tag, ok := sf.Tag.Lookup("secret"); ok {
+		return tag == "true"
+	}
+	name := strings.ToLower(sf.Name)
+	for _, s := range secretNames {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Dump 按字段路径输出配置，每行一个字段，敏感字段输出为 MaskedValue，可以放心写入日志
+func Dump(config any) string {
+	var lines []string
+	_ = walkConfigFields(reflect.ValueOf(config), func(f configField) error {
+		v := f.peek()
+		value := fieldToString(v)
+		if isSecretField(f.Field) && v.IsValid() && !v.IsZero() {
+			value = MaskedValue
+		}
+		lines = append(lines, strings.Join(f.Path, ".")+" = "+value)
+		return nil
+	})
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
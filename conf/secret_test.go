@@ -0,0 +1,143 @@
+package conf
+
+import (
+	"crypto/elliptic"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Chairou/toolbox/util/crypt/ecc"
+)
+
+func eccKeyFiles(t *testing.T, pass string) SecretKey {
+	t.Helper()
+	pub, priv, err := ecc.GenerateKeys(elliptic.P521())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubPEM, err := pub.PEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+	privPEM, err := priv.PEM(pass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	key := SecretKey{
+		PublicKeyFile:  filepath.Join(dir, "public.pem"),
+		PrivateKeyFile: filepath.Join(dir, "private.pem"),
+		PrivateKeyPass: pass,
+	}
+	if err = os.WriteFile(key.PublicKeyFile, pubPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(key.PrivateKeyFile, privPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestSecretKey_EncryptDecrypt(t *testing.T) {
+	for name, key := range map[string]SecretKey{
+		"ecc":    eccKeyFiles(t, "pem-pass"),
+		"aesgcm": {Passphrase: "short"},
+	} {
+		enc, err := key.Encrypt("p@ss word")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !IsEncrypted(enc) || !strings.HasPrefix(enc, "ENC("+name+":") {
+			t.Errorf("%s: 加密结果格式错误 %s", name, enc)
+		}
+		plain, err := key.Decrypt(enc)
+		if err != nil || plain != "p@ss word" {
+			t.Errorf("%s: 解密错误 %q %v", name, plain, err)
+		}
+	}
+
+	if _, err := (SecretKey{}).Decrypt("ENC(aesgcm:abc)"); !errors.Is(err, ErrNoSecretKey) {
+		t.Errorf("没有密钥时应返回 ErrNoSecretKey: %v", err)
+	}
+	if _, err := (SecretKey{Passphrase: "x"}).Decrypt("ENC(aesgcm:bm90IGEgY2lwaGVy)"); err == nil {
+		t.Errorf("错误的密文应返回错误")
+	}
+	if v, _ := (SecretKey{}).Decrypt("plain"); v != "plain" {
+		t.Errorf("非加密值应原样返回")
+	}
+}
+
+func TestSecretKey_AESGCM(t *testing.T) {
+	key := SecretKey{Passphrase: "conf-test"}
+	a, _ := key.Encrypt("root123")
+	b, _ := key.Encrypt("root123")
+	if a == b {
+		t.Errorf("同一个明文每次加密的结果应不同: %s", a)
+	}
+	if _, err := (SecretKey{Passphrase: "other"}).Decrypt(a); err == nil {
+		t.Errorf("错误的口令应返回错误")
+	}
+	data, _ := base64.StdEncoding.DecodeString(a[len("ENC(aesgcm:") : len(a)-1])
+	data[len(data)-1] ^= 1
+	if _, err := key.Decrypt("ENC(aesgcm:" + base64.StdEncoding.EncodeToString(data) + ")"); err == nil {
+		t.Errorf("被修改的密文应返回错误")
+	}
+	// 不带前缀的密文按口令的格式解密
+	if plain, err := key.Decrypt("ENC(" + a[len("ENC(aesgcm:"):]); err != nil || plain != "root123" {
+		t.Errorf("不带前缀的密文解密错误: %q %v", plain, err)
+	}
+}
+
+func TestLoader_DecryptSecrets(t *testing.T) {
+	key := SecretKey{Passphrase: "conf-test"}
+	pass, err := key.Encrypt("root123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := writeSourceFile(t, "mysql_pass: "+pass+"\nredis_auth: plain\n")
+	t.Setenv(EnvSecretPassphrase, key.Passphrase)
+
+	conf := &Config{}
+	if err = NewLoader[Config](&FileSource{Path: path}).Load(conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.MysqlPass != "root123" || conf.RedisAuth != "plain" {
+		t.Errorf("ENC 值应被解密: %+v", conf)
+	}
+
+	dump := conf.String()
+	if strings.Contains(dump, "root123") || strings.Contains(dump, "plain") ||
+		!strings.Contains(dump, "MysqlPass = "+MaskedValue) {
+		t.Errorf("输出配置时应屏蔽敏感字段: %s", dump)
+	}
+
+	err = NewLoader[Config](&FileSource{Path: path}).WithSecretKey(SecretKey{}).Load(&Config{})
+	if !errors.Is(err, ErrNoSecretKey) {
+		t.Errorf("没有密钥时应返回错误: %v", err)
+	}
+}
+
+func TestRotateSecrets(t *testing.T) {
+	oldKey := SecretKey{Passphrase: "old"}
+	newKey := eccKeyFiles(t, "")
+	a, _ := oldKey.Encrypt("a")
+	b, _ := oldKey.Encrypt("b")
+	data := []byte("# keep comment\nmysql_pass: " + a + "\nredis_auth: " + b + "\nenv: dev\n")
+
+	out, n, err := RotateSecrets(data, oldKey, newKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || strings.Contains(string(out), "aesgcm:") || !strings.Contains(string(out), "# keep comment\n") {
+		t.Errorf("轮换结果错误: %d %s", n, out)
+	}
+	enc := encPattern.FindAllString(string(out), -1)
+	for i, want := range []string{"a", "b"} {
+		if plain, err := newKey.Decrypt(enc[i]); err != nil || plain != want {
+			t.Errorf("新密钥解密错误: %q %v", plain, err)
+		}
+	}
+}
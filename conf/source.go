@@ -0,0 +1,340 @@
+package conf
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/pflag"
+)
+
+// Source 配置来源
+type Source interface {
+	// Name 来源名字，用于 Provenance 报告
+	Name() string
+	// Load 把配置写入 config（与目标同类型的空结构体指针），返回设置过的字段路径，例如 Mysql.Host。
+	// 只有返回的字段会覆盖低优先级来源的值，因此 0、"" 这样的零值同样可以覆盖
+	Load(config any) ([]string, error)
+}
+
+// FileSource 配置文件来源，按扩展名识别 YAML、JSON、TOML 和 .env 格式，支持 include 指令。
+// 文件中出现的 key 视为已设置
+type FileSource struct {
+	Path string
+	// Optional 为 true 时文件不存在不报错
+	Optional bool
+
+	files
+}
+
+// Name 来源名字
+func (s *FileSource) Name() string {
+	return "file:" + s.Path
+}
+
+// Load 读取配置文件
+func (s *FileSource) Load(config any) ([]string, error) {
+	if s.Optional {
+		if _, err := os.Stat(s.Path); errors.Is(err, os.ErrNotExist) {
+			s.record(nil, nil)
+			return nil, nil
+		}
+	}
+	return s.load(config, []string{s.Path})
+}
+
+// watchFiles 监听的配置文件
+func (s *FileSource) watchFiles() []string {
+	return absPaths(append([]string{s.Path}, s.loaded()...))
+}
+
+// LayeredSource 分层配置文件来源，按 Layers 的顺序加载并深度合并，后面的层覆盖前面的层。
+// 每一层在 SearchPaths 中按顺序查找 <层名><扩展名>，使用第一个找到的文件，扩展名顺序见 configExts；
+// 找不到的层直接跳过
+type LayeredSource struct {
+	// Env 环境名，默认的层为 base、<Env>、local
+	Env string
+	// SearchPaths 查找目录，为空时使用 DefaultSearchPaths
+	SearchPaths []string
+	// Layers 层名，为空时使用 base、<Env>、local
+	Layers []string
+
+	files
+}
+
+// DefaultSearchPaths 默认的配置文件查找目录
+var DefaultSearchPaths = []string{".", "conf", "config"}
+
+// Name 来源名字
+func (s *LayeredSource) Name() string {
+	return "files"
+}
+
+// Load 查找并加载各层配置文件
+func (s *LayeredSource) Load(config any) ([]string, error) {
+	var paths []string
+	for _, layer := range s.layers() {
+		if path := s.find(layer); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return s.load(config, paths)
+}
+
+func (s *LayeredSource) layers() []string {
+	if len(s.Layers) > 0 {
+		return s.Layers
+	}
+	return []string{"base", s.Env, "local"}
+}
+
+func (s *LayeredSource) searchPaths() []string {
+	if len(s.SearchPaths) > 0 {
+		return s.SearchPaths
+	}
+	return DefaultSearchPaths
+}
+
+// find 查找一层的配置文件，找不到时返回空字符串
+func (s *LayeredSource) find(layer string) string {
+	if layer == "" {
+		return ""
+	}
+	for _, dir := range s.searchPaths() {
+		for _, ext := range configExts {
+			path := filepath.Join(dir, layer+ext)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// watchFiles 监听全部候选文件，新建 local.yaml 这样的文件时也会重新加载
+func (s *LayeredSource) watchFiles() []string {
+	var paths []string
+	for _, layer := range s.layers() {
+		if layer == "" {
+			continue
+		}
+		for _, dir := range s.searchPaths() {
+			for _, ext := range configExts {
+				paths = append(paths, filepath.Join(dir, layer+ext))
+			}
+		}
+	}
+	return absPaths(append(paths, s.loaded()...))
+}
+
+// files 记录文件来源最近一次加载的文件和每个字段所在的文件
+type files struct {
+	mu      sync.Mutex
+	origins map[string]string
+	read    []string
+}
+
+func (f *files) load(config any, paths []string) ([]string, error) {
+	origins, read, err := loadConfFiles(config, paths)
+	if err != nil {
+		return nil, err
+	}
+	f.record(origins, read)
+	ret := make([]string, 0, len(origins))
+	for path := range origins {
+		ret = append(ret, path)
+	}
+	return ret, nil
+}
+
+func (f *files) record(origins map[string]string, read []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.origins = origins
+	f.read = read
+}
+
+func (f *files) loaded() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.read...)
+}
+
+// fieldOrigin 字段所在的文件，用于 Provenance 报告
+func (f *files) fieldOrigin(path string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if file, ok := f.origins[path]; ok {
+		return "file:" + file
+	}
+	return ""
+}
+
+// originSource 可以报告每个字段具体来源的 Source，例如字段来自分层配置中的哪个文件
+type originSource interface {
+	fieldOrigin(path string) string
+}
+
+func absPaths(paths []string) []string {
+	ret := make([]string, len(paths))
+	for i, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			abs = p
+		}
+		ret[i] = abs
+	}
+	return ret
+}
+
+// yamlFieldName yaml.v2 的字段名规则：标签名，没有标签时为小写字段名
+func yamlFieldName(sf reflect.StructField) (string, bool) {
+	tag := sf.Tag.Get("yaml")
+	parts := strings.Split(tag, ",")
+	inline := false
+	for _, opt := range parts[1:] {
+		if opt == "inline" {
+			inline = true
+		}
+	}
+	if parts[0] != "" {
+		return parts[0], inline
+	}
+	return strings.ToLower(sf.Name), inline
+}
+
+// EnvSource 环境变量来源，变量名规则见 loadConfFromEnv
+type EnvSource struct{}
+
+// Name 来源名字
+func (s *EnvSource) Name() string {
+	return "env"
+}
+
+// Load 读取环境变量
+func (s *EnvSource) Load(config any) ([]string, error) {
+	return loadConfFromEnv(config)
+}
+
+// CmdSource 命令行参数来源，参数名规则见 LoadConfFromArgs
+type CmdSource struct {
+	// Args 命令行参数，为 nil 时使用 os.Args[1:]
+	Args []string
+}
+
+// Name 来源名字
+func (s *CmdSource) Name() string {
+	return "cmd"
+}
+
+// Load 解析命令行参数，使用独立的 FlagSet，可以重复调用。程序可能还有自己的参数，
+// 因此不认识的参数和 -h/--help 会被忽略；单横线的长参数（-port=80）同样可以识别
+func (s *CmdSource) Load(config any) ([]string, error) {
+	args := s.Args
+	if args == nil && len(os.Args) > 1 {
+		args = os.Args[1:]
+	}
+	fs, flags, err := newConfigFlagSet("conf", config)
+	if err != nil {
+		return nil, err
+	}
+	fs.SetOutput(io.Discard)
+	if err = fs.Parse(knownFlagArgs(fs, args)); err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, f := range flags {
+		if f.set {
+			paths = append(paths, strings.Join(f.field.Path, "."))
+		}
+	}
+	return paths, nil
+}
+
+// knownFlagArgs 去掉 FlagSet 中没有注册的参数，例如 go test 的 -test.v
+func knownFlagArgs(fs *pflag.FlagSet, args []string) []string {
+	var ret []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		var f *pflag.Flag
+		if !strings.HasPrefix(arg, "--") && len(name) == 1 {
+			f = fs.ShorthandLookup(name)
+		} else {
+			f = fs.Lookup(kebabPath(name))
+		}
+		if f == nil {
+			continue
+		}
+		arg = "--" + f.Name
+		if hasValue {
+			arg += "=" + value
+		}
+		ret = append(ret, arg)
+		if hasValue || f.NoOptDefVal != "" {
+			continue
+		}
+		if i+1 < len(args) {
+			i++
+			ret = append(ret, args[i])
+		}
+	}
+	return ret
+}
+
+// ProvenanceReport 每个字段由哪个来源设置，key 为字段路径，例如 Mysql.Host
+type ProvenanceReport map[string]string
+
+// String 按字段路径排序输出，每行一个字段
+func (p ProvenanceReport) String() string {
+	keys := make([]string, 0, len(p))
+	for k := range p {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteString(" <- ")
+		b.WriteString(p[k])
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// copyFieldPath 把 src 中 path 对应的字段复制到 dst，路径上的 nil 指针会被创建
+func copyFieldPath(dst, src reflect.Value, path []string) {
+	for dst.Kind() == reflect.Ptr {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		dst = dst.Elem()
+	}
+	for src.Kind() == reflect.Ptr {
+		if src.IsNil() {
+			return
+		}
+		src = src.Elem()
+	}
+	df := dst.FieldByName(path[0])
+	sf := src.FieldByName(path[0])
+	if !df.IsValid() || !sf.IsValid() {
+		return
+	}
+	if len(path) == 1 {
+		df.Set(sf)
+		return
+	}
+	copyFieldPath(df, sf, path[1:])
+}
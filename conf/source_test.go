@@ -0,0 +1,117 @@
+package conf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type sourceConf struct {
+	Name  string `yaml:"name"`
+	Port  int    `yaml:"port"`
+	Debug bool   `yaml:"debug"`
+	Mysql struct {
+		Host string `yaml:"host"`
+		User string `yaml:"user"`
+	} `yaml:"mysql"`
+}
+
+func writeSourceFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "conf.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoader_Precedence(t *testing.T) {
+	path := writeSourceFile(t, "name: file\nport: 8080\ndebug: true\nmysql:\n  host: file-db\n  user: file-user\n")
+	t.Setenv("PORT", "9090")
+	t.Setenv("MYSQL_HOST", "env-db")
+
+	conf := &sourceConf{}
+	loader := NewLoader[sourceConf](
+		&FileSource{Path: path},
+		&EnvSource{},
+		&CmdSource{Args: []string{"--mysql.host", "cmd-db", "-test.v"}},
+	)
+	if err := loader.Load(conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Name != "file" || conf.Port != 9090 || conf.Mysql.Host != "cmd-db" || conf.Mysql.User != "file-user" {
+		t.Errorf("优先级应为 cmd > env > file: %+v", conf)
+	}
+
+	p := loader.Provenance()
+	want := map[string]string{
+		"Name":       "file:" + path,
+		"Port":       "env",
+		"Mysql.Host": "cmd",
+		"Mysql.User": "file:" + path,
+	}
+	for k, v := range want {
+		if p[k] != v {
+			t.Errorf("%s 的来源期望 %s, 实际 %s", k, v, p[k])
+		}
+	}
+	if !strings.Contains(p.String(), "Mysql.Host <- cmd\n") {
+		t.Errorf("Provenance 输出错误: %s", p)
+	}
+}
+
+func TestLoader_ZeroValueOverride(t *testing.T) {
+	path := writeSourceFile(t, "name: file\nport: 8080\ndebug: true\nmysql:\n  host: file-db\n")
+	t.Setenv("DEBUG", "false")
+	t.Setenv("MYSQL_HOST", "")
+
+	conf := &sourceConf{}
+	loader := NewLoader[sourceConf](
+		&FileSource{Path: path},
+		&EnvSource{},
+		&CmdSource{Args: []string{"--port=0", "--name", ""}},
+	)
+	if err := loader.Load(conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Port != 0 || conf.Name != "" || conf.Debug || conf.Mysql.Host != "" {
+		t.Errorf("零值应能覆盖低优先级来源: %+v", conf)
+	}
+	if p := loader.Provenance(); p["Mysql.Host"] != "env" {
+		t.Errorf("设置为空的环境变量也是来源: %s", p["Mysql.Host"])
+	}
+}
+
+func TestLoader_KeepUnsetAndErrors(t *testing.T) {
+	conf := &sourceConf{Name: "preset"}
+	loader := NewLoader[sourceConf](
+		&FileSource{Path: filepath.Join(t.TempDir(), "missing.yaml"), Optional: true},
+		&FileSource{Path: filepath.Join(t.TempDir(), "required.yaml")},
+		&CmdSource{Args: []string{"--port", "abc", "--debug"}},
+	)
+	err := loader.Load(conf)
+	if err == nil {
+		t.Fatal("缺少必需的配置文件和参数格式错误应返回错误")
+	}
+	if !strings.Contains(err.Error(), "required.yaml") || !strings.Contains(err.Error(), "cmd") {
+		t.Errorf("错误应包含全部出错的来源: %v", err)
+	}
+	if conf.Name != "preset" {
+		t.Errorf("没有来源设置的字段应保持原值: %+v", conf)
+	}
+}
+
+func TestLoadAllConf_Reload(t *testing.T) {
+	conf := &Config{}
+	t.Setenv("redis_name", "first")
+	LoadAllConf(conf)
+	if conf.RedisName != "first" || Provenance()["RedisName"] != "env" {
+		t.Errorf("第一次加载错误: %+v %v", conf, Provenance())
+	}
+	t.Setenv("redis_name", "second")
+	LoadAllConf(conf)
+	if conf.RedisName != "second" {
+		t.Errorf("每次调用都应重新加载: %+v", conf)
+	}
+}
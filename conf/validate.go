@@ -0,0 +1,184 @@
+package conf
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// FieldError 单个字段的校验错误
+type FieldError struct {
+	// Path 字段路径，例如 Mysql.Host
+	Path string
+	// Rule 没有通过的规则，例如 required、oneof=dev|test|release
+	Rule string
+	Msg  string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Msg)
+}
+
+// ValidationErrors 全部字段的校验错误
+type ValidationErrors []*FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return "invalid config: " + strings.Join(msgs, "; ")
+}
+
+// applyDefaults 没有任何来源设置、且仍为零值的字段使用 default 标签的值，
+// 写入的字段在 provenance 中记为 default。路径上的指针结构体为 nil 时视为未配置，不处理
+func applyDefaults(config any, provenance ProvenanceReport) error {
+	var errs ValidationErrors
+	err := walkConfigFields(reflect.ValueOf(config), func(f configField) error {
+		def, ok := f.Field.Tag.Lookup("default")
+		if !ok {
+			return nil
+		}
+		path := strings.Join(f.Path, ".")
+		if _, set := provenance[path]; set {
+			return nil
+		}
+		cur := f.peek()
+		if !cur.IsValid() || !cur.IsZero() {
+			return nil
+		}
+		if err := setFieldFromString(f.value(), def); err != nil {
+			errs = append(errs, &FieldError{Path: path, Rule: "default", Msg: fmt.Sprintf("invalid default %q: %v", def, err)})
+			return nil
+		}
+		if provenance != nil {
+			provenance[path] = "default"
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Validate 按 validate 标签校验配置，返回全部不通过的字段。规则用逗号分隔：
+//
+//	required        不能为零值
+//	hostport        host:port 格式，端口为 0-65535
+//	oneof=a|b|c     只能是列出的值之一
+//	profile         已注册的环境名，见 RegisterProfile
+//	min=N / max=N   数字的取值范围，字符串、切片、map 的长度范围
+//
+// 除 required 外，零值字段不校验；路径上的指针结构体为 nil 时视为未配置，不校验
+func Validate(config any) error {
+	var errs ValidationErrors
+	err := walkConfigFields(reflect.ValueOf(config), func(f configField) error {
+		tag := f.Field.Tag.Get("validate")
+		if tag == "" || tag == "-" {
+			return nil
+		}
+		v := f.peek()
+		if !v.IsValid() {
+			return nil
+		}
+		path := strings.Join(f.Path, ".")
+		for _, rule := range strings.Split(tag, ",") {
+			rule = strings.TrimSpace(rule)
+			if rule == "" {
+				continue
+			}
+			if msg := checkRule(v, rule); msg != "" {
+				errs = append(errs, &FieldError{Path: path, Rule: rule, Msg: msg})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// checkRule 校验单条规则，通过时返回空字符串
+func checkRule(v reflect.Value, rule string) string {
+	name, arg, _ := strings.Cut(rule, "=")
+	if name == "required" {
+		if v.IsZero() {
+			return "is required"
+		}
+		return ""
+	}
+	if v.IsZero() {
+		return ""
+	}
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	switch name {
+	case "hostport":
+		host, port, err := net.SplitHostPort(fieldToString(v))
+		if err != nil {
+			return fmt.Sprintf("%q is not host:port", fieldToString(v))
+		}
+		if host == "" {
+			return "host is empty"
+		}
+		if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+			return fmt.Sprintf("invalid port %q", port)
+		}
+	case "oneof":
+		s := fieldToString(v)
+		for _, opt := range strings.Split(arg, "|") {
+			if s == opt {
+				return ""
+			}
+		}
+		return fmt.Sprintf("%q must be one of %s", s, arg)
+	case "profile":
+		if s := fieldToString(v); !Profile(s).Valid() {
+			return fmt.Sprintf("%q must be one of %v", s, Profiles())
+		}
+	case "min", "max":
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return fmt.Sprintf("invalid rule %s", rule)
+		}
+		n, ok := sizeOf(v)
+		if !ok {
+			return fmt.Sprintf("rule %s not supported for %s", name, v.Kind())
+		}
+		if name == "min" && n < limit {
+			return fmt.Sprintf("%v is less than %s", n, arg)
+		}
+		if name == "max" && n > limit {
+			return fmt.Sprintf("%v is greater than %s", n, arg)
+		}
+	default:
+		return fmt.Sprintf("unknown rule %s", rule)
+	}
+	return ""
+}
+
+// sizeOf 数字取值，字符串、切片、map 取长度
+func sizeOf(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), true
+	}
+	return 0, false
+}
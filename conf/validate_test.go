@@ -0,0 +1,96 @@
+package conf
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type validateConf struct {
+	Env     string        `default:"dev" validate:"oneof=dev|test|release"`
+	Host    string        `validate:"required,hostport"`
+	Workers int           `default:"4" validate:"min=1,max=64"`
+	Timeout time.Duration `default:"3s"`
+	Tags    []string      `validate:"max=2"`
+	Redis   *struct {
+		Host string `validate:"required,hostport"`
+	}
+}
+
+func TestValidate(t *testing.T) {
+	conf := &validateConf{Env: "prod", Host: "localhost", Workers: 100, Tags: []string{"a", "b", "c"}}
+	err := Validate(conf)
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("期望 ValidationErrors, 实际 %v", err)
+	}
+	got := map[string]string{}
+	for _, fe := range errs {
+		got[fe.Path] = fe.Rule
+	}
+	want := map[string]string{"Env": "oneof=dev|test|release", "Host": "hostport", "Workers": "max=64", "Tags": "max=2"}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s 期望规则 %s 不通过, 实际 %q", k, v, got[k])
+		}
+	}
+	if len(errs) != len(want) {
+		t.Errorf("错误数量期望 %d, 实际 %d: %v", len(want), len(errs), err)
+	}
+
+	conf = &validateConf{Env: "test", Host: "127.0.0.1:80", Workers: 1}
+	if err = Validate(conf); err != nil {
+		t.Errorf("有效配置不应返回错误: %v", err)
+	}
+}
+
+func TestApplyDefaults(t *testing.T) {
+	conf := &validateConf{Workers: 8}
+	provenance := ProvenanceReport{"Timeout": "env"}
+	if err := applyDefaults(conf, provenance); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Env != "dev" || conf.Workers != 8 || conf.Timeout != 0 {
+		t.Errorf("default 只应填充未设置的零值字段: %+v", conf)
+	}
+	if provenance["Env"] != "default" || provenance["Timeout"] != "env" {
+		t.Errorf("provenance 错误: %v", provenance)
+	}
+	if conf.Redis != nil {
+		t.Errorf("不应为 nil 的指针结构体创建默认值")
+	}
+}
+
+func TestLoader_DefaultsAndValidate(t *testing.T) {
+	path := writeSourceFile(t, "host: db\n")
+	conf := &validateConf{}
+	err := NewLoader[validateConf](&FileSource{Path: path}).Load(conf)
+	if err == nil {
+		t.Fatal("无效配置应返回错误")
+	}
+	if conf.Workers != 4 || conf.Timeout != 3*time.Second {
+		t.Errorf("合并后应填充默认值: %+v", conf)
+	}
+}
+
+func TestLoadConf_Strict(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("env", "test")
+	t.Setenv("mysql_host", "no-port")
+
+	conf := &Config{RedisName: "keep"}
+	if err := LoadConf(conf); err == nil {
+		t.Fatal("无效配置应返回错误")
+	}
+	if conf.MysqlHost != "" || conf.RedisName != "keep" {
+		t.Errorf("失败时不应修改配置: %+v", conf)
+	}
+
+	t.Setenv("mysql_host", "db:3306")
+	if err := LoadConf(conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.MysqlHost != "db:3306" || conf.MysqlCharSet != "utf8mb4" || conf.Env != "test" {
+		t.Errorf("加载结果错误: %+v", conf)
+	}
+}
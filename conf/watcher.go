@@ -0,0 +1,262 @@
+package conf
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce 编辑器保存文件时会产生多个事件，合并为一次重新加载
+const watchDebounce = 100 * time.Millisecond
+
+// fileWatchable 可以被 Watcher 监听的来源
+type fileWatchable interface {
+	watchFiles() []string
+}
+
+// notifySource 自己能发现变化的来源，例如远程配置；watchChanges 阻塞直到 ctx 结束
+type notifySource interface {
+	watchChanges(ctx context.Context, notify func())
+}
+
+// Watcher 监听配置文件和远程配置，发生变化时重新加载全部来源，校验通过后原子替换配置并通知订阅者。
+// 加载或校验失败时保留旧配置
+type Watcher[T any] struct {
+	loader   *Loader[T]
+	current  atomic.Pointer[T]
+	validate func(*T) error
+
+	mu      sync.Mutex
+	subs    []func(old, new *T)
+	onError func(err error)
+
+	reloadMu sync.Mutex
+	fsw      *fsnotify.Watcher
+	dirs     map[string]bool
+	files    map[string]bool
+	trigger  chan struct{}
+	cancel   context.CancelFunc
+	done     chan struct{}
+	closed   sync.Once
+}
+
+// NewWatcher 加载配置并开始监听，sources 为空时使用 DefaultSources。
+// validate 标签总会被校验，validate 参数是额外的校验，可以为 nil；首次加载或校验失败时返回错误
+func NewWatcher[T any](validate func(*T) error, sources ...Source) (*Watcher[T], error) {
+	if len(sources) == 0 {
+		sources = DefaultSources("")
+	}
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher[T]{
+		loader:   NewLoader[T](sources...),
+		validate: validate,
+		fsw:      fsw,
+		dirs:     make(map[string]bool),
+		files:    make(map[string]bool),
+		trigger:  make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	conf, err := w.load()
+	if err != nil {
+		_ = fsw.Close()
+		return nil, err
+	}
+	w.current.Store(conf)
+	if err = w.watch(); err != nil {
+		_ = fsw.Close()
+		return nil, err
+	}
+	var ctx context.Context
+	ctx, w.cancel = context.WithCancel(context.Background())
+	for _, src := range sources {
+		if ns, ok := src.(notifySource); ok {
+			go ns.watchChanges(ctx, w.notify)
+		}
+	}
+	go w.run()
+	return w, nil
+}
+
+// notify 来源通知有变化，和文件事件一样合并后重新加载
+func (w *Watcher[T]) notify() {
+	select {
+	case w.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Get 当前配置，返回的结构体不会被修改，可以在多个协程中读取
+func (w *Watcher[T]) Get() *T {
+	return w.current.Load()
+}
+
+// OnChange 订阅配置变化，每次成功重新加载后按订阅顺序调用
+func (w *Watcher[T]) OnChange(fn func(old, new *T)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subs = append(w.subs, fn)
+}
+
+// OnError 重新加载失败时的回调，默认打印日志
+func (w *Watcher[T]) OnError(fn func(err error)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onError = fn
+}
+
+// Provenance 当前配置中每个字段的来源
+func (w *Watcher[T]) Provenance() ProvenanceReport {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+	return w.loader.Provenance()
+}
+
+// Reload 立即重新加载，失败时保留旧配置并返回错误
+func (w *Watcher[T]) Reload() error {
+	w.reloadMu.Lock()
+	conf, err := w.load()
+	if err == nil {
+		err = w.watch()
+	}
+	if err != nil {
+		w.reloadMu.Unlock()
+		return err
+	}
+	old := w.current.Swap(conf)
+	w.reloadMu.Unlock()
+
+	w.mu.Lock()
+	subs := append([]func(old, new *T){}, w.subs...)
+	w.mu.Unlock()
+	for _, fn := range subs {
+		fn(old, conf)
+	}
+	return nil
+}
+
+// Close 停止监听
+func (w *Watcher[T]) Close() error {
+	var err error
+	w.closed.Do(func() {
+		w.cancel()
+		close(w.done)
+		err = w.fsw.Close()
+	})
+	return err
+}
+
+// load 从空结构体加载，保证删除的配置项不会残留旧值
+func (w *Watcher[T]) load() (*T, error) {
+	conf := new(T)
+	if err := w.loader.Load(conf); err != nil {
+		return nil, err
+	}
+	if w.validate != nil {
+		if err := w.validate(conf); err != nil {
+			return nil, err
+		}
+	}
+	return conf, nil
+}
+
+// watch 监听配置文件所在目录，编辑器通过重命名保存文件时仍然可以收到事件；不存在的目录跳过
+func (w *Watcher[T]) watch() error {
+	var errs []error
+	for _, src := range w.loader.sources {
+		fw, ok := src.(fileWatchable)
+		if !ok {
+			continue
+		}
+		for _, file := range fw.watchFiles() {
+			w.files[file] = true
+			dir := filepath.Dir(file)
+			if w.dirs[dir] {
+				continue
+			}
+			if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err := w.fsw.Add(dir); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			w.dirs[dir] = true
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (w *Watcher[T]) watching(name string) bool {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+	path, err := filepath.Abs(name)
+	if err != nil {
+		path = name
+	}
+	return w.files[path]
+}
+
+func (w *Watcher[T]) run() {
+	var timer *time.Timer
+	var fire <-chan time.Time
+	for {
+		select {
+		case <-w.done:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case ev, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			if ev.Has(fsnotify.Chmod) || !w.watching(ev.Name) {
+				continue
+			}
+			timer = debounce(timer)
+			fire = timer.C
+		case <-w.trigger:
+			timer = debounce(timer)
+			fire = timer.C
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			w.reportError(err)
+		case <-fire:
+			fire = nil
+			if err := w.Reload(); err != nil {
+				w.reportError(err)
+			}
+		}
+	}
+}
+
+func debounce(timer *time.Timer) *time.Timer {
+	if timer == nil {
+		return time.NewTimer(watchDebounce)
+	}
+	timer.Reset(watchDebounce)
+	return timer
+}
+
+func (w *Watcher[T]) reportError(err error) {
+	w.mu.Lock()
+	fn := w.onError
+	w.mu.Unlock()
+	if fn != nil {
+		fn(err)
+		return
+	}
+	log.Println("conf.Watcher reload err: ", err)
+}
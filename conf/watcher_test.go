@@ -0,0 +1,82 @@
+package conf
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func waitFor(t *testing.T, ch <-chan struct{}, msg string) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(3 * time.Second):
+		t.Fatal(msg)
+	}
+}
+
+func TestWatcher_Reload(t *testing.T) {
+	path := writeSourceFile(t, "name: v1\nport: 8080\n")
+	validate := func(c *sourceConf) error {
+		if c.Port <= 0 {
+			return errors.New("port must be positive")
+		}
+		return nil
+	}
+	w, err := NewWatcher[sourceConf](validate, &FileSource{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if w.Get().Name != "v1" {
+		t.Fatalf("首次加载错误: %+v", w.Get())
+	}
+
+	changed := make(chan struct{}, 1)
+	failed := make(chan struct{}, 1)
+	w.OnChange(func(old, new *sourceConf) {
+		if old.Name != "v1" || new.Name != "v2" {
+			t.Errorf("订阅者收到的配置错误: %+v -> %+v", old, new)
+		}
+		changed <- struct{}{}
+	})
+	w.OnError(func(err error) {
+		failed <- struct{}{}
+	})
+
+	// 校验失败的配置不会替换旧配置
+	if err = os.WriteFile(path, []byte("name: bad\nport: 0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, failed, "无效配置应触发 OnError")
+	if w.Get().Name != "v1" {
+		t.Errorf("无效配置不应生效: %+v", w.Get())
+	}
+
+	// 通过重命名保存，和大多数编辑器一致
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, []byte("name: v2\nport: 9090\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, changed, "配置文件修改后应通知订阅者")
+	if c := w.Get(); c.Name != "v2" || c.Port != 9090 {
+		t.Errorf("新配置未生效: %+v", c)
+	}
+}
+
+func TestWatcher_InitialLoadError(t *testing.T) {
+	path := writeSourceFile(t, "port: -1\n")
+	_, err := NewWatcher[sourceConf](func(c *sourceConf) error {
+		if c.Port < 0 {
+			return errors.New("invalid port")
+		}
+		return nil
+	}, &FileSource{Path: path})
+	if err == nil {
+		t.Errorf("首次加载校验失败应返回错误")
+	}
+}
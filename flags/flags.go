@@ -0,0 +1,394 @@
+// Package flags 功能开关：布尔开关和多值开关，支持按用户灰度和按用户、环境、IP 段、自定义属性定向。
+// 开关定义通过 conf 加载，配置变化时自动生效，不需要重启。
+//
+//	flags:
+//	  new_checkout:
+//	    enabled: true
+//	    default: "false"
+//	    rules:
+//	      - users: [alice, bob]
+//	        value: "true"
+//	      - envs: [test]
+//	        cidrs: [10.0.0.0/16]
+//	        rollout:
+//	          - {value: "true", weight: 50}
+//	    rollout:
+//	      - {value: "true", weight: 5}
+package flags
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/netip"
+	"sync/atomic"
+
+	"github.com/Chairou/toolbox/util/cal"
+)
+
+// bucketCount 灰度分桶数量，权重精度为 0.01%
+const bucketCount = 10000
+
+// Config 开关配置，一般放在配置文件的 flags 下
+type Config struct {
+	Flags map[string]*Flag `yaml:"flags" json:"flags"`
+}
+
+// Flag 一个开关的定义。依次匹配 Rules，命中第一条规则时使用它的 Value 或 Rollout；
+// 都没有命中时按 Rollout 灰度，灰度没有覆盖到的用户使用 Default
+type Flag struct {
+	// Enabled 为 false 时总是返回 Default
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Default 默认值，布尔开关为 "true" 或 "false"，为空时视为 "false"
+	Default string    `yaml:"default" json:"default"`
+	Rules   []Rule    `yaml:"rules" json:"rules"`
+	Rollout []Variant `yaml:"rollout" json:"rollout"`
+}
+
+// Rule 定向规则，设置了的条件全部满足才命中，同一个条件中的多个值满足其一即可
+type Rule struct {
+	Users []string `yaml:"users" json:"users"`
+	Envs  []string `yaml:"envs" json:"envs"`
+	// CIDRs IP 段，例如 10.0.0.0/16
+	CIDRs []string `yaml:"cidrs" json:"cidrs"`
+	// Attrs 自定义属性，key 为属性名，value 为可选值
+	Attrs map[string][]string `yaml:"attrs" json:"attrs"`
+	// Value 命中时的值，为空时按 Rollout 灰度
+	Value   string    `yaml:"value" json:"value"`
+	Rollout []Variant `yaml:"rollout" json:"rollout"`
+}
+
+// Variant 灰度的一个取值，Weight 为百分比，全部 Weight 之和不能超过 100
+type Variant struct {
+	Value  string  `yaml:"value" json:"value"`
+	Weight float64 `yaml:"weight" json:"weight"`
+}
+
+// Target 求值对象
+type Target struct {
+	// Key 灰度分桶使用的 key，为空时使用 UserName
+	Key      string
+	UserName string
+	// Env 为空时使用 Set 的环境
+	Env   string
+	IP    string
+	Attrs map[string]string
+}
+
+// Set 编译后的开关集合，求值时不加锁，可以在多个协程中使用
+type Set struct {
+	env    string
+	flags  atomic.Pointer[map[string]*compiledFlag]
+	closer io.Closer
+}
+
+// New 编译开关配置，env 为当前环境，Target.Env 为空时使用
+func New(cfg *Config, env string) (*Set, error) {
+	s := &Set{env: env}
+	if err := s.Update(cfg); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Update 编译新的开关配置并原子替换，配置有误时返回错误并保留旧配置
+func (s *Set) Update(cfg *Config) error {
+	flags, err := compile(cfg)
+	if err != nil {
+		return err
+	}
+	s.flags.Store(&flags)
+	return nil
+}
+
+// Validate 校验开关配置，可以在发布配置前调用
+func Validate(cfg *Config) error {
+	_, err := compile(cfg)
+	return err
+}
+
+// compile 校验并编译开关配置
+func compile(cfg *Config) (map[string]*compiledFlag, error) {
+	flags := make(map[string]*compiledFlag)
+	if cfg == nil {
+		return flags, nil
+	}
+	for name, f := range cfg.Flags {
+		if f == nil {
+			continue
+		}
+		cf, err := compileFlag(name, f)
+		if err != nil {
+			return nil, fmt.Errorf("flag %s: %v", name, err)
+		}
+		flags[name] = cf
+	}
+	return flags, nil
+}
+
+// Bool 布尔开关，值为 "true" 时返回 true，开关不存在时返回 false
+func (s *Set) Bool(name string, t Target) bool {
+	v, _ := s.Variant(name, t)
+	return v == "true"
+}
+
+// Variant 多值开关，开关不存在时返回空字符串和 false
+func (s *Set) Variant(name string, t Target) (string, bool) {
+	flags := s.flags.Load()
+	if flags == nil {
+		return "", false
+	}
+	f, ok := (*flags)[name]
+	if !ok {
+		return "", false
+	}
+	return f.eval(s, t), true
+}
+
+// Names 全部开关名
+func (s *Set) Names() []string {
+	flags := s.flags.Load()
+	if flags == nil {
+		return nil
+	}
+	names := make([]string, 0, len(*flags))
+	for name := range *flags {
+		names = append(names, name)
+	}
+	return names
+}
+
+type compiledFlag struct {
+	name    string
+	enabled bool
+	def     string
+	rules   []compiledRule
+	rollout []bucket
+}
+
+type compiledRule struct {
+	users   map[string]struct{}
+	envs    map[string]struct{}
+	ranges  []ipRange
+	attrs   map[string]map[string]struct{}
+	value   string
+	rollout []bucket
+}
+
+// bucket 灰度区间，分桶号小于 upper 的用户使用 value
+type bucket struct {
+	upper uint32
+	value string
+}
+
+// ipRange IP 段，IPv4 16 到 30 位掩码使用 cal.GetCidrIpRange 计算的范围，其它使用 netip.Prefix
+type ipRange struct {
+	first, last netip.Addr
+	prefix      netip.Prefix
+}
+
+func (r ipRange) contains(ip netip.Addr) bool {
+	if r.prefix.IsValid() {
+		return r.prefix.Contains(ip)
+	}
+	return ip.Compare(r.first) >= 0 && ip.Compare(r.last) <= 0
+}
+
+func compileFlag(name string, f *Flag) (*compiledFlag, error) {
+	cf := &compiledFlag{name: name, enabled: f.Enabled, def: f.Default}
+	if cf.def == "" {
+		cf.def = "false"
+	}
+	var err error
+	if cf.rollout, err = compileRollout(f.Rollout); err != nil {
+		return nil, err
+	}
+	for i, r := range f.Rules {
+		cr := compiledRule{value: r.Value, users: toSet(r.Users), envs: toSet(r.Envs)}
+		for _, cidr := range r.CIDRs {
+			rg, err := compileCIDR(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: %v", i, err)
+			}
+			cr.ranges = append(cr.ranges, rg)
+		}
+		if len(r.Attrs) > 0 {
+			cr.attrs = make(map[string]map[string]struct{}, len(r.Attrs))
+			for k, values := range r.Attrs {
+				cr.attrs[k] = toSet(values)
+			}
+		}
+		if cr.rollout, err = compileRollout(r.Rollout); err != nil {
+			return nil, fmt.Errorf("rule %d: %v", i, err)
+		}
+		if cr.value == "" && cr.rollout == nil {
+			return nil, fmt.Errorf("rule %d: value or rollout is required", i)
+		}
+		cf.rules = append(cf.rules, cr)
+	}
+	return cf, nil
+}
+
+func compileRollout(variants []Variant) ([]bucket, error) {
+	if len(variants) == 0 {
+		return nil, nil
+	}
+	buckets := make([]bucket, 0, len(variants))
+	total := 0.0
+	for _, v := range variants {
+		if v.Weight < 0 {
+			return nil, fmt.Errorf("negative weight %v", v.Weight)
+		}
+		total += v.Weight
+		if total > 100+1e-9 {
+			return nil, fmt.Errorf("total rollout weight %v exceeds 100", total)
+		}
+		buckets = append(buckets, bucket{upper: uint32(math.Round(total * bucketCount / 100)), value: v.Value})
+	}
+	return buckets, nil
+}
+
+func compileCIDR(cidr string) (ipRange, error) {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return ipRange{}, err
+	}
+	ones, bits := ipNet.Mask.Size()
+	if bits == 32 && ones >= 16 && ones <= 30 {
+		first, broadcast := cal.GetCidrIpRange(cidr)
+		firstAddr, err1 := netip.ParseAddr(first)
+		lastAddr, err2 := netip.ParseAddr(broadcast)
+		if err1 == nil && err2 == nil {
+			// GetCidrIpRange 返回的首地址为网络地址加 1，这里包含网络地址
+			return ipRange{first: firstAddr.Prev(), last: lastAddr}, nil
+		}
+	}
+	prefix, err := netip.ParsePrefix(ipNet.String())
+	if err != nil {
+		return ipRange{}, err
+	}
+	return ipRange{prefix: prefix}, nil
+}
+
+func toSet(values []string) map[string]struct{} {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return set
+}
+
+func (f *compiledFlag) eval(s *Set, t Target) string {
+	if !f.enabled {
+		return f.def
+	}
+	env := t.Env
+	if env == "" {
+		env = s.env
+	}
+	var ip netip.Addr
+	ipParsed := false
+	for i := range f.rules {
+		r := &f.rules[i]
+		if r.users != nil && !inSet(r.users, t.UserName) {
+			continue
+		}
+		if r.envs != nil && !inSet(r.envs, env) {
+			continue
+		}
+		if r.ranges != nil {
+			if !ipParsed {
+				ip, _ = netip.ParseAddr(t.IP)
+				ip = ip.Unmap()
+				ipParsed = true
+			}
+			if !r.matchIP(ip) {
+				continue
+			}
+		}
+		if !r.matchAttrs(t.Attrs) {
+			continue
+		}
+		if r.value != "" {
+			return r.value
+		}
+		if v, ok := pick(r.rollout, f.name, t); ok {
+			return v
+		}
+		return f.def
+	}
+	if v, ok := pick(f.rollout, f.name, t); ok {
+		return v
+	}
+	return f.def
+}
+
+func inSet(set map[string]struct{}, v string) bool {
+	_, ok := set[v]
+	return ok
+}
+
+func (r *compiledRule) matchIP(ip netip.Addr) bool {
+	if !ip.IsValid() {
+		return false
+	}
+	for _, rg := range r.ranges {
+		if rg.contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *compiledRule) matchAttrs(attrs map[string]string) bool {
+	for k, values := range r.attrs {
+		v, ok := attrs[k]
+		if !ok || !inSet(values, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// pick 按分桶选择灰度取值，没有 key 或没有落在任何区间时返回 false
+func pick(buckets []bucket, name string, t Target) (string, bool) {
+	if len(buckets) == 0 {
+		return "", false
+	}
+	key := t.Key
+	if key == "" {
+		key = t.UserName
+	}
+	if key == "" {
+		return "", false
+	}
+	b := Bucket(name, key)
+	for _, bk := range buckets {
+		if b < bk.upper {
+			return bk.value, true
+		}
+	}
+	return "", false
+}
+
+// Bucket 用户在开关中的分桶号，范围 [0, 10000)。同一个用户在同一个开关中的分桶号固定，
+// 不同开关之间相互独立；增加灰度比例时已经命中的用户保持命中
+func Bucket(name, key string) uint32 {
+	// FNV-1a，避免 hash.Hash 的内存分配
+	h := uint32(2166136261)
+	for i := 0; i < len(name); i++ {
+		h ^= uint32(name[i])
+		h *= 16777619
+	}
+	h ^= ':'
+	h *= 16777619
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return h % bucketCount
+}
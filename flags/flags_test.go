@@ -0,0 +1,154 @@
+package flags
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Chairou/toolbox/conf"
+)
+
+func newTestSet(t *testing.T) *Set {
+	t.Helper()
+	s, err := New(&Config{Flags: map[string]*Flag{
+		"checkout": {
+			Enabled: true,
+			Rules: []Rule{
+				{Users: []string{"alice"}, Value: "true"},
+				{Envs: []string{"test"}, CIDRs: []string{"10.1.0.0/16", "192.168.1.7/32"}, Value: "true"},
+				{Attrs: map[string][]string{"plan": {"vip"}}, Rollout: []Variant{{Value: "true", Weight: 100}}},
+			},
+			Rollout: []Variant{{Value: "true", Weight: 20}},
+		},
+		"color": {
+			Enabled: true,
+			Default: "blue",
+			Rollout: []Variant{{Value: "red", Weight: 50}, {Value: "green", Weight: 50}},
+		},
+		"off": {Enabled: false, Default: "true", Rules: []Rule{{Users: []string{"alice"}, Value: "false"}}},
+	}}, "release")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSet_Targeting(t *testing.T) {
+	s := newTestSet(t)
+	cases := []struct {
+		target Target
+		want   bool
+	}{
+		{Target{UserName: "alice"}, true},
+		{Target{Key: "k", Env: "test", IP: "10.1.200.3"}, true},
+		{Target{Key: "k", Env: "test", IP: "192.168.1.7"}, true},
+		{Target{Key: "k", Env: "test", IP: "::ffff:10.1.0.0"}, true},
+		{Target{Key: "k", Env: "release", IP: "10.1.200.3"}, false},
+		{Target{Key: "k", Env: "test", IP: "10.2.0.1"}, false},
+		{Target{Key: "k", Attrs: map[string]string{"plan": "vip"}}, true},
+	}
+	for _, c := range cases {
+		if c.target.Key == "k" && Bucket("checkout", "k") < 2000 {
+			t.Fatal("测试 key 恰好落在灰度范围内，需要换一个")
+		}
+		if got := s.Bool("checkout", c.target); got != c.want {
+			t.Errorf("%+v 期望 %v, 实际 %v", c.target, c.want, got)
+		}
+	}
+	if !s.Bool("off", Target{UserName: "alice"}) {
+		t.Errorf("关闭的开关应返回默认值")
+	}
+	if _, ok := s.Variant("missing", Target{}); ok || s.Bool("missing", Target{}) {
+		t.Errorf("不存在的开关应返回 false")
+	}
+}
+
+func TestSet_Rollout(t *testing.T) {
+	s := newTestSet(t)
+	on := 0
+	colors := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		target := Target{UserName: fmt.Sprintf("user-%d", i)}
+		if s.Bool("checkout", target) {
+			on++
+		}
+		v, _ := s.Variant("color", target)
+		colors[v]++
+		if s.Bool("checkout", target) != s.Bool("checkout", target) {
+			t.Fatal("同一用户的结果应保持稳定")
+		}
+	}
+	if on < 1800 || on > 2200 {
+		t.Errorf("20%% 灰度命中数量异常: %d", on)
+	}
+	if colors["blue"] != 0 || colors["red"] < 4800 || colors["green"] < 4800 {
+		t.Errorf("多值灰度分布异常: %v", colors)
+	}
+	if v, _ := s.Variant("color", Target{}); v != "blue" {
+		t.Errorf("没有 key 时应返回默认值: %s", v)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	bad := []*Flag{
+		{Rollout: []Variant{{Value: "a", Weight: 60}, {Value: "b", Weight: 60}}},
+		{Rules: []Rule{{CIDRs: []string{"10.0.0.0/33"}, Value: "true"}}},
+		{Rules: []Rule{{Users: []string{"a"}}}},
+	}
+	for i, f := range bad {
+		if err := Validate(&Config{Flags: map[string]*Flag{"f": f}}); err == nil {
+			t.Errorf("第 %d 个配置应校验失败", i)
+		}
+	}
+}
+
+func TestLoad_HotReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.yaml")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("flags:\n  beta:\n    enabled: true\n    rules:\n      - users: [alice]\n        value: \"true\"\n")
+	s, err := Load("dev", &conf.FileSource{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if !s.Bool("beta", Target{UserName: "alice"}) || s.Bool("beta", Target{UserName: "bob"}) {
+		t.Fatal("首次加载结果错误")
+	}
+
+	// 无效配置被拒绝
+	write("flags:\n  beta:\n    enabled: true\n    rollout:\n      - {value: \"true\", weight: 200}\n")
+	time.Sleep(300 * time.Millisecond)
+	if !s.Bool("beta", Target{UserName: "alice"}) {
+		t.Fatal("无效配置不应生效")
+	}
+
+	write("flags:\n  beta:\n    enabled: true\n    rules:\n      - users: [bob]\n        value: \"true\"\n")
+	deadline := time.Now().Add(3 * time.Second)
+	for !s.Bool("beta", Target{UserName: "bob"}) {
+		if time.Now().After(deadline) {
+			t.Fatal("修改后的开关应自动生效")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func BenchmarkSet_Bool(b *testing.B) {
+	s, _ := New(&Config{Flags: map[string]*Flag{
+		"f": {
+			Enabled: true,
+			Rules:   []Rule{{Envs: []string{"test"}, CIDRs: []string{"10.0.0.0/16"}, Value: "true"}},
+			Rollout: []Variant{{Value: "true", Weight: 30}},
+		},
+	}}, "release")
+	target := Target{UserName: "alice", IP: "10.0.3.4"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		s.Bool("f", target)
+	}
+}
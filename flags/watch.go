@@ -0,0 +1,34 @@
+package flags
+
+import (
+	"github.com/Chairou/toolbox/conf"
+)
+
+// Load 通过 conf 加载开关配置并监听变化，配置文件或远程配置修改后新的开关立即生效，
+// 有误的配置会被拒绝并保留旧配置。sources 为空时使用 conf.DefaultSources(env)
+func Load(env string, sources ...conf.Source) (*Set, error) {
+	if len(sources) == 0 {
+		sources = conf.DefaultSources(env)
+	}
+	w, err := conf.NewWatcher[Config](Validate, sources...)
+	if err != nil {
+		return nil, err
+	}
+	s := &Set{env: env, closer: w}
+	if err = s.Update(w.Get()); err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+	w.OnChange(func(_, c *Config) {
+		_ = s.Update(c)
+	})
+	return s, nil
+}
+
+// Close 停止监听配置变化，New 创建的 Set 不需要关闭
+func (s *Set) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
@@ -0,0 +1,397 @@
+package gin
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Chairou/toolbox/util/redis"
+)
+
+// CacheStore 响应缓存存储
+type CacheStore interface {
+	// Get 读取缓存，不存在或已过期时返回 false
+	Get(key string) ([]byte, bool, error)
+	// Set 写入缓存并关联标签
+	Set(key string, value []byte, ttl time.Duration, tags []string) error
+	// InvalidateTags 删除关联了任一标签的全部缓存
+	InvalidateTags(tags ...string) error
+}
+
+// CacheOpt 响应缓存中间件参数
+type CacheOpt struct {
+	// Store 缓存存储，NewMemoryCacheStore 或 NewRedisCacheStore
+	Store CacheStore
+	// TTL 缓存时长，默认 1 分钟
+	TTL time.Duration
+	// Tags 缓存关联的标签，写接口通过 InvalidateCache 按标签失效
+	Tags []string
+	// TagFunc 按请求生成额外标签，例如按项目 ID 区分
+	TagFunc func(c *Context) []string
+	// PerUser 为 true 时按 UserName 区分缓存，用于结果与当前用户相关的接口
+	PerUser bool
+	// KeyPrefix 缓存 key 前缀，默认 cache:
+	KeyPrefix string
+}
+
+// CacheStatusHeader 响应头，值为 HIT 或 MISS
+const CacheStatusHeader = "X-Cache"
+
+// cachedResponse 缓存的响应
+type cachedResponse struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"contentType"`
+	Body        []byte `json:"body"`
+}
+
+// Cache GET/HEAD 接口的响应缓存中间件。
+// 缓存 key 由方法、路径、排序后的查询参数以及可选的 UserName 组成；
+// 只缓存 HTTP 200 且 RetJson 返回码为 API_OK 的响应；
+// 同一个 key 的并发请求只执行一次处理函数，其余请求等待并复用结果。
+//
+// 使用方法：
+//
+//	store := NewMemoryCacheStore(10000)
+//	group.GET("/orders", Cache(CacheOpt{Store: store, TTL: time.Minute, Tags: []string{"order"}}), listOrders)
+//	group.POST("/order", func(c *Context) {
+//		... // 写入数据库
+//		_ = InvalidateCache(store, "order")
+//	})
+func Cache(opt CacheOpt) HandlerFunc {
+	if opt.TTL <= 0 {
+		opt.TTL = time.Minute
+	}
+	if opt.KeyPrefix == "" {
+		opt.KeyPrefix = "cache:"
+	}
+	group := &singleflightGroup{}
+
+	return func(c *Context) {
+		if opt.Store == nil || (c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead) {
+			c.Next()
+			return
+		}
+		key := opt.KeyPrefix + cacheKey(c, opt.PerUser)
+
+		if resp, ok := getCachedResponse(c, opt.Store, key); ok {
+			c.Header(CacheStatusHeader, "HIT")
+			c.Data(resp.Status, resp.ContentType, resp.Body)
+			c.Abort()
+			return
+		}
+
+		leader := false
+		val, _ := group.Do(key, func() (interface{}, error) {
+			leader = true
+			return runAndCache(c, opt, key), nil
+		})
+		if leader {
+			return
+		}
+		// 等待其他请求的结果；不可缓存时自己执行处理函数
+		resp, _ := val.(*cachedResponse)
+		if resp == nil {
+			c.Next()
+			return
+		}
+		c.Header(CacheStatusHeader, "HIT")
+		c.Data(resp.Status, resp.ContentType, resp.Body)
+		c.Abort()
+	}
+}
+
+// runAndCache 执行处理函数并缓存响应，不可缓存时返回 nil
+func runAndCache(c *Context, opt CacheOpt, key string) *cachedResponse {
+	c.Header(CacheStatusHeader, "MISS")
+	blw := &bodyLogWriter{body: bytes.NewBufferString(""), ResponseWriter: c.Writer}
+	c.Writer = blw
+	c.Next()
+
+	if blw.Status() != http.StatusOK || requestFailed(c) {
+		return nil
+	}
+	if code, ok := c.Get("code"); ok && code != API_OK {
+		return nil
+	}
+	resp := &cachedResponse{
+		Status:      blw.Status(),
+		ContentType: blw.Header().Get("Content-Type"),
+		Body:        blw.body.Bytes(),
+	}
+	tags := opt.Tags
+	if opt.TagFunc != nil {
+		tags = append(append([]string{}, tags...), opt.TagFunc(c)...)
+	}
+	data, _ := json.Marshal(resp)
+	if err := opt.Store.Set(key, data, opt.TTL, tags); err != nil {
+		_ = c.Errorf("cache set %s err: %v", key, err)
+	}
+	return resp
+}
+
+func getCachedResponse(c *Context, store CacheStore, key string) (*cachedResponse, bool) {
+	data, ok, err := store.Get(key)
+	if err != nil {
+		_ = c.Errorf("cache get %s err: %v", key, err)
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+	var resp cachedResponse
+	if err = json.Unmarshal(data, &resp); err != nil {
+		_ = c.Errorf("cache decode %s err: %v", key, err)
+		return nil, false
+	}
+	return &resp, true
+}
+
+// cacheKey 方法、路径、排序后的查询参数、用户名的 sha256
+func cacheKey(c *Context, perUser bool) string {
+	query := c.Request.URL.Query()
+	for _, v := range query {
+		sort.Strings(v)
+	}
+	raw := c.Request.Method + " " + c.Request.URL.Path + "?" + query.Encode()
+	if perUser {
+		raw += "\n" + c.UserName
+	}
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// InvalidateCache 按标签使缓存失效，在写接口中调用
+func InvalidateCache(store CacheStore, tags ...string) error {
+	if store == nil || len(tags) == 0 {
+		return nil
+	}
+	return store.InvalidateTags(tags...)
+}
+
+// singleflightGroup 合并同一个 key 的并发调用
+type singleflightGroup struct {
+	mu sync.Mutex
+	m  map[string]*singleflightCall
+}
+
+type singleflightCall struct {
+	wg  sync.WaitGroup
+	val interface{}
+	err error
+}
+
+// Do 同一个 key 同时只执行一次 fn，并发调用者等待并共享结果
+func (g *singleflightGroup) Do(key string, fn func() (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[string]*singleflightCall)
+	}
+	if call, ok := g.m[key]; ok {
+		g.mu.Unlock()
+		call.wg.Wait()
+		return call.val, call.err
+	}
+	call := &singleflightCall{}
+	call.wg.Add(1)
+	g.m[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		// fn panic 时等待者拿到 nil，自己执行处理函数
+		call.wg.Done()
+		g.mu.Lock()
+		delete(g.m, key)
+		g.mu.Unlock()
+	}()
+	call.val, call.err = fn()
+	return call.val, call.err
+}
+
+// MemoryCacheStore 进程内 LRU 缓存
+type MemoryCacheStore struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+	tags     map[string]map[string]struct{}
+	now      func() time.Time
+}
+
+type memoryCacheEntry struct {
+	key      string
+	value    []byte
+	expireAt time.Time
+	tags     []string
+}
+
+// NewMemoryCacheStore 创建进程内 LRU 缓存，capacity 为最多缓存的条数
+func NewMemoryCacheStore(capacity int) *MemoryCacheStore {
+	if capacity <= 0 {
+		capacity = 1000
+	}
+	return &MemoryCacheStore{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+		tags:     make(map[string]map[string]struct{}),
+		now:      time.Now,
+	}
+}
+
+// Get 读取缓存
+func (s *MemoryCacheStore) Get(key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := elem.Value.(*memoryCacheEntry)
+	if !s.now().Before(entry.expireAt) {
+		s.removeElement(elem)
+		return nil, false, nil
+	}
+	s.ll.MoveToFront(elem)
+	return entry.value, true, nil
+}
+
+// Set 写入缓存，超过容量时淘汰最久未使用的条目
+func (s *MemoryCacheStore) Set(key string, value []byte, ttl time.Duration, tags []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if elem, ok := s.items[key]; ok {
+		s.removeElement(elem)
+	}
+	entry := &memoryCacheEntry{key: key, value: value, expireAt: s.now().Add(ttl), tags: tags}
+	s.items[key] = s.ll.PushFront(entry)
+	for _, tag := range tags {
+		if s.tags[tag] == nil {
+			s.tags[tag] = make(map[string]struct{})
+		}
+		s.tags[tag][key] = struct{}{}
+	}
+	for s.ll.Len() > s.capacity {
+		s.removeElement(s.ll.Back())
+	}
+	return nil
+}
+
+// InvalidateTags 删除关联了任一标签的缓存
+func (s *MemoryCacheStore) InvalidateTags(tags ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tag := range tags {
+		for key := range s.tags[tag] {
+			if elem, ok := s.items[key]; ok {
+				s.removeElement(elem)
+			}
+		}
+		delete(s.tags, tag)
+	}
+	return nil
+}
+
+// Len 当前缓存条数
+func (s *MemoryCacheStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ll.Len()
+}
+
+// removeElement 删除条目及其标签索引，调用方需持有锁
+func (s *MemoryCacheStore) removeElement(elem *list.Element) {
+	entry := elem.Value.(*memoryCacheEntry)
+	s.ll.Remove(elem)
+	delete(s.items, entry.key)
+	for _, tag := range entry.tags {
+		if keys := s.tags[tag]; keys != nil {
+			delete(keys, entry.key)
+			if len(keys) == 0 {
+				delete(s.tags, tag)
+			}
+		}
+	}
+}
+
+// RedisCacheStore 基于 util/redis 的缓存，标签通过 set 记录关联的 key
+type RedisCacheStore struct {
+	pool      *redis.RdPool
+	tagPrefix string
+}
+
+// NewRedisCacheStore 创建 redis 缓存，tagPrefix 为标签 set 的 key 前缀，默认 cache-tag:
+func NewRedisCacheStore(pool *redis.RdPool, tagPrefix string) *RedisCacheStore {
+	if tagPrefix == "" {
+		tagPrefix = "cache-tag:"
+	}
+	return &RedisCacheStore{pool: pool, tagPrefix: tagPrefix}
+}
+
+// Get 读取缓存
+func (s *RedisCacheStore) Get(key string) ([]byte, bool, error) {
+	str, err := s.pool.Get(key)
+	if errors.Is(err, redis.ErrNil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return []byte(str), true, nil
+}
+
+// Set 写入缓存，并把 key 加入各标签的 set，标签 set 的过期时间不短于缓存
+func (s *RedisCacheStore) Set(key string, value []byte, ttl time.Duration, tags []string) error {
+	seconds := ttlSeconds(ttl)
+	if _, err := s.pool.SetEX(key, value, seconds); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		tagKey := s.tagPrefix + tag
+		if _, err := s.pool.Do("SADD", tagKey, key); err != nil {
+			return err
+		}
+		// 只延长不缩短标签 set 的过期时间，-1 表示刚创建还没有过期时间
+		ttlLeft, err := s.pool.Ttl(tagKey)
+		if err != nil {
+			return err
+		}
+		if ttlLeft < int64(seconds) {
+			if _, err = s.pool.Expired(tagKey, seconds); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// InvalidateTags 删除标签关联的缓存和标签 set
+func (s *RedisCacheStore) InvalidateTags(tags ...string) error {
+	var errs []string
+	for _, tag := range tags {
+		tagKey := s.tagPrefix + tag
+		reply, err := s.pool.Do("SMEMBERS", tagKey)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		args := []interface{}{tagKey}
+		if members, ok := reply.([]interface{}); ok {
+			args = append(args, members...)
+		}
+		if _, err = s.pool.Do("DEL", args...); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New("invalidate cache tags err: " + strings.Join(errs, "; "))
+	}
+	return nil
+}
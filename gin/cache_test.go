@@ -0,0 +1,120 @@
+package gin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func getPath(r http.Handler, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestCache_HitAndInvalidate(t *testing.T) {
+	r, group := newResilienceRouter()
+	store := NewMemoryCacheStore(10)
+	calls := 0
+	group.GET("/orders", Cache(CacheOpt{Store: store, Tags: []string{"order"}}), func(c *Context) {
+		calls++
+		c.RetJson(API_OK, calls)
+	})
+
+	first := getPath(r, "/orders?b=2&a=1")
+	second := getPath(r, "/orders?a=1&b=2")
+	if calls != 1 {
+		t.Fatalf("查询参数顺序不同应命中同一缓存, 执行次数 %d", calls)
+	}
+	if second.Header().Get(CacheStatusHeader) != "HIT" || first.Header().Get(CacheStatusHeader) != "MISS" {
+		t.Errorf("X-Cache 响应头不正确")
+	}
+	if first.Body.String() != second.Body.String() {
+		t.Errorf("缓存响应不一致")
+	}
+
+	getPath(r, "/orders?a=2")
+	if calls != 2 {
+		t.Errorf("不同查询参数应分别缓存")
+	}
+
+	if err := InvalidateCache(store, "order"); err != nil {
+		t.Fatal(err)
+	}
+	if store.Len() != 0 {
+		t.Errorf("按标签失效后缓存应为空, 实际 %d", store.Len())
+	}
+	getPath(r, "/orders?a=1&b=2")
+	if calls != 3 {
+		t.Errorf("失效后应重新执行处理函数")
+	}
+}
+
+func TestCache_SkipErrorResponse(t *testing.T) {
+	r, group := newResilienceRouter()
+	store := NewMemoryCacheStore(10)
+	calls := 0
+	group.GET("/err", Cache(CacheOpt{Store: store}), func(c *Context) {
+		calls++
+		c.RetJson(API_DB_ERROR, nil, "db error")
+	})
+	getPath(r, "/err")
+	getPath(r, "/err")
+	if calls != 2 {
+		t.Errorf("错误响应不应被缓存")
+	}
+}
+
+func TestCache_Singleflight(t *testing.T) {
+	r, group := newResilienceRouter()
+	store := NewMemoryCacheStore(10)
+	var calls int32
+	release := make(chan struct{})
+	group.GET("/slow", Cache(CacheOpt{Store: store}), func(c *Context) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		c.RetJson(API_OK, "ok")
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if w := getPath(r, "/slow"); w.Code != http.StatusOK {
+				t.Errorf("状态码错误: %d", w.Code)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("并发请求应只执行一次处理函数, 实际 %d", n)
+	}
+}
+
+func TestMemoryCacheStore_LRUAndTTL(t *testing.T) {
+	store := NewMemoryCacheStore(2)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	_ = store.Set("a", []byte("1"), time.Minute, nil)
+	_ = store.Set("b", []byte("2"), time.Minute, nil)
+	_, _, _ = store.Get("a")
+	_ = store.Set("c", []byte("3"), time.Minute, nil)
+	if _, ok, _ := store.Get("b"); ok {
+		t.Errorf("最久未使用的 b 应被淘汰")
+	}
+	if _, ok, _ := store.Get("a"); !ok {
+		t.Errorf("a 最近使用过, 不应被淘汰")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok, _ := store.Get("c"); ok {
+		t.Errorf("过期条目不应返回")
+	}
+}
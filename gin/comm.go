@@ -22,6 +22,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/Chairou/toolbox/i18n"
 	"github.com/Chairou/toolbox/logger"
 	"github.com/Chairou/toolbox/util/check"
 	"github.com/Chairou/toolbox/util/conv"
@@ -34,13 +35,13 @@ const API_INTERNAL_ERROR = -99
 const API_DB_ERROR = -98
 const API_REMOTE_ERROR = -97
 const API_ARG_ERROR = -96
+const API_TIMEOUT_ERROR = -95
+const API_BUSY_ERROR = -94
+const API_BREAKER_OPEN = -93
+const API_CONFLICT_ERROR = -92
 
-// Logger 统一的日志接口
-type Logger interface {
-	Debug(v ...interface{})
-	Info(v ...interface{})
-	Error(v ...interface{})
-}
+// Logger 统一的日志接口，即 logger.Log
+type Logger = logger.Log
 
 var logPtr Logger
 var conf1 *conf.Config
@@ -106,17 +107,10 @@ func NewServer(env string, logFileName string, middle []func(c *Context)) *gin.E
 	logPtr = log
 	r := gin.Default()
 
-	mode := env
-	switch mode {
-	case "dev":
-		gin.SetMode(gin.DebugMode)
-	case "test":
-		gin.SetMode(gin.TestMode)
-	case "release":
-		gin.SetMode(gin.ReleaseMode)
-	default:
-		gin.SetMode(gin.DebugMode)
-	}
+	profile := setProfileMode(env)
+	log.SetProfile(profile)
+	// 请求转储中可能有 Authorization、密码等，默认脱敏
+	log.SetRedactor(logger.DefaultRedactor())
 	stdRouter := &RouterGroup{
 		routerGroup: &r.RouterGroup,
 	}
@@ -131,14 +125,19 @@ func NewServer(env string, logFileName string, middle []func(c *Context)) *gin.E
 	return r
 }
 
-func NewServerWithConf(env string, conf any, middle []func(c *Context)) *gin.Engine {
+func NewServerWithConf(env string, config any, middle []func(c *Context)) *gin.Engine {
 	var err error
 	logOpt := logger.LogOpt{}
-	err = copier.Copy(&logOpt, conf)
+	err = copier.Copy(&logOpt, config)
 	if err != nil {
 		_ = fmt.Errorf("NewServerWithConf|copier.Copy err: %v", err)
 		os.Exit(1)
 	}
+	if logOpt.Profile == "" {
+		// 与 NewServer 一样按环境设置日志，env 没有注册时使用 dev
+		profile, _ := conf.ParseProfile(env)
+		logOpt.Profile = string(profile)
+	}
 
 	fmt.Printf("config : %+v", logOpt)
 	logV2, err := logger.NewLogOpt("api", &logOpt)
@@ -147,20 +146,14 @@ func NewServerWithConf(env string, conf any, middle []func(c *Context)) *gin.Eng
 		os.Exit(1)
 	}
 	logPtr = logV2
+	if logOpt.Redact == 0 && len(logOpt.RedactKeys) == 0 && len(logOpt.RedactPatterns) == 0 {
+		// 与 NewServer 一样默认脱敏，配置了脱敏规则时使用配置
+		logV2.SetRedactor(logger.DefaultRedactor())
+	}
 	r := gin.Default()
 
 	logV2.Info("START HTTP SERVER AND LOGGING NOW")
-	mode := env
-	switch mode {
-	case "dev":
-		gin.SetMode(gin.DebugMode)
-	case "test":
-		gin.SetMode(gin.TestMode)
-	case "release":
-		gin.SetMode(gin.ReleaseMode)
-	default:
-		gin.SetMode(gin.DebugMode)
-	}
+	setProfileMode(env)
 	stdRouter := &RouterGroup{
 		routerGroup: &r.RouterGroup,
 	}
@@ -176,6 +169,16 @@ func NewServerWithConf(env string, conf any, middle []func(c *Context)) *gin.Eng
 	return r
 }
 
+// setProfileMode 按环境设置 gin 运行模式，env 为空或没有注册时使用 dev
+func setProfileMode(env string) conf.Profile {
+	profile, err := conf.ParseProfile(env)
+	if env != "" && err != nil {
+		logPtr.Error(fmt.Sprintf("setProfileMode| %v, use %s", err, profile))
+	}
+	gin.SetMode(profile.Defaults().GinMode)
+	return profile
+}
+
 var routerRegisters []func(*RouterGroup)
 
 // SetRouterRegister 设置路由注册器
@@ -204,9 +207,15 @@ func (r *responseDumper) bytes() []byte {
 
 // RetJson 直接返回json串
 func (c *Context) RetJson(code int, data interface{}, messages ...interface{}) {
+	c.retJsonStatus(http.StatusOK, code, data, messages...)
+}
+
+// retJsonStatus 以指定的 HTTP 状态码返回 Ret 结构
+func (c *Context) retJsonStatus(status int, code int, data interface{}, messages ...interface{}) {
 	var ret Ret
 	ret.Code = code
 	ret.Data = data
+	c.Set("code", code)
 	seq, ok := c.Get("seq")
 	if ok {
 		ret.Seq = conv.String(seq)
@@ -219,6 +228,9 @@ func (c *Context) RetJson(code int, data interface{}, messages ...interface{}) {
 		switch v := arg.(type) {
 		case error:
 			msg.WriteString(v.Error())
+		case i18n.Message:
+			// 待翻译的消息，按请求语言翻译
+			msg.WriteString(I18n.Tm(c.Lang(), v))
 		case string:
 			// 处理string类型
 			msg.WriteString(v)
@@ -247,34 +259,52 @@ func (c *Context) RetJson(code int, data interface{}, messages ...interface{}) {
 	}
 	ret.Msg = msg.String()
 	fmt.Println(ret)
-	c.JSON(http.StatusOK, ret)
+	c.JSON(status, ret)
+
+}
+
+// log 带请求 context 中字段（request_id、trace_id 等）的日志，调用位置记录为调用 Context.Info 等方法的代码
+func (c *Context) log() *logger.FieldLogger {
+	if logPtr == nil {
+		return nil
+	}
+	return logPtr.FromContext(c.Request.Context()).WithCallerSkip(1)
+}
 
+// logMsg 消息前加上 request id，与加入 request_id 字段之前的 text 格式一致（file.go:N: <requestID> msg），
+// 已有的按前缀检索和告警不受影响
+func (c *Context) logMsg(msg string) string {
+	return c.requestID + " " + msg
+}
+
+// WithLogFields 给当前请求添加日志字段，args 为 key1, value1, key2, value2 形式，
+// 之后 Context.Info 等方法以及使用 c.Request.Context() 的 logger.FromContext 都会带上这些字段
+func (c *Context) WithLogFields(args ...any) {
+	c.Request = c.Request.WithContext(logger.WithContext(c.Request.Context(), args...))
 }
 
 // Debugf formats message according to format specifier
 // and writes to log with level = Debug.
 func (c *Context) Debugf(format string, params ...interface{}) {
-	msg := fmt.Sprintf(c.requestID+" "+format, params...)
-	if logPtr != nil {
-		logPtr.Debug(msg)
+	if l := c.log(); l != nil {
+		l.Debug(c.logMsg(fmt.Sprintf(format, params...)))
 	}
 }
 
 // Infof formats message according to format specifier
 // and writes to log with level = Info.
 func (c *Context) Infof(format string, params ...interface{}) {
-	msg := fmt.Sprintf(c.requestID+" "+format, params...)
-	if logPtr != nil {
-		logPtr.Info(msg)
+	if l := c.log(); l != nil {
+		l.Info(c.logMsg(fmt.Sprintf(format, params...)))
 	}
 }
 
 // Errorf formats message according to format specifier
 // and writes to log with level = Error.
 func (c *Context) Errorf(format string, params ...interface{}) error {
-	msg := c.requestID + " " + fmt.Sprintf(format, params...)
-	if logPtr != nil {
-		logPtr.Error(msg)
+	msg := c.logMsg(fmt.Sprintf(format, params...))
+	if l := c.log(); l != nil {
+		l.Error(msg)
 	}
 	return errors.New(msg)
 }
@@ -282,18 +312,32 @@ func (c *Context) Errorf(format string, params ...interface{}) error {
 // Debug formats message using the default formats for its operands
 // and writes to log with level = Debug
 func (c *Context) Debug(v ...interface{}) {
-	msg := c.requestID + " " + fmt.Sprint(v...)
-	if logPtr != nil {
-		logPtr.Debug(msg)
+	if l := c.log(); l != nil {
+		l.Debug(c.logMsg(fmt.Sprint(v...)))
 	}
 }
 
 // Info formats message using the default formats for its operands
 // and writes to log with level = Info
 func (c *Context) Info(v ...interface{}) {
-	msg := c.requestID + " " + fmt.Sprint(v...)
-	if logPtr != nil {
-		logPtr.Info(msg)
+	if l := c.log(); l != nil {
+		l.Info(c.logMsg(fmt.Sprint(v...)))
+	}
+}
+
+// Warnf formats message according to format specifier
+// and writes to log with level = Warn.
+func (c *Context) Warnf(format string, params ...interface{}) {
+	if l := c.log(); l != nil {
+		l.Warn(c.logMsg(fmt.Sprintf(format, params...)))
+	}
+}
+
+// Warn formats message using the default formats for its operands
+// and writes to log with level = Warn
+func (c *Context) Warn(v ...interface{}) {
+	if l := c.log(); l != nil {
+		l.Warn(c.logMsg(fmt.Sprint(v...)))
 	}
 }
 
@@ -301,8 +345,8 @@ func (c *Context) Info(v ...interface{}) {
 // and writes to log with level = Error
 func (c *Context) Error(v ...interface{}) error {
 	msg := fmt.Sprint(v...)
-	if logPtr != nil {
-		logPtr.Error(c.requestID + " " + msg)
+	if l := c.log(); l != nil {
+		l.Error(c.logMsg(msg))
 	}
 	return errors.New(msg)
 }
@@ -315,7 +359,7 @@ func (c *Context) GetPager() (pageIndex, pageSize uint, code int, err error) {
 	} else {
 		mPageIndex, err := strconv.Atoi(pIndex)
 		if err != nil || mPageIndex <= 0 {
-			return pageIndex, pageSize, 59998, c.Error("pageIndex is invalid, pageIndex：" + pIndex)
+			return pageIndex, pageSize, 59998, c.Error(c.T("pager.index_invalid", "value", pIndex))
 		}
 		pageIndex = uint(mPageIndex)
 	}
@@ -325,7 +369,7 @@ func (c *Context) GetPager() (pageIndex, pageSize uint, code int, err error) {
 	} else {
 		mPageSize, err := strconv.Atoi(pSize)
 		if err != nil || mPageSize <= 0 {
-			return pageIndex, pageSize, 59999, c.Error("pageSize is invalid, pageSize：" + pSize)
+			return pageIndex, pageSize, 59999, c.Error(c.T("pager.size_invalid", "value", pSize))
 		}
 		pageSize = uint(mPageSize)
 	}
@@ -362,7 +406,7 @@ func (c *Context) GetConditionByParam(parConstruct map[string]*ParamConstruct) (
 		strParam := c.Query(k)
 		//1.无值且必传,直接返回
 		if len(strParam) == 0 && v.Need {
-			return "", nil, "", c.Error("need param " + k + " is null.")
+			return "", nil, "", c.Error(c.T("param.need", "name", k))
 		}
 		//2.验证参数值
 		if len(strParam) > 0 {
@@ -423,7 +467,7 @@ func (c *Context) CheckParam(key string, param interface{}, defaultValue []inter
 	c.Info("CheckParam key:", key, ";param:", param, ";defaultValue:", defaultValue)
 	//1.参数值为空,返回错误
 	if param == nil || conv.String(param) == "" {
-		return c.Error("param " + key + " is null.")
+		return c.Error(c.T("param.null", "name", key))
 	}
 	//2.没有定义取值范围数组,表示参数有值,正常返回nil
 	if defaultValue == nil || len(defaultValue) == 0 {
@@ -442,7 +486,7 @@ func (c *Context) CheckParam(key string, param interface{}, defaultValue []inter
 		return nil
 	}
 	//不在取值范围内,返回错误
-	return c.Error("param " + key + " value is error.")
+	return c.Error(c.T("param.invalid", "name", key))
 }
 
 // genCondition 生成条件
@@ -637,7 +681,7 @@ func (c *Context) GetBaseConditionByParam(parConstruct map[string]*ParamConstruc
 		strParam := c.Query(k)
 		//1.无值且必传,直接返回
 		if len(strParam) == 0 && v.Need {
-			return "", nil, c.Error("need param " + k + " is null.")
+			return "", nil, c.Error(c.T("param.need", "name", k))
 		}
 		//2.验证参数值
 		if len(strParam) > 0 {
@@ -656,7 +700,7 @@ func (c *Context) GetBaseConditionByParam(parConstruct map[string]*ParamConstruc
 		switch k {
 		case "orderBy":
 			//orderBy特殊处理
-			return "", nil, c.Error("orderBy not allowed here.")
+			return "", nil, c.Error(c.T("param.order_by_not_allowed"))
 		case "searchKey":
 			//searchKey特殊处理,模糊查找无值不需要执行like
 			strSearchKey, agsSearchKey := GenBaseSearchKeyWhere(strParam, v.FieldName, v.Symbol)
@@ -668,7 +712,7 @@ func (c *Context) GetBaseConditionByParam(parConstruct map[string]*ParamConstruc
 				args = append(args, agsSearchKey...)
 			}
 		case "accessPerson":
-			return "", nil, c.Error("accessPerson not allowed here.")
+			return "", nil, c.Error(c.T("param.access_person_not_allowed"))
 		default:
 			//其它参数
 			arg := genArgs(strParam, v)
@@ -724,6 +768,8 @@ type HandlerFunc func(*Context)
 // RouterGroup wrap gin RouterGroup
 type RouterGroup struct {
 	routerGroup *gin.RouterGroup
+	routes      *routeTable  // 路由元数据表，同一个根路由下的分组共享
+	lastRoutes  []*RouteInfo // 最近一次注册的路由，供 Doc 附加文档
 }
 
 func (r *RouterGroup) SetGroup(rg *gin.RouterGroup) {
@@ -799,6 +845,41 @@ func wrapHandlers(handlers []HandlerFunc) []gin.HandlerFunc {
 	return ginHandlers
 }
 
+// getContext 取出或创建请求的 Context，创建时把 request_id、trace_id 和 api 日志放入 c.Request 的 context，
+// 下层的 service、httphelper 等通过 logger.FromContext(ctx) 记录日志时会带上这些字段
+func getContext(c *gin.Context, requestID string) *Context {
+	if v, exists := c.Get(_ContextKey); exists {
+		return v.(*Context)
+	}
+	ctx := &Context{
+		Context:   c,
+		requestID: strings.ReplaceAll(requestID, "-", ""),
+	}
+	fields := []any{logger.FieldRequestID, ctx.requestID}
+	if traceID := traceIDFromHeader(c.Request.Header.Get("traceparent")); traceID != "" {
+		fields = append(fields, logger.FieldTraceID, traceID)
+	}
+	reqCtx := logger.WithContext(c.Request.Context(), fields...)
+	if logPtr != nil {
+		reqCtx = logger.WithLogger(reqCtx, logPtr)
+	}
+	c.Request = c.Request.WithContext(reqCtx)
+	c.Set(_ContextKey, ctx)
+	return ctx
+}
+
+// traceIDFromHeader 从 W3C traceparent 头（version-traceid-parentid-flags）中取出 trace id，格式不对时返回空
+func traceIDFromHeader(traceparent string) string {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) < 4 || len(parts[1]) != 32 || parts[1] == strings.Repeat("0", 32) {
+		return ""
+	}
+	if _, err := hex.DecodeString(parts[1]); err != nil {
+		return ""
+	}
+	return parts[1]
+}
+
 func wrapHandler(h HandlerFunc) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
@@ -816,17 +897,7 @@ func wrapHandler(h HandlerFunc) gin.HandlerFunc {
 				c.Writer.Header().Set("X-Request-Id", requestID)
 			}
 		}
-		context, exists := c.Get(_ContextKey)
-		var ctx *Context
-		if exists {
-			ctx = context.(*Context)
-		} else {
-			ctx = &Context{
-				Context:   c,
-				requestID: strings.ReplaceAll(requestID, "-", ""),
-			}
-			c.Set(_ContextKey, ctx)
-		}
+		ctx := getContext(c, requestID)
 
 		defer func() {
 			if err := recover(); err != nil {
@@ -886,6 +957,7 @@ func wrapHandler(h HandlerFunc) gin.HandlerFunc {
 func (group *RouterGroup) Group(relativePath string, handlers ...HandlerFunc) *RouterGroup {
 	return &RouterGroup{
 		routerGroup: group.routerGroup.Group(relativePath, wrapHandlers(handlers)...),
+		routes:      group.table(),
 	}
 }
 
@@ -916,7 +988,9 @@ func (group *RouterGroup) Handle(httpMethod, relativePath string, handlers ...Ha
 	group.routerGroup.Handle(httpMethod, relativePath, wrapHandlers(handlers)...)
 	gin.SetMode(mode)
 
-	debugPrintRoute(httpMethod, joinPaths(group.BasePath(), relativePath), handlers)
+	absolutePath := joinPaths(group.BasePath(), relativePath)
+	debugPrintRoute(httpMethod, absolutePath, handlers)
+	group.lastRoutes = []*RouteInfo{group.table().add(httpMethod, absolutePath, nameOfFunction(last(handlers)))}
 	return group
 }
 
@@ -965,15 +1039,12 @@ func (group *RouterGroup) HEAD(relativePath string, handlers ...HandlerFunc) *Ro
 // Any registers a route that matches all the HTTP methods.
 // GET, POST, PUT, PATCH, HEAD, OPTIONS, DELETE, CONNECT, TRACE.
 func (group *RouterGroup) Any(relativePath string, handlers ...HandlerFunc) *RouterGroup {
-	group.Handle("GET", relativePath, handlers...)
-	group.Handle("POST", relativePath, handlers...)
-	group.Handle("PUT", relativePath, handlers...)
-	group.Handle("PATCH", relativePath, handlers...)
-	group.Handle("HEAD", relativePath, handlers...)
-	group.Handle("OPTIONS", relativePath, handlers...)
-	group.Handle("DELETE", relativePath, handlers...)
-	group.Handle("CONNECT", relativePath, handlers...)
-	group.Handle("TRACE", relativePath, handlers...)
+	var routes []*RouteInfo
+	for _, method := range anyMethods {
+		group.Handle(method, relativePath, handlers...)
+		routes = append(routes, group.lastRoutes...)
+	}
+	group.lastRoutes = routes
 	return group
 }
 
@@ -993,7 +1064,9 @@ func (group *RouterGroup) StdHandle(httpMethod, relativePath string, handlers ..
 	group.routerGroup.Handle(httpMethod, relativePath, wrapStdHandlers(handlers)...)
 	gin.SetMode(mode)
 
-	debugPrintStdRoute(httpMethod, joinPaths(group.BasePath(), relativePath), handlers)
+	absolutePath := joinPaths(group.BasePath(), relativePath)
+	debugPrintStdRoute(httpMethod, absolutePath, handlers)
+	group.lastRoutes = []*RouteInfo{group.table().add(httpMethod, absolutePath, nameOfFunction(lastStd(handlers)))}
 	return group
 }
 
@@ -1042,15 +1115,12 @@ func (group *RouterGroup) StdHEAD(relativePath string, handlers ...StdHandlerFun
 // StdAny registers a route that matches all the HTTP methods.
 // GET, POST, PUT, PATCH, HEAD, OPTIONS, DELETE, CONNECT, TRACE.
 func (group *RouterGroup) StdAny(relativePath string, handlers ...StdHandlerFunc) *RouterGroup {
-	group.StdHandle("GET", relativePath, handlers...)
-	group.StdHandle("POST", relativePath, handlers...)
-	group.StdHandle("PUT", relativePath, handlers...)
-	group.StdHandle("PATCH", relativePath, handlers...)
-	group.StdHandle("HEAD", relativePath, handlers...)
-	group.StdHandle("OPTIONS", relativePath, handlers...)
-	group.StdHandle("DELETE", relativePath, handlers...)
-	group.StdHandle("CONNECT", relativePath, handlers...)
-	group.StdHandle("TRACE", relativePath, handlers...)
+	var routes []*RouteInfo
+	for _, method := range anyMethods {
+		group.StdHandle(method, relativePath, handlers...)
+		routes = append(routes, group.lastRoutes...)
+	}
+	group.lastRoutes = routes
 	return group
 }
 
@@ -1089,17 +1159,7 @@ func wrapMiddleware(h HandlerFunc) gin.HandlerFunc {
 			}
 		}
 
-		context, exists := c.Get(_ContextKey)
-		var ctx *Context
-		if exists {
-			ctx = context.(*Context)
-		} else {
-			ctx = &Context{
-				Context:   c,
-				requestID: strings.ReplaceAll(requestID, "-", ""),
-			}
-			c.Set(_ContextKey, ctx)
-		}
+		ctx := getContext(c, requestID)
 		h(ctx)
 	}
 }
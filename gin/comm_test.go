@@ -1,8 +1,14 @@
 package gin
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/Chairou/toolbox/logger"
 )
 
 type NestedStruct struct {
@@ -92,3 +98,65 @@ func TestNewGormTagParser(t *testing.T) {
 	column, _ := parser.Get("column")
 	t.Logf("%+v", column)
 }
+
+func TestContext_LogFields(t *testing.T) {
+	lp, err := logger.NewLogOpt("gin_ctx_log", &logger.LogOpt{FileName: "log/gin_ctx_log.log"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		logger.Unregister("gin_ctx_log")
+		_ = lp.Close()
+		_ = os.RemoveAll("log")
+	}()
+	mem := logger.NewMemorySink(10, logger.TRACE_LEVEL, nil)
+	lp.AddSink(mem)
+	old := logPtr
+	logPtr = lp
+	defer func() { logPtr = old }()
+
+	r, group := newResilienceRouter()
+	group.GET("/log", func(c *Context) {
+		c.WithLogFields(logger.FieldUser, "alice")
+		c.Infof("handle %d", 1)
+		logger.FromContext(c.Request.Context()).Info("service")
+		c.RetJson(API_OK, "ok")
+	})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/log", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(w, req)
+
+	requestID := w.Header().Get("X-Request-Id")
+	var handled, service *logger.Record
+	records := mem.Records()
+	for i := range records {
+		switch records[i].Msg {
+		case requestID + " handle 1":
+			handled = &records[i]
+		case "service":
+			service = &records[i]
+		}
+	}
+	if handled == nil || service == nil {
+		t.Fatalf("缺少日志: %v", mem.Lines())
+	}
+	for _, rec := range []*logger.Record{handled, service} {
+		want := map[string]any{
+			logger.FieldRequestID: requestID,
+			logger.FieldTraceID:   "4bf92f3577b34da6a3ce929d0e0e4736",
+			logger.FieldUser:      "alice",
+		}
+		for _, f := range rec.Fields {
+			if v, ok := want[f.Key]; ok && v == f.Value {
+				delete(want, f.Key)
+			}
+		}
+		if len(want) != 0 {
+			t.Errorf("%s 缺少字段 %v: %v", rec.Msg, want, rec.Fields)
+		}
+		if !strings.HasPrefix(rec.Caller, "comm_test.go:") {
+			t.Errorf("调用位置应为处理函数, 实际 %s", rec.Caller)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package gin
+
+import (
+	"github.com/Chairou/toolbox/flags"
+)
+
+// FlagTarget 当前请求的功能开关求值对象，按 UserName 灰度，IP 为客户端地址。
+// 需要环境或自定义属性时在返回值上补充
+func (c *Context) FlagTarget() flags.Target {
+	return flags.Target{
+		UserName: c.UserName,
+		IP:       c.ClientIP(),
+	}
+}
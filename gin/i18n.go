@@ -0,0 +1,58 @@
+package gin
+
+import (
+	"embed"
+
+	"github.com/Chairou/toolbox/i18n"
+)
+
+//go:embed locales/*.yaml
+var localeFS embed.FS
+
+const _LangKey = "__Lang__"
+
+// defaultLang I18n 的默认语言，内置消息原来大多是英文
+const defaultLang = "en"
+
+// I18n gin 包使用的消息目录，内置 zh、en 两种语言，默认 en，没有带 Accept-Language 的请求使用默认语言。
+// 安全检查的拒绝消息原来是中文，需要保持时调用 I18n.SetDefaultLang("zh")。
+// 业务可以加载自己的消息文件，与内置消息共用：
+//
+//	_ = gin.I18n.LoadFile("locales/en.yaml")
+//	c.RetJson(API_ARG_ERROR, nil, i18n.M("order.not_found", "id", id))
+var I18n = newI18n()
+
+func newI18n() *i18n.Bundle {
+	b := i18n.NewBundle(defaultLang)
+	if err := b.LoadFS(localeFS, "locales"); err != nil {
+		panic("load gin locales err: " + err.Error())
+	}
+	return b
+}
+
+// Lang 根据 Accept-Language 协商的请求语言，同一个请求只协商一次
+func (c *Context) Lang() string {
+	if lang := c.GetString(_LangKey); lang != "" {
+		return lang
+	}
+	accept := c.Request.Header.Get("Accept-Language")
+	if accept == "" {
+		// 不缓存，后续 SetLang 或 I18n.SetDefaultLang 仍然生效
+		return I18n.DefaultLang()
+	}
+	lang := I18n.Match(accept)
+	c.Set(_LangKey, lang)
+	return lang
+}
+
+// SetLang 指定请求语言，例如按用户设置覆盖 Accept-Language
+func (c *Context) SetLang(lang string) {
+	c.Set(_LangKey, lang)
+}
+
+// T 按请求语言翻译消息，kv 为成对的参数名和参数值
+//
+//	c.T("param.null", "name", "id")
+func (c *Context) T(key string, kv ...interface{}) string {
+	return I18n.T(c.Lang(), key, i18n.Pairs(kv...))
+}
@@ -0,0 +1,105 @@
+package gin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Chairou/toolbox/i18n"
+)
+
+func TestI18n_SafeCheckEnglish(t *testing.T) {
+	r := setupTestRouter()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader(`{"name":"1 union select 1"}`))
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	r.ServeHTTP(w, req)
+
+	var resp map[string]string
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusForbidden || resp["message"] != "Access forbidden" {
+		t.Errorf("英文请求应返回英文消息, 实际 %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestI18n_CheckParam(t *testing.T) {
+	r, group := newResilienceRouter()
+	group.GET("/check", func(c *Context) {
+		err := c.CheckParam("id", "", nil)
+		c.RetJson(API_ARG_ERROR, nil, err)
+	})
+	group.GET("/msg", func(c *Context) {
+		c.RetJson(API_TIMEOUT_ERROR, nil, i18n.M("request.timeout"))
+	})
+
+	cases := []struct {
+		path, lang, want string
+	}{
+		{"/check", "en", "param id is null."},
+		{"/check", "zh-CN", "参数 id 为空。"},
+		{"/check", "", "param id is null."},
+		{"/msg", "en", "request timeout."},
+		{"/msg", "zh", "请求超时。"},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.lang != "" {
+			req.Header.Set("Accept-Language", tc.lang)
+		}
+		r.ServeHTTP(w, req)
+		var ret Ret
+		_ = json.Unmarshal(w.Body.Bytes(), &ret)
+		if ret.Msg != tc.want {
+			t.Errorf("%s Accept-Language=%q 期望 %q, 实际 %q", tc.path, tc.lang, tc.want, ret.Msg)
+		}
+	}
+}
+
+func TestI18n_DefaultLang(t *testing.T) {
+	r := setupTestRouter()
+	forbidden := func(lang string) string {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader(`{"name":"1 union select 1"}`))
+		if lang != "" {
+			req.Header.Set("Accept-Language", lang)
+		}
+		r.ServeHTTP(w, req)
+		var resp map[string]string
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp["message"]
+	}
+	if msg := forbidden(""); msg != "Access forbidden" {
+		t.Errorf("没有 Accept-Language 时应使用默认语言, 实际 %q", msg)
+	}
+	if msg := forbidden("fr"); msg != "Access forbidden" {
+		t.Errorf("无法协商的语言应使用默认语言, 实际 %q", msg)
+	}
+
+	I18n.SetDefaultLang("zh")
+	defer I18n.SetDefaultLang(defaultLang)
+	if msg := forbidden(""); msg != "访问被禁止" {
+		t.Errorf("修改默认语言后应使用该语言, 实际 %q", msg)
+	}
+	if msg := forbidden("en"); msg != "Access forbidden" {
+		t.Errorf("英文请求应返回英文消息, 实际 %q", msg)
+	}
+}
+
+func TestI18n_CatalogsComplete(t *testing.T) {
+	// 内置的中英文消息 key 必须一致
+	for _, key := range []string{
+		"safe.forbidden", "param.null", "param.invalid", "param.need",
+		"param.order_by_not_allowed", "param.access_person_not_allowed",
+		"pager.index_invalid", "pager.size_invalid", "request.timeout",
+		"server.busy", "server.breaker_open", "idempotency.in_progress", "idempotency.key_reused",
+	} {
+		zh := I18n.T("zh", key, nil)
+		en := I18n.T("en", key, nil)
+		if zh == key || en == key || zh == en {
+			t.Errorf("消息 %s 缺少翻译: zh=%q en=%q", key, zh, en)
+		}
+	}
+}
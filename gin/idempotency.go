@@ -0,0 +1,267 @@
+package gin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Chairou/toolbox/i18n"
+	"github.com/Chairou/toolbox/util/listopt"
+	"github.com/Chairou/toolbox/util/redis"
+	"github.com/google/uuid"
+)
+
+// IdempotencyStore 幂等记录存储，*redis.RdPool 直接满足该接口。
+// Get 在 key 不存在时需返回 redis.ErrNil
+type IdempotencyStore interface {
+	SetNX(key string, value interface{}, expire int) (int64, error)
+	SetEX(key string, value interface{}, expire int) (string, error)
+	Get(key string) (string, error)
+	Del(key string) (int64, error)
+}
+
+// idempotencyCommander 可以执行任意命令的存储，*redis.RdPool 满足该接口，保存响应和释放锁时用 Lua 脚本原子地比较并写入或删除；
+// 其他存储先 Get 比较再 SetEX 或 Del
+type idempotencyCommander interface {
+	Do(commandName string, args ...interface{}) (interface{}, error)
+}
+
+// idempotencyUnlockScript 值仍是本请求写入的锁时才删除
+const idempotencyUnlockScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) end return 0`
+
+// idempotencySaveScript 值仍是本请求写入的锁时才保存响应
+const idempotencySaveScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then redis.call("SET", KEYS[1], ARGV[2], "EX", ARGV[3]) return 1 end return 0`
+
+// IdempotencyOpt 幂等中间件参数
+type IdempotencyOpt struct {
+	// Store 幂等记录存储，一般为 redis.GetRedisByName 返回的连接池
+	Store IdempotencyStore
+	// TTL 响应保存时长，期间相同 key 的请求直接重放，默认 24 小时
+	TTL time.Duration
+	// LockTTL 处理中状态的锁时长，处理函数卡死时锁到期自动释放，默认 1 分钟
+	LockTTL time.Duration
+	// Header 幂等键请求头，默认 Idempotency-Key
+	Header string
+	// KeyPrefix redis key 前缀，默认 idempotency:
+	KeyPrefix string
+	// Methods 启用幂等的方法，默认 POST、PUT、PATCH
+	Methods []string
+}
+
+const (
+	idempotencyProcessing = "processing"
+	idempotencyDone       = "done"
+
+	// IdempotentReplayedHeader 重放的响应会带上该响应头
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// idempotencyRecord 保存在存储中的幂等记录
+type idempotencyRecord struct {
+	State       string `json:"state"`
+	Fingerprint string `json:"fingerprint"`
+	// Token 加锁请求的随机标识，释放锁时只删除自己的锁
+	Token       string `json:"token,omitempty"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// Idempotency 幂等中间件。请求带有 Idempotency-Key 头时：
+//  1. 首个请求通过 SetNX 加锁并正常处理，通过 RetJson 输出的最终状态码和响应体保存 TTL 时长
+//  2. TTL 内的重复请求直接重放保存的响应，并带上 Idempotent-Replayed: true 响应头
+//  3. 首个请求处理中时，并发的重复请求返回 409 和 API_CONFLICT_ERROR
+//  4. 同一个 key 对应的请求方法、路径或请求体不同时返回 422
+//
+// 处理失败（HTTP 5xx、panic、内部错误/数据库错误/远程调用错误/超时）时不保存响应，客户端可以用同一个 key 重试。
+// 存储不可用时放行请求，不影响业务。
+func Idempotency(opt IdempotencyOpt) HandlerFunc {
+	if opt.TTL <= 0 {
+		opt.TTL = 24 * time.Hour
+	}
+	if opt.LockTTL <= 0 {
+		opt.LockTTL = time.Minute
+	}
+	if opt.Header == "" {
+		opt.Header = "Idempotency-Key"
+	}
+	if opt.KeyPrefix == "" {
+		opt.KeyPrefix = "idempotency:"
+	}
+	if len(opt.Methods) == 0 {
+		opt.Methods = []string{http.MethodPost, http.MethodPut, http.MethodPatch}
+	}
+
+	return func(c *Context) {
+		idemKey := c.Request.Header.Get(opt.Header)
+		if idemKey == "" || opt.Store == nil || !listopt.IsInStringArr(opt.Methods, c.Request.Method) {
+			c.Next()
+			return
+		}
+		key := opt.KeyPrefix + c.UserName + ":" + idemKey
+		fingerprint, err := requestFingerprint(c)
+		if err != nil {
+			_ = c.Errorf("idempotency read body err: %v", err)
+			c.Next()
+			return
+		}
+
+		lock, locked, err := lockIdempotencyKey(opt, key, fingerprint)
+		if err != nil {
+			_ = c.Errorf("idempotency lock %s err: %v", key, err)
+			c.Next()
+			return
+		}
+		if !locked {
+			replayIdempotentResponse(c, opt, key, fingerprint)
+			return
+		}
+
+		blw := &bodyLogWriter{body: bytes.NewBufferString(""), ResponseWriter: c.Writer}
+		c.Writer = blw
+		saved := false
+		defer func() {
+			// panic 或处理失败时释放锁，允许客户端重试。处理超过 LockTTL 时锁可能已经属于其他请求，不能删除
+			if !saved {
+				if err := unlockIdempotencyKey(opt.Store, key, lock); err != nil {
+					_ = c.Errorf("idempotency unlock %s err: %v", key, err)
+				}
+			}
+		}()
+
+		c.Next()
+
+		if _, ok := c.Get("code"); !ok || requestFailed(c) {
+			return
+		}
+		record := idempotencyRecord{
+			State:       idempotencyDone,
+			Fingerprint: fingerprint,
+			Status:      blw.Status(),
+			ContentType: blw.Header().Get("Content-Type"),
+			Body:        blw.body.Bytes(),
+		}
+		data, _ := json.Marshal(record)
+		owned, err := saveIdempotentResponse(opt, key, lock, string(data))
+		if err != nil {
+			_ = c.Errorf("idempotency save %s err: %v", key, err)
+			return
+		}
+		if !owned {
+			// 处理超过 LockTTL，锁已经属于其他请求，不能覆盖它的状态
+			c.Infof("idempotency save %s skipped: lock expired", key)
+		}
+		saved = true
+	}
+}
+
+// saveIdempotentResponse 幂等键的值仍是 lock 时保存响应，返回是否保存
+func saveIdempotentResponse(opt IdempotencyOpt, key, lock, data string) (bool, error) {
+	if cmd, ok := opt.Store.(idempotencyCommander); ok {
+		ret, err := cmd.Do("EVAL", idempotencySaveScript, 1, key, lock, data, ttlSeconds(opt.TTL))
+		n, _ := ret.(int64)
+		return n == 1, err
+	}
+	str, err := opt.Store.Get(key)
+	if errors.Is(err, redis.ErrNil) {
+		return false, nil
+	}
+	if err != nil || str != lock {
+		return false, err
+	}
+	_, err = opt.Store.SetEX(key, data, ttlSeconds(opt.TTL))
+	return err == nil, err
+}
+
+// lockIdempotencyKey 通过 SetNX 抢占幂等键，返回写入的锁和是否抢到
+func lockIdempotencyKey(opt IdempotencyOpt, key, fingerprint string) (string, bool, error) {
+	data, _ := json.Marshal(idempotencyRecord{State: idempotencyProcessing, Fingerprint: fingerprint, Token: uuid.New().String()})
+	n, err := opt.Store.SetNX(key, string(data), ttlSeconds(opt.LockTTL))
+	if err != nil {
+		return "", false, err
+	}
+	return string(data), n == 1, nil
+}
+
+// unlockIdempotencyKey 幂等键的值仍是 lock 时删除
+func unlockIdempotencyKey(store IdempotencyStore, key, lock string) error {
+	if cmd, ok := store.(idempotencyCommander); ok {
+		_, err := cmd.Do("EVAL", idempotencyUnlockScript, 1, key, lock)
+		return err
+	}
+	str, err := store.Get(key)
+	if errors.Is(err, redis.ErrNil) {
+		return nil
+	}
+	if err != nil || str != lock {
+		return err
+	}
+	_, err = store.Del(key)
+	return err
+}
+
+// replayIdempotentResponse 处理未抢到幂等键的重复请求
+func replayIdempotentResponse(c *Context, opt IdempotencyOpt, key, fingerprint string) {
+	str, err := opt.Store.Get(key)
+	if errors.Is(err, redis.ErrNil) {
+		// 首个请求刚好处理失败释放了锁，按处理中返回，客户端稍后重试
+		str, err = "", nil
+	}
+	if err != nil {
+		_ = c.Errorf("idempotency get %s err: %v", key, err)
+		c.Next()
+		return
+	}
+
+	var record idempotencyRecord
+	if str != "" {
+		if err = json.Unmarshal([]byte(str), &record); err != nil {
+			_ = c.Errorf("idempotency decode %s err: %v", key, err)
+			c.Next()
+			return
+		}
+	}
+	if record.Fingerprint != "" && record.Fingerprint != fingerprint {
+		c.retJsonStatus(http.StatusUnprocessableEntity, API_ARG_ERROR, nil, i18n.M("idempotency.key_reused"))
+		c.Abort()
+		return
+	}
+	if record.State != idempotencyDone {
+		c.retJsonStatus(http.StatusConflict, API_CONFLICT_ERROR, nil, i18n.M("idempotency.in_progress"))
+		c.Abort()
+		return
+	}
+
+	c.Infof("idempotency replay %s", key)
+	c.Header(IdempotentReplayedHeader, "true")
+	c.Data(record.Status, record.ContentType, record.Body)
+	c.Abort()
+}
+
+// requestFingerprint 请求指纹：方法、路径、请求体的 sha256
+func requestFingerprint(c *Context) (string, error) {
+	h := sha256.New()
+	h.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+	if c.Request.Body != nil && c.Request.Body != http.NoBody {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return "", err
+		}
+		_ = c.Request.Body.Close()
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		h.Write(body)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func ttlSeconds(d time.Duration) int {
+	if s := int(d / time.Second); s > 0 {
+		return s
+	}
+	return 1
+}
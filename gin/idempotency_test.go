@@ -0,0 +1,174 @@
+package gin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Chairou/toolbox/util/redis"
+)
+
+// memIdempotencyStore 测试用的内存存储，忽略过期时间
+type memIdempotencyStore struct {
+	mu   sync.Mutex
+	data map[string]string
+}
+
+func newMemIdempotencyStore() *memIdempotencyStore {
+	return &memIdempotencyStore{data: make(map[string]string)}
+}
+
+func (s *memIdempotencyStore) SetNX(key string, value interface{}, expire int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data[key]; ok {
+		return 0, nil
+	}
+	s.data[key] = value.(string)
+	return 1, nil
+}
+
+func (s *memIdempotencyStore) SetEX(key string, value interface{}, expire int) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = value.(string)
+	return "OK", nil
+}
+
+func (s *memIdempotencyStore) Get(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.data[key]
+	if !ok {
+		return "", redis.ErrNil
+	}
+	return v, nil
+}
+
+func (s *memIdempotencyStore) Del(key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, key)
+	return 1, nil
+}
+
+func postWithKey(r http.Handler, key, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency_Replay(t *testing.T) {
+	r, group := newResilienceRouter()
+	store := newMemIdempotencyStore()
+	created := 0
+	group.POST("/order", Idempotency(IdempotencyOpt{Store: store}), func(c *Context) {
+		created++
+		c.RetJson(API_OK, created)
+	})
+
+	first := postWithKey(r, "k1", `{"a":1}`)
+	second := postWithKey(r, "k1", `{"a":1}`)
+	if created != 1 {
+		t.Fatalf("重复请求不应再次执行处理函数, 执行次数 %d", created)
+	}
+	if first.Body.String() != second.Body.String() {
+		t.Errorf("重放响应不一致: %s / %s", first.Body.String(), second.Body.String())
+	}
+	if second.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("重放响应应带有 %s 头", IdempotentReplayedHeader)
+	}
+
+	if w := postWithKey(r, "k1", `{"a":2}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("同一个 key 不同请求体应返回 422, 实际 %d", w.Code)
+	}
+	postWithKey(r, "", `{"a":1}`)
+	if created != 2 {
+		t.Errorf("没有幂等键的请求应正常执行")
+	}
+}
+
+func TestIdempotency_Conflict(t *testing.T) {
+	r, group := newResilienceRouter()
+	store := newMemIdempotencyStore()
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	group.POST("/order", Idempotency(IdempotencyOpt{Store: store}), func(c *Context) {
+		close(entered)
+		<-release
+		c.RetJson(API_OK, nil)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		postWithKey(r, "k2", "")
+	}()
+	<-entered
+	if w := postWithKey(r, "k2", ""); w.Code != http.StatusConflict {
+		t.Errorf("并发重复请求应返回 409, 实际 %d", w.Code)
+	}
+	close(release)
+	<-done
+}
+
+func TestIdempotency_FailureReleasesKey(t *testing.T) {
+	r, group := newResilienceRouter()
+	store := newMemIdempotencyStore()
+	calls := 0
+	group.POST("/order", Idempotency(IdempotencyOpt{Store: store}), func(c *Context) {
+		calls++
+		if calls == 1 {
+			c.RetJson(API_DB_ERROR, nil, "db error")
+			return
+		}
+		c.RetJson(API_OK, nil)
+	})
+
+	postWithKey(r, "k3", "")
+	postWithKey(r, "k3", "")
+	if calls != 2 {
+		t.Errorf("处理失败后应允许使用同一个 key 重试, 执行次数 %d", calls)
+	}
+}
+
+func TestIdempotency_FailureKeepsOthersLock(t *testing.T) {
+	r, group := newResilienceRouter()
+	store := newMemIdempotencyStore()
+	group.POST("/order", Idempotency(IdempotencyOpt{Store: store}), func(c *Context) {
+		// 处理超过 LockTTL，锁过期后被另一个请求抢到
+		for k := range store.data {
+			_, _ = store.SetEX(k, `{"state":"processing","fingerprint":"x","token":"other"}`, 60)
+		}
+		c.RetJson(API_DB_ERROR, nil, "db error")
+	})
+
+	postWithKey(r, "k4", "")
+	// 未登录时 key 为前缀 + 空用户名 + 幂等键
+	if v, err := store.Get("idempotency::k4"); err != nil || !strings.Contains(v, `"token":"other"`) {
+		t.Errorf("处理失败时不应删除其他请求的锁: %q %v", v, err)
+	}
+}
+
+func TestIdempotency_SlowKeepsOthersLock(t *testing.T) {
+	r, group := newResilienceRouter()
+	store := newMemIdempotencyStore()
+	group.POST("/order", Idempotency(IdempotencyOpt{Store: store}), func(c *Context) {
+		// 处理超过 LockTTL，锁过期后被另一个请求抢到
+		for k := range store.data {
+			_, _ = store.SetEX(k, `{"state":"processing","fingerprint":"x","token":"other"}`, 60)
+		}
+		c.RetJson(API_OK, nil)
+	})
+
+	postWithKey(r, "k5", "")
+	if v, err := store.Get("idempotency::k5"); err != nil || !strings.Contains(v, `"token":"other"`) {
+		t.Errorf("锁过期后不应覆盖其他请求的状态: %q %v", v, err)
+	}
+}
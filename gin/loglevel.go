@@ -0,0 +1,71 @@
+package gin
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Chairou/toolbox/logger"
+)
+
+// LogLevelReq 修改日志级别的请求
+type LogLevelReq struct {
+	// Logger 日志名，为空时使用 api 日志，见 logger.Names
+	Logger string `json:"logger"`
+	// Package 调用方的包路径前缀，例如 myapp/payment，为空时修改日志本身的级别
+	Package string `json:"package"`
+	// Level trace、debug、info、warn、error、fatal，Package 不为空时为空字符串表示删除该包的级别
+	Level string `json:"level"`
+	// Duration 自动恢复的时间，例如 10m，为空时永久修改
+	Duration string `json:"duration"`
+}
+
+// ServeLogLevel 在路由组下注册查看和修改日志级别的接口，应挂在需要鉴权的路由组下：
+//
+//	GET <group>/log/level  全部日志的级别，以及按包设置的级别和自动恢复时间
+//	PUT <group>/log/level  修改级别，请求体为 LogLevelReq，例如 {"package":"myapp/payment","level":"debug","duration":"10m"}
+//
+// 线上临时开启 DEBUG 时设置 duration，到期自动恢复为修改前的级别
+func (group *RouterGroup) ServeLogLevel() *RouterGroup {
+	group.GET("/log/level", func(c *Context) {
+		c.RetJson(API_OK, logger.Levels())
+	})
+	group.PUT("/log/level", func(c *Context) {
+		var req LogLevelReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.RetJson(API_ARG_ERROR, nil, err)
+			return
+		}
+		if err := changeLogLevel(req); err != nil {
+			c.RetJson(API_ARG_ERROR, nil, err)
+			return
+		}
+		c.Infof("log level changed: %+v", req)
+		c.RetJson(API_OK, logger.Levels())
+	})
+	return group
+}
+
+// changeLogLevel 按请求修改级别
+func changeLogLevel(req LogLevelReq) error {
+	if req.Logger == "" {
+		req.Logger = "api"
+	}
+	var d time.Duration
+	if req.Duration != "" {
+		var err error
+		if d, err = time.ParseDuration(req.Duration); err != nil || d < 0 {
+			return fmt.Errorf("invalid duration %q", req.Duration)
+		}
+	}
+	if req.Level == "" {
+		if req.Package == "" {
+			return fmt.Errorf("level is required")
+		}
+		return logger.DeletePackageLevel(req.Logger, req.Package)
+	}
+	level, err := logger.ParseLevel(req.Level)
+	if err != nil {
+		return err
+	}
+	return logger.ChangeLevel(req.Logger, req.Package, level, d)
+}
@@ -0,0 +1,76 @@
+package gin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Chairou/toolbox/logger"
+)
+
+func TestServeLogLevel(t *testing.T) {
+	lp, err := logger.NewLogOpt("gin_level", &logger.LogOpt{FileName: "log/gin_level.log", Level: logger.INFO_LEVEL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		logger.Unregister("gin_level")
+		_ = lp.Close()
+		_ = os.RemoveAll("log")
+	}()
+	r, group := newResilienceRouter()
+	group.Group("/admin").ServeLogLevel()
+
+	put := func(body string) Ret {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/admin/log/level", strings.NewReader(body)))
+		var ret Ret
+		if err := json.Unmarshal(w.Body.Bytes(), &ret); err != nil {
+			t.Fatalf("响应不是 Ret 结构: %s", w.Body.String())
+		}
+		return ret
+	}
+
+	if ret := put(`{"logger":"gin_level","level":"debug","duration":"10m"}`); ret.Code != API_OK {
+		t.Fatalf("修改级别: %+v", ret)
+	}
+	if ret := put(`{"logger":"gin_level","package":"myapp/payment","level":"trace"}`); ret.Code != API_OK {
+		t.Fatalf("修改包的级别: %+v", ret)
+	}
+	if lp.GetLevel() != logger.DEBUG_LEVEL || lp.PackageLevels()["myapp/payment"] != logger.TRACE_LEVEL {
+		t.Errorf("级别未修改: %d %v", lp.GetLevel(), lp.PackageLevels())
+	}
+
+	ret := serveRet(t, r, "/admin/log/level")
+	data, _ := json.Marshal(ret.Data)
+	var levels []logger.LoggerLevels
+	_ = json.Unmarshal(data, &levels)
+	found := false
+	for _, ll := range levels {
+		if ll.Logger == "gin_level" {
+			found = ll.Level == "DEBUG" && ll.RevertAt != nil && len(ll.Packages) == 1 &&
+				ll.Packages[0].Level == "TRACE" && ll.Packages[0].RevertAt == nil
+		}
+	}
+	if !found {
+		t.Errorf("GET: %s", data)
+	}
+
+	for _, body := range []string{`{"logger":"gin_level","level":"loud"}`, `{"logger":"gin_level","level":"debug","duration":"soon"}`,
+		`{"logger":"gin_none","level":"debug"}`, `{"logger":"gin_level"}`} {
+		if ret := put(body); ret.Code != API_ARG_ERROR {
+			t.Errorf("%s 应返回 API_ARG_ERROR: %+v", body, ret)
+		}
+	}
+
+	// 永久修改取消自动恢复，空的级别删除包的级别
+	put(`{"logger":"gin_level","level":"info"}`)
+	put(`{"logger":"gin_level","package":"myapp/payment"}`)
+	if levels := logger.Levels(); lp.GetLevel() != logger.INFO_LEVEL || len(lp.PackageLevels()) != 0 {
+		t.Errorf("恢复: %+v", levels)
+	}
+}
@@ -0,0 +1,615 @@
+package gin
+
+import (
+	"encoding/json"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Chairou/toolbox/util/conv"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v2"
+)
+
+// anyMethods Any/StdAny 注册的全部 HTTP 方法
+var anyMethods = []string{"GET", "POST", "PUT", "PATCH", "HEAD", "OPTIONS", "DELETE", "CONNECT", "TRACE"}
+
+// SwaggerUIVersion 默认使用的 swagger-ui-dist 版本，固定到具体版本，避免 CDN 上的新版本被直接加载
+const SwaggerUIVersion = "5.17.14"
+
+// SwaggerUIAssetsURL Swagger UI 静态资源地址，内网环境可改为自建镜像；设置了 SwaggerUIAssets 时不使用
+var SwaggerUIAssetsURL = "https://unpkg.com/swagger-ui-dist@" + SwaggerUIVersion
+
+// SwaggerUIAssets 应用内嵌的 swagger-ui-dist 静态资源，至少包含 swagger-ui.css 和 swagger-ui-bundle.js，
+// 设置后由 ServeOpenAPI 在 <group>/swagger/assets 下提供，页面不再加载外部资源，离线和内网环境推荐使用：
+//
+//	//go:embed swagger-ui
+//	var swaggerUI embed.FS
+//
+//	gin.SwaggerUIAssets, _ = fs.Sub(swaggerUI, "swagger-ui")
+//
+// 需要在调用 ServeOpenAPI 之前设置
+var SwaggerUIAssets fs.FS
+
+// SwaggerUIIntegrity 加载 SwaggerUIAssetsURL 下资源时使用的 SRI 摘要，形如 sha384-xxx，
+// 为空时不校验。修改 SwaggerUIAssetsURL 的版本时需要同时更新
+var SwaggerUIIntegrity = struct {
+	CSS string
+	JS  string
+}{}
+
+// RouteDoc 路由文档元数据，通过 RouterGroup.Doc 附加到路由上
+type RouteDoc struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Deprecated  bool
+	// Request 请求体结构体实例，POST/PUT/PATCH 时生成 requestBody
+	Request any
+	// Response Ret.Data 对应的结构体实例，文档中会包裹在 Ret 结构里
+	Response any
+	// Params GetConditionByParam 使用的过滤参数，生成为 query 参数
+	Params map[string]*ParamConstruct
+	// Pager 为 true 时追加 GetPager 使用的 pageIndex、pageSize 参数
+	Pager bool
+}
+
+// RouteInfo 通过 RouterGroup 注册的一条路由
+type RouteInfo struct {
+	Method  string
+	Path    string
+	Handler string
+	Doc     *RouteDoc
+}
+
+// routeTable 路由元数据表
+type routeTable struct {
+	mu     sync.RWMutex
+	routes []*RouteInfo
+}
+
+func (t *routeTable) add(method, absolutePath, handler string) *RouteInfo {
+	info := &RouteInfo{Method: method, Path: absolutePath, Handler: handler}
+	t.mu.Lock()
+	t.routes = append(t.routes, info)
+	t.mu.Unlock()
+	return info
+}
+
+func (t *routeTable) list() []RouteInfo {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	ret := make([]RouteInfo, 0, len(t.routes))
+	for _, r := range t.routes {
+		ret = append(ret, *r)
+	}
+	return ret
+}
+
+// table 返回路由元数据表，根路由组首次使用时创建
+func (group *RouterGroup) table() *routeTable {
+	if group.routes == nil {
+		group.routes = &routeTable{}
+	}
+	return group.routes
+}
+
+// Doc 为最近一次注册的路由附加文档元数据，用于链式调用：
+//
+//	group.StdGET("catalog", getCatalog).Doc(RouteDoc{Summary: "查询目录", Response: []Catalog{}})
+func (group *RouterGroup) Doc(doc RouteDoc) *RouterGroup {
+	group.table().mu.Lock()
+	for _, r := range group.lastRoutes {
+		d := doc
+		r.Doc = &d
+	}
+	group.table().mu.Unlock()
+	return group
+}
+
+// Routes 返回当前路由组所属根路由下注册的全部路由
+func (group *RouterGroup) Routes() []RouteInfo {
+	return group.table().list()
+}
+
+// OpenAPIInfo 文档基本信息
+type OpenAPIInfo struct {
+	Title       string
+	Version     string
+	Description string
+	Servers     []string
+}
+
+// OpenAPISpec OpenAPI 3 文档
+type OpenAPISpec struct {
+	OpenAPI    string                                  `json:"openapi" yaml:"openapi"`
+	Info       OpenAPIInfoObject                       `json:"info" yaml:"info"`
+	Servers    []OpenAPIServer                         `json:"servers,omitempty" yaml:"servers,omitempty"`
+	Paths      map[string]map[string]*OpenAPIOperation `json:"paths" yaml:"paths"`
+	Components OpenAPIComponents                       `json:"components" yaml:"components"`
+}
+
+type OpenAPIInfoObject struct {
+	Title       string `json:"title" yaml:"title"`
+	Version     string `json:"version" yaml:"version"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+type OpenAPIServer struct {
+	URL string `json:"url" yaml:"url"`
+}
+
+type OpenAPIComponents struct {
+	Schemas map[string]*Schema `json:"schemas" yaml:"schemas"`
+}
+
+type OpenAPIOperation struct {
+	Summary     string                      `json:"summary,omitempty" yaml:"summary,omitempty"`
+	Description string                      `json:"description,omitempty" yaml:"description,omitempty"`
+	OperationID string                      `json:"operationId,omitempty" yaml:"operationId,omitempty"`
+	Tags        []string                    `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Deprecated  bool                        `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+	Parameters  []*OpenAPIParameter         `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses" yaml:"responses"`
+}
+
+type OpenAPIParameter struct {
+	Name        string  `json:"name" yaml:"name"`
+	In          string  `json:"in" yaml:"in"`
+	Description string  `json:"description,omitempty" yaml:"description,omitempty"`
+	Required    bool    `json:"required,omitempty" yaml:"required,omitempty"`
+	Schema      *Schema `json:"schema" yaml:"schema"`
+}
+
+type OpenAPIRequestBody struct {
+	Required bool                         `json:"required,omitempty" yaml:"required,omitempty"`
+	Content  map[string]*OpenAPIMediaType `json:"content" yaml:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string                       `json:"description" yaml:"description"`
+	Content     map[string]*OpenAPIMediaType `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+type OpenAPIMediaType struct {
+	Schema *Schema `json:"schema" yaml:"schema"`
+}
+
+// Schema OpenAPI 3 Schema 对象（常用子集）
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty" yaml:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty" yaml:"type,omitempty"`
+	Format               string             `json:"format,omitempty" yaml:"format,omitempty"`
+	Description          string             `json:"description,omitempty" yaml:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty" yaml:"nullable,omitempty"`
+	Enum                 []any              `json:"enum,omitempty" yaml:"enum,omitempty"`
+	Default              any                `json:"default,omitempty" yaml:"default,omitempty"`
+	Items                *Schema            `json:"items,omitempty" yaml:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty" yaml:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty" yaml:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"`
+}
+
+// OpenAPI 根据当前路由组所属根路由下注册的路由生成 OpenAPI 3 文档
+func (group *RouterGroup) OpenAPI(info OpenAPIInfo) *OpenAPISpec {
+	return GenerateOpenAPI(info, group.Routes())
+}
+
+// GenerateOpenAPI 根据路由列表生成 OpenAPI 3 文档
+func GenerateOpenAPI(info OpenAPIInfo, routes []RouteInfo) *OpenAPISpec {
+	spec := &OpenAPISpec{
+		OpenAPI: "3.0.3",
+		Info: OpenAPIInfoObject{
+			Title:       info.Title,
+			Version:     info.Version,
+			Description: info.Description,
+		},
+		Paths:      make(map[string]map[string]*OpenAPIOperation),
+		Components: OpenAPIComponents{Schemas: make(map[string]*Schema)},
+	}
+	if spec.Info.Title == "" {
+		spec.Info.Title = "API"
+	}
+	if spec.Info.Version == "" {
+		spec.Info.Version = "1.0.0"
+	}
+	for _, s := range info.Servers {
+		spec.Servers = append(spec.Servers, OpenAPIServer{URL: s})
+	}
+
+	gen := &schemaGenerator{schemas: spec.Components.Schemas, names: make(map[reflect.Type]string)}
+	for _, r := range routes {
+		path, pathParams := openAPIPath(r.Path)
+		if _, ok := spec.Paths[path]; !ok {
+			spec.Paths[path] = make(map[string]*OpenAPIOperation)
+		}
+		spec.Paths[path][strings.ToLower(r.Method)] = gen.operation(r, pathParams)
+	}
+	return spec
+}
+
+// openAPIPath 把 gin 路径 /user/:id/*file 转换为 /user/{id}/{file}，并返回路径参数名
+func openAPIPath(ginPath string) (string, []string) {
+	var params []string
+	segs := strings.Split(ginPath, "/")
+	for i, seg := range segs {
+		if len(seg) > 1 && (seg[0] == ':' || seg[0] == '*') {
+			params = append(params, seg[1:])
+			segs[i] = "{" + seg[1:] + "}"
+		}
+	}
+	return strings.Join(segs, "/"), params
+}
+
+func (g *schemaGenerator) operation(r RouteInfo, pathParams []string) *OpenAPIOperation {
+	op := &OpenAPIOperation{
+		OperationID: strings.ToLower(r.Method) + operationName(r.Path),
+		Responses:   make(map[string]*OpenAPIResponse),
+	}
+	for _, p := range pathParams {
+		op.Parameters = append(op.Parameters, &OpenAPIParameter{
+			Name: p, In: "path", Required: true, Schema: &Schema{Type: "string"},
+		})
+	}
+
+	doc := r.Doc
+	if doc == nil {
+		doc = &RouteDoc{}
+	}
+	op.Summary = doc.Summary
+	if op.Summary == "" {
+		op.Summary = r.Handler
+	}
+	op.Description = doc.Description
+	op.Tags = doc.Tags
+	op.Deprecated = doc.Deprecated
+
+	// 过滤参数按名字排序，保证文档输出稳定
+	keys := make([]string, 0, len(doc.Params))
+	for k := range doc.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		op.Parameters = append(op.Parameters, filterParameter(k, doc.Params[k]))
+	}
+	if doc.Pager {
+		op.Parameters = append(op.Parameters,
+			&OpenAPIParameter{Name: "pageIndex", In: "query", Description: "页码，从 1 开始，默认 1",
+				Schema: &Schema{Type: "integer", Default: 1}},
+			&OpenAPIParameter{Name: "pageSize", In: "query", Description: "每页条数，默认 10000",
+				Schema: &Schema{Type: "integer", Default: 10000}},
+		)
+	}
+
+	if doc.Request != nil {
+		switch r.Method {
+		case "POST", "PUT", "PATCH", "DELETE":
+			op.RequestBody = &OpenAPIRequestBody{
+				Required: true,
+				Content: map[string]*OpenAPIMediaType{
+					"application/json": {Schema: g.schemaOf(reflect.TypeOf(doc.Request))},
+				},
+			}
+		}
+	}
+
+	var data *Schema
+	if doc.Response != nil {
+		data = g.schemaOf(reflect.TypeOf(doc.Response))
+	} else {
+		data = &Schema{Nullable: true}
+	}
+	op.Responses["200"] = &OpenAPIResponse{
+		Description: "OK",
+		Content: map[string]*OpenAPIMediaType{
+			"application/json": {Schema: retSchema(data)},
+		},
+	}
+	return op
+}
+
+// operationName 由路径生成 operationId 后缀：/api/user/{id} -> ApiUserId
+func operationName(path string) string {
+	var b strings.Builder
+	upper := true
+	for _, ch := range path {
+		switch {
+		case ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9':
+			if upper && ch >= 'a' && ch <= 'z' {
+				ch -= 'a' - 'A'
+			}
+			b.WriteRune(ch)
+			upper = false
+		default:
+			upper = true
+		}
+	}
+	return b.String()
+}
+
+// retSchema 把 data 的 schema 包裹在统一返回结构 Ret 中
+func retSchema(data *Schema) *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"code":    {Type: "integer", Description: "返回码，0 表示成功"},
+			"message": {Type: "string"},
+			"data":    data,
+			"seq":     {Type: "string", Description: "请求序列号"},
+		},
+		Required: []string{"code", "message", "data", "seq"},
+	}
+}
+
+// filterParameter 把 GetConditionByParam 的过滤参数转换为 query 参数
+func filterParameter(name string, p *ParamConstruct) *OpenAPIParameter {
+	param := &OpenAPIParameter{Name: name, In: "query", Schema: &Schema{Type: "string"}}
+	if p == nil {
+		return param
+	}
+	param.Required = p.Need
+	for _, v := range p.CheckValue {
+		param.Schema.Enum = append(param.Schema.Enum, conv.String(v))
+	}
+	if p.DefaultValue != nil && conv.String(p.DefaultValue) != "" {
+		param.Schema.Default = conv.String(p.DefaultValue)
+	}
+	switch name {
+	case "orderBy":
+		param.Description = "排序，格式 field|asc;field2|desc"
+	case "searchKey":
+		param.Description = "关键字模糊查询"
+	case "accessPerson":
+		param.Description = "创建人过滤：0 所有人，1 本人，2 同事"
+	default:
+		if p.Symbol != "" {
+			param.Description = "过滤条件 " + p.Symbol
+		}
+	}
+	return param
+}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	byteType = reflect.TypeOf(byte(0))
+)
+
+// schemaGenerator 通过反射生成 schema，具名结构体放入 components 并以 $ref 引用
+type schemaGenerator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func (g *schemaGenerator) schemaOf(t reflect.Type) *Schema {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+	if t == timeType || t.ConvertibleTo(timeType) {
+		return &Schema{Type: "string", Format: "date-time", Nullable: nullable}
+	}
+
+	var s *Schema
+	switch t.Kind() {
+	case reflect.Bool:
+		s = &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		s = &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		s = &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		s = &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		s = &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		s = &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem() == byteType {
+			s = &Schema{Type: "string", Format: "byte"}
+		} else {
+			s = &Schema{Type: "array", Items: g.schemaOf(t.Elem())}
+		}
+	case reflect.Map:
+		s = &Schema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			s = g.structSchema(t)
+		} else {
+			return &Schema{Ref: "#/components/schemas/" + g.component(t), Nullable: nullable}
+		}
+	default:
+		// interface 等无法确定类型，返回空 schema 表示任意值
+		s = &Schema{}
+	}
+	s.Nullable = nullable
+	return s
+}
+
+// component 注册具名结构体，返回 components 中的名字
+func (g *schemaGenerator) component(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, used := g.schemas[name]; used {
+		// 不同包的同名结构体，用包名区分
+		pkg := t.PkgPath()
+		if i := strings.LastIndex(pkg, "/"); i >= 0 {
+			pkg = pkg[i+1:]
+		}
+		name = pkg + "." + name
+	}
+	g.names[t] = name
+	// 先占位，避免自引用结构体无限递归
+	g.schemas[name] = &Schema{}
+	*g.schemas[name] = *g.structSchema(t)
+	return name
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.addFields(s, t)
+	return s
+}
+
+func (g *schemaGenerator) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, omitempty, skip := jsonFieldName(field)
+		if skip {
+			continue
+		}
+		// 匿名嵌入且没有 json 名字的结构体，字段展开到父结构体
+		if field.Anonymous && name == field.Name {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.addFields(s, ft)
+				continue
+			}
+		}
+		fs := g.schemaOf(field.Type)
+		// $ref 不允许有兄弟属性，引用类型的字段描述只能丢弃
+		if desc := fieldDescription(field); desc != "" && fs.Ref == "" {
+			fs.Description = desc
+		}
+		s.Properties[name] = fs
+		if !omitempty && fieldRequired(field) {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// jsonFieldName 解析 json 标签，返回字段名、是否 omitempty、是否忽略
+func jsonFieldName(field reflect.StructField) (string, bool, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	name := field.Name
+	omitempty := false
+	parts := strings.Split(tag, ",")
+	if parts[0] != "" {
+		name = parts[0]
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitempty = true
+		}
+	}
+	return name, omitempty, false
+}
+
+// fieldRequired binding、validate 标签中声明了 required 的字段为必填
+func fieldRequired(field reflect.StructField) bool {
+	for _, key := range []string{"binding", "validate"} {
+		for _, rule := range strings.Split(field.Tag.Get(key), ",") {
+			if rule == "required" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fieldDescription 字段描述取自 comment 标签，其次取 gorm 标签中的 comment
+func fieldDescription(field reflect.StructField) string {
+	if c := field.Tag.Get("comment"); c != "" {
+		return c
+	}
+	if gormTag := field.Tag.Get("gorm"); gormTag != "" {
+		if c, ok := NewGormTagParser(gormTag).Get("comment"); ok {
+			return c
+		}
+	}
+	return ""
+}
+
+// ServeOpenAPI 在路由组下注册文档路由：
+//
+//	<group>/openapi.json  JSON 格式文档
+//	<group>/openapi.yaml  YAML 格式文档
+//	<group>/swagger       Swagger UI 页面（swaggerUI 为 true 时注册）
+//	<group>/swagger/assets Swagger UI 静态资源（swaggerUI 为 true 且设置了 SwaggerUIAssets 时注册）
+//
+// 文档在请求时生成，之后注册的路由同样会出现在文档中；文档路由本身不会出现在文档里
+func (group *RouterGroup) ServeOpenAPI(info OpenAPIInfo, swaggerUI bool) *RouterGroup {
+	group.routerGroup.GET("/openapi.json", func(c *gin.Context) {
+		c.JSON(http.StatusOK, group.OpenAPI(info))
+	})
+	group.routerGroup.GET("/openapi.yaml", func(c *gin.Context) {
+		data, err := openAPIYAML(group.OpenAPI(info))
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.Data(http.StatusOK, "application/yaml; charset=utf-8", data)
+	})
+	if swaggerUI {
+		specURL := joinPaths(group.BasePath(), "/openapi.json")
+		page := map[string]string{
+			"Title":  info.Title,
+			"Assets": SwaggerUIAssetsURL,
+			"CSSSRI": SwaggerUIIntegrity.CSS,
+			"JSSRI":  SwaggerUIIntegrity.JS,
+			"Spec":   specURL,
+		}
+		if SwaggerUIAssets != nil {
+			group.routerGroup.StaticFS("/swagger/assets", http.FS(SwaggerUIAssets))
+			// 同源资源不需要 SRI
+			page["Assets"] = joinPaths(group.BasePath(), "/swagger/assets")
+			page["CSSSRI"], page["JSSRI"] = "", ""
+		}
+		group.routerGroup.GET("/swagger", func(c *gin.Context) {
+			c.Status(http.StatusOK)
+			c.Header("Content-Type", "text/html; charset=utf-8")
+			_ = swaggerUITemplate.Execute(c.Writer, page)
+		})
+	}
+	return group
+}
+
+// openAPIYAML 先转为 JSON 再转为 YAML，保证字段名与 JSON 文档一致
+func openAPIYAML(spec *OpenAPISpec) ([]byte, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	var m yaml.MapSlice
+	if err = yaml.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(m)
+}
+
+var swaggerUITemplate = template.Must(template.New("swagger").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="{{.Assets}}/swagger-ui.css"{{with .CSSSRI}} integrity="{{.}}" crossorigin="anonymous"{{end}}>
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="{{.Assets}}/swagger-ui-bundle.js"{{with .JSSRI}} integrity="{{.}}" crossorigin="anonymous"{{end}}></script>
+<script>
+window.onload = function () {
+  window.ui = SwaggerUIBundle({url: "{{.Spec}}", dom_id: "#swagger-ui"});
+};
+</script>
+</body>
+</html>
+`))
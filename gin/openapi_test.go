@@ -0,0 +1,166 @@
+package gin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type openAPIUser struct {
+	ID        int64     `json:"id" gorm:"column:id;comment:用户ID"`
+	Name      string    `json:"name" binding:"required" comment:"用户名"`
+	Email     string    `json:"email,omitempty"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"createdAt"`
+	Secret    string    `json:"-"`
+}
+
+func setupOpenAPIRouter() (*gin.Engine, *RouterGroup) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	group := &RouterGroup{routerGroup: &r.RouterGroup}
+	api := group.Group("/api")
+	api.StdGET("/user/:id", func(c *Context) {}).
+		Doc(RouteDoc{Summary: "查询用户", Tags: []string{"user"}, Response: openAPIUser{}})
+	api.StdPOST("/user", func(c *Context) {}).
+		Doc(RouteDoc{Summary: "创建用户", Request: &openAPIUser{}, Response: openAPIUser{}})
+	api.StdGET("/users", func(c *Context) {}).
+		Doc(RouteDoc{
+			Summary:  "用户列表",
+			Response: []openAPIUser{},
+			Pager:    true,
+			Params: map[string]*ParamConstruct{
+				"status": {FieldName: "status", CheckValue: []interface{}{"on", "off"}, Need: true, Symbol: "="},
+			},
+		})
+	api.GET("/ping", func(c *Context) {})
+	group.ServeOpenAPI(OpenAPIInfo{Title: "test", Version: "1.0"}, true)
+	return r, group
+}
+
+func TestOpenAPI_Routes(t *testing.T) {
+	_, group := setupOpenAPIRouter()
+	routes := group.Routes()
+	if len(routes) != 4 {
+		t.Fatalf("路由数量不正确, 期望 4, 实际 %d", len(routes))
+	}
+	if routes[0].Path != "/api/user/:id" || routes[0].Method != "GET" || routes[0].Doc == nil {
+		t.Errorf("路由记录不正确: %+v", routes[0])
+	}
+	if routes[3].Doc != nil {
+		t.Errorf("未附加文档的路由 Doc 应为 nil")
+	}
+}
+
+func TestOpenAPI_Spec(t *testing.T) {
+	_, group := setupOpenAPIRouter()
+	spec := group.OpenAPI(OpenAPIInfo{Title: "test"})
+
+	op := spec.Paths["/api/user/{id}"]["get"]
+	if op == nil {
+		t.Fatalf("缺少 /api/user/{id} GET 文档")
+	}
+	if op.Summary != "查询用户" || len(op.Parameters) != 1 || op.Parameters[0].In != "path" {
+		t.Errorf("路径参数或摘要不正确: %+v", op)
+	}
+	data := op.Responses["200"].Content["application/json"].Schema.Properties["data"]
+	if data.Ref != "#/components/schemas/openAPIUser" {
+		t.Errorf("响应应引用 openAPIUser, 实际 %q", data.Ref)
+	}
+
+	user := spec.Components.Schemas["openAPIUser"]
+	if user == nil {
+		t.Fatalf("components 中缺少 openAPIUser")
+	}
+	if _, ok := user.Properties["Secret"]; ok {
+		t.Errorf("json:\"-\" 字段不应出现在文档中")
+	}
+	if user.Properties["id"].Description != "用户ID" || user.Properties["name"].Description != "用户名" {
+		t.Errorf("字段描述不正确")
+	}
+	if user.Properties["createdAt"].Format != "date-time" {
+		t.Errorf("time.Time 应为 date-time")
+	}
+	if len(user.Required) != 1 || user.Required[0] != "name" {
+		t.Errorf("必填字段不正确: %v", user.Required)
+	}
+
+	post := spec.Paths["/api/user"]["post"]
+	if post.RequestBody == nil {
+		t.Errorf("POST 应生成 requestBody")
+	}
+
+	list := spec.Paths["/api/users"]["get"]
+	if len(list.Parameters) != 3 {
+		t.Fatalf("列表参数数量不正确: %d", len(list.Parameters))
+	}
+	status := list.Parameters[0]
+	if status.Name != "status" || !status.Required || len(status.Schema.Enum) != 2 {
+		t.Errorf("过滤参数不正确: %+v", status)
+	}
+	if items := list.Responses["200"].Content["application/json"].Schema.Properties["data"].Items; items == nil {
+		t.Errorf("切片响应应为 array")
+	}
+
+	if spec.Paths["/api/ping"]["get"].Summary == "" {
+		t.Errorf("未附加文档的路由应以处理函数名作为摘要")
+	}
+}
+
+func TestOpenAPI_Serve(t *testing.T) {
+	r, _ := setupOpenAPIRouter()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("openapi.json 状态码错误: %d", w.Code)
+	}
+	var spec map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("openapi.json 不是合法 JSON: %v", err)
+	}
+	if spec["openapi"] != "3.0.3" {
+		t.Errorf("openapi 版本不正确: %v", spec["openapi"])
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.yaml", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "openapi: 3.0.3") {
+		t.Errorf("openapi.yaml 输出不正确: %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/swagger", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "/openapi.json") {
+		t.Errorf("swagger 页面输出不正确")
+	}
+	if !strings.Contains(w.Body.String(), SwaggerUIAssetsURL) || strings.Contains(w.Body.String(), "integrity=") {
+		t.Errorf("未设置 SRI 时应只使用固定版本的地址: %s", w.Body.String())
+	}
+}
+
+func TestOpenAPI_SwaggerUIAssets(t *testing.T) {
+	SwaggerUIAssets = fstest.MapFS{
+		"swagger-ui.css":       {Data: []byte("body{}")},
+		"swagger-ui-bundle.js": {Data: []byte("var SwaggerUIBundle;")},
+	}
+	defer func() { SwaggerUIAssets = nil }()
+	r, _ := setupOpenAPIRouter()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/swagger", nil))
+	if body := w.Body.String(); strings.Contains(body, SwaggerUIAssetsURL) || !strings.Contains(body, `src="/swagger/assets/swagger-ui-bundle.js"`) {
+		t.Errorf("设置 SwaggerUIAssets 后应使用内嵌资源: %s", body)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/swagger/assets/swagger-ui-bundle.js", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "var SwaggerUIBundle;" {
+		t.Errorf("内嵌资源输出不正确: %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,128 @@
+package gin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Chairou/toolbox/i18n"
+	"github.com/Chairou/toolbox/util/breaker"
+)
+
+// Timeout 请求超时中间件，为 c.Request 附加 context.WithTimeout。
+// 处理函数需要把 c.Request.Context() 传给下游（如 gorm 的 WithContext、httphelper 的 SetContext），
+// 超时后下游调用会被取消；处理函数返回时如果已超时且尚未输出，返回 API_TIMEOUT_ERROR。
+//
+// 使用方法：
+//
+//	group.GET("/report", Timeout(3*time.Second), getReport)
+//	group.Group("/report", Timeout(3*time.Second)).StdGET("", getReport)
+func Timeout(timeout time.Duration) HandlerFunc {
+	return func(c *Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			c.Infof("request timeout after %s", timeout)
+			if !c.Writer.Written() {
+				c.RetJson(API_TIMEOUT_ERROR, nil, i18n.M("request.timeout"))
+			}
+		}
+	}
+}
+
+// Bulkhead 舱壁隔离中间件，限制每个路由同时处理的请求数。
+// 超过 maxConcurrent 时最多排队等待 maxWait，仍未获得名额则返回 API_BUSY_ERROR；maxWait 为 0 时不等待。
+// 每次调用 Bulkhead 创建的中间件单独计数，同一个中间件挂在多个路由上时按路由分别计数。
+func Bulkhead(maxConcurrent int, maxWait time.Duration) HandlerFunc {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 1
+	}
+	var slots sync.Map
+	return func(c *Context) {
+		key := c.Request.Method + " " + c.FullPath()
+		v, ok := slots.Load(key)
+		if !ok {
+			v, _ = slots.LoadOrStore(key, make(chan struct{}, maxConcurrent))
+		}
+		sem := v.(chan struct{})
+
+		if !acquireSlot(c.Request.Context(), sem, maxWait) {
+			c.Infof("bulkhead full: %s, max concurrent %d", key, maxConcurrent)
+			c.RetJson(API_BUSY_ERROR, nil, i18n.M("server.busy"))
+			c.Abort()
+			return
+		}
+		defer func() { <-sem }()
+		c.Next()
+	}
+}
+
+func acquireSlot(ctx context.Context, sem chan struct{}, maxWait time.Duration) bool {
+	select {
+	case sem <- struct{}{}:
+		return true
+	default:
+	}
+	if maxWait <= 0 {
+		return false
+	}
+	timer := time.NewTimer(maxWait)
+	defer timer.Stop()
+	select {
+	case sem <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// CircuitBreaker 熔断中间件，name 为空时按 "方法 路由" 区分熔断器，
+// 多个路由共用同一个下游时可以传入下游名字共享熔断器。
+// 熔断器参数通过 breaker.New 预先设置，未设置时使用 breaker.DefaultOptions。
+// HTTP 状态码 >= 500、请求超时或返回码为内部错误、数据库错误、远程调用错误、超时错误时记为失败。
+func CircuitBreaker(name string) HandlerFunc {
+	return func(c *Context) {
+		key := name
+		if key == "" {
+			key = c.Request.Method + " " + c.FullPath()
+		}
+		b := breaker.Get(key)
+		token, err := b.Allow()
+		if err != nil {
+			c.Infof("circuit breaker %s rejected request, state %s", key, b.State())
+			c.RetJson(API_BREAKER_OPEN, nil, i18n.M("server.breaker_open"))
+			c.Abort()
+			return
+		}
+		success := false
+		defer func() {
+			// 处理函数 panic 时 success 保持 false
+			b.Report(token, success)
+		}()
+		c.Next()
+		success = !requestFailed(c)
+	}
+}
+
+// requestFailed 判断请求是否应计为熔断失败
+func requestFailed(c *Context) bool {
+	if c.Writer.Status() >= http.StatusInternalServerError {
+		return true
+	}
+	if errors.Is(c.Request.Context().Err(), context.DeadlineExceeded) {
+		return true
+	}
+	switch c.GetInt("code") {
+	case API_INTERNAL_ERROR, API_DB_ERROR, API_REMOTE_ERROR, API_TIMEOUT_ERROR, http.StatusInternalServerError:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package gin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Chairou/toolbox/util/breaker"
+	"github.com/gin-gonic/gin"
+)
+
+func newResilienceRouter() (*gin.Engine, *RouterGroup) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	return r, &RouterGroup{routerGroup: &r.RouterGroup}
+}
+
+func serveRet(t *testing.T, r http.Handler, path string) Ret {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	var ret Ret
+	if err := json.Unmarshal(w.Body.Bytes(), &ret); err != nil {
+		t.Fatalf("响应不是 Ret 结构: %s", w.Body.String())
+	}
+	return ret
+}
+
+func TestTimeout(t *testing.T) {
+	r, group := newResilienceRouter()
+	group.GET("/slow", Timeout(20*time.Millisecond), func(c *Context) {
+		<-c.Request.Context().Done()
+	})
+	group.GET("/fast", Timeout(time.Second), func(c *Context) {
+		if _, ok := c.Request.Context().Deadline(); !ok {
+			t.Errorf("请求 context 应带有 deadline")
+		}
+		c.RetJson(API_OK, "ok")
+	})
+
+	if ret := serveRet(t, r, "/slow"); ret.Code != API_TIMEOUT_ERROR {
+		t.Errorf("超时请求应返回 API_TIMEOUT_ERROR, 实际 %d", ret.Code)
+	}
+	if ret := serveRet(t, r, "/fast"); ret.Code != API_OK {
+		t.Errorf("未超时请求应正常返回, 实际 %d", ret.Code)
+	}
+}
+
+func TestBulkhead(t *testing.T) {
+	r, group := newResilienceRouter()
+	release := make(chan struct{})
+	entered := make(chan struct{})
+	group.GET("/bulk", Bulkhead(1, 0), func(c *Context) {
+		entered <- struct{}{}
+		<-release
+		c.RetJson(API_OK, nil)
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		serveRet(t, r, "/bulk")
+	}()
+	<-entered
+
+	if ret := serveRet(t, r, "/bulk"); ret.Code != API_BUSY_ERROR {
+		t.Errorf("并发超限应返回 API_BUSY_ERROR, 实际 %d", ret.Code)
+	}
+	close(release)
+	wg.Wait()
+
+	go func() { <-entered }()
+	if ret := serveRet(t, r, "/bulk"); ret.Code != API_OK {
+		t.Errorf("名额释放后应正常处理, 实际 %d", ret.Code)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	r, group := newResilienceRouter()
+	breaker.New("test-downstream", breaker.Options{FailureThreshold: 2, OpenTimeout: time.Hour})
+	fail := true
+	group.GET("/cb", CircuitBreaker("test-downstream"), func(c *Context) {
+		if fail {
+			c.RetJson(API_REMOTE_ERROR, nil, "remote error")
+			return
+		}
+		c.RetJson(API_OK, nil)
+	})
+
+	for i := 0; i < 2; i++ {
+		if ret := serveRet(t, r, "/cb"); ret.Code != API_REMOTE_ERROR {
+			t.Fatalf("熔断前应执行处理函数, 实际 %d", ret.Code)
+		}
+	}
+	fail = false
+	if ret := serveRet(t, r, "/cb"); ret.Code != API_BREAKER_OPEN {
+		t.Errorf("连续失败后应熔断, 实际 %d", ret.Code)
+	}
+
+	breaker.Get("test-downstream").Reset()
+	if ret := serveRet(t, r, "/cb"); ret.Code != API_OK {
+		t.Errorf("重置后应恢复, 实际 %d", ret.Code)
+	}
+}
@@ -44,7 +44,7 @@ func SafeCheck(c *Context) {
 		for key, values := range queryParams {
 			for _, value := range values {
 				if detectSQLInjection(value) {
-					c.JSON(http.StatusForbidden, H{"message": "访问被禁止"})
+					c.JSON(http.StatusForbidden, H{"message": c.T("safe.forbidden")})
 					fmt.Printf("SQL注入检测 - 参数：%s，值：%s\n", key, value)
 					c.Abort()
 					return
@@ -67,7 +67,7 @@ func SafeCheck(c *Context) {
 			for key, value := range jsonData {
 				if strVal, ok := value.(string); ok {
 					if detectSQLInjection(strVal) {
-						c.JSON(http.StatusForbidden, H{"message": "访问被禁止"})
+						c.JSON(http.StatusForbidden, H{"message": c.T("safe.forbidden")})
 						fmt.Printf("SQL注入检测 - 参数：%s，值：%s\n", key, strVal)
 						c.Abort()
 						return
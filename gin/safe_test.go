@@ -52,8 +52,8 @@ func TestSafeCheck_PostWithSelectInjection(t *testing.T) {
 	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
 		t.Fatalf("解析响应 body 失败: %v", err)
 	}
-	if resp["message"] != "访问被禁止" {
-		t.Errorf("期望响应消息为 '访问被禁止'，实际得到 '%s'", resp["message"])
+	if resp["message"] != "Access forbidden" {
+		t.Errorf("期望响应消息为 'Access forbidden'，实际得到 '%s'", resp["message"])
 	}
 	t.Logf("SQL注入检测成功，响应: %s", w.Body.String())
 }
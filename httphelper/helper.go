@@ -2,6 +2,7 @@ package httphelper
 
 import (
 	"bytes"
+	"context"
 	"crypto/md5"
 	"encoding/base64"
 	"encoding/hex"
@@ -75,6 +76,9 @@ type Helper interface {
 	SetUploadFile(fileName string, fileSize int64) Helper
 
 	SetTimeout(dialTimeout time.Duration, totalTimeout time.Duration) Helper
+
+	// SetContext 设置请求的 context，context 取消或超时后请求会被中断
+	SetContext(ctx context.Context) Helper
 }
 
 const (
@@ -110,6 +114,8 @@ func init() {
 			return
 		}
 		_ = log.SetLevel(logger.DEBUG_LEVEL)
+		// DebugDetail 会记录请求头、cookie 和请求体，默认脱敏
+		log.SetRedactor(logger.DefaultRedactor())
 		log.Info("http log init.")
 	})
 }
@@ -243,6 +249,14 @@ func (p *httpHelper) SetTimeout(dialTimeout time.Duration, totalTimeout time.Dur
 	return p
 }
 
+// SetContext 设置请求的 context，context 取消或超时后请求会被中断
+func (p *httpHelper) SetContext(ctx context.Context) Helper {
+	if ctx != nil {
+		p.req = p.req.WithContext(ctx)
+	}
+	return p
+}
+
 // Do 发送请求
 func (p *httpHelper) Do() Result {
 	startTime := time.Now()
@@ -255,7 +269,7 @@ func (p *httpHelper) Do() Result {
 		if err != nil {
 			redStr := color.SetColor(color.Red, fmt.Sprintf("%v", err))
 			s := fmt.Sprintf("%s  io.ReadAll err: %s", p.Uuid, redStr)
-			log.Error(s)
+			p.ctxLog().Error(s)
 			return result.Errorf("io.ReadAll err: %v", err)
 		}
 	}
@@ -264,21 +278,21 @@ func (p *httpHelper) Do() Result {
 	switch p.debug {
 	case DebugNormal:
 		if p.req.Method == "POST" {
-			log.Info("HTTP REQ:", p.Uuid, "\n", p.req.Method, p.req.URL.String(), "\n【reqBODY】:",
-				color.SetColor(color.Green, result.ReqBody))
+			p.ctxLog().Info(fmt.Sprintln("HTTP REQ:", p.Uuid, "\n", p.req.Method, p.req.URL.String(), "\n【reqBODY】:",
+				color.SetColor(color.Green, result.ReqBody)))
 		} else {
-			log.Info("HTTP REQ:", p.Uuid, "\n", p.req.Method, color.SetColor(color.Green, p.req.URL.String()))
+			p.ctxLog().Info(fmt.Sprintln("HTTP REQ:", p.Uuid, "\n", p.req.Method, color.SetColor(color.Green, p.req.URL.String())))
 		}
 	case DebugDetail:
 		if p.req.Method == "POST" {
-			log.Info("HTTP REQ:", p.Uuid, "\n", p.req.Method, p.req.URL.String(), p.req.Header, p.req.Cookies(),
-				"\n【reqBODY】 :", color.SetColor(color.Green, result.ReqBody))
+			p.ctxLog().Info(fmt.Sprintln("HTTP REQ:", p.Uuid, "\n", p.req.Method, p.req.URL.String(), p.req.Header, p.req.Cookies(),
+				"\n【reqBODY】 :", color.SetColor(color.Green, result.ReqBody)))
 		} else {
-			log.Info("HTTP REQ:", p.Uuid, "\n", p.req.Method, color.SetColor(color.Green, p.req.URL.String()))
+			p.ctxLog().Info(fmt.Sprintln("HTTP REQ:", p.Uuid, "\n", p.req.Method, color.SetColor(color.Green, p.req.URL.String())))
 		}
 	case DebugUpload:
-		log.Info("HTTP UPLOAD FILE:", p.Uuid, "\n", p.req.Method, p.req.URL.String(), ", fileName:",
-			p.UploadFileName, ", fileSize:", p.UploadFileSize)
+		p.ctxLog().Info(fmt.Sprintln("HTTP UPLOAD FILE:", p.Uuid, "\n", p.req.Method, p.req.URL.String(), ", fileName:",
+			p.UploadFileName, ", fileSize:", p.UploadFileSize))
 	}
 
 	if len(byteBody) > 0 {
@@ -291,7 +305,7 @@ func (p *httpHelper) Do() Result {
 	if err != nil {
 		redStr := color.SetColor(color.Red, fmt.Sprintf("%v", err))
 		s := fmt.Sprintf("%s do http request err: %s", p.Uuid, redStr)
-		log.Error(s)
+		p.ctxLog().Error(s)
 		return result.Errorf("do http request err: %v", err)
 	}
 
@@ -300,7 +314,7 @@ func (p *httpHelper) Do() Result {
 		if err != nil {
 			redStr := color.SetColor(color.Red, fmt.Sprintf("%v", err))
 			s := fmt.Sprintf("%s body close err: %s", p.Uuid, redStr)
-			log.Error(s)
+			p.ctxLog().Error(s)
 		}
 	}(resp.Body)
 
@@ -308,7 +322,7 @@ func (p *httpHelper) Do() Result {
 	if err != nil {
 		redStr := color.SetColor(color.Red, fmt.Sprintf("%v", err))
 		s := fmt.Sprintf("%s read response body err: %s", p.Uuid, redStr)
-		log.Error(s)
+		p.ctxLog().Error(s)
 		return result.Errorf("read response body err: %v", err)
 	}
 
@@ -325,16 +339,16 @@ func (p *httpHelper) Do() Result {
 	result.Uuid = p.Uuid
 	if resp.StatusCode != http.StatusOK {
 		s := fmt.Sprintf("%s http resp status code: %d, body: %s", p.Uuid, resp.StatusCode, result.RetBody)
-		log.Error(s)
+		p.ctxLog().Error(s)
 		return result.Errorf("http resp status code: %d, body: %s", resp.StatusCode, result.RetBody)
 	}
 	switch p.debug {
 	case DebugNormal:
-		log.Info("HTTP RESP:", p.Uuid, "\n【retBody】:", color.SetColor(color.Green, result.RetBody),
-			"elapsed :", elapsed)
+		p.ctxLog().Info(fmt.Sprintln("HTTP RESP:", p.Uuid, "\n【retBody】:", color.SetColor(color.Green, result.RetBody),
+			"elapsed :", elapsed))
 	case DebugDetail:
-		log.Info("HTTP RESP:", p.Uuid, "\n【retBody】:", color.SetColor(color.Green, result.RetBody),
-			result.RetHeader, result.RetCookie, "elapsed :", elapsed)
+		p.ctxLog().Info(fmt.Sprintln("HTTP RESP:", p.Uuid, "\n【retBody】:", color.SetColor(color.Green, result.RetBody),
+			result.RetHeader, result.RetCookie, "elapsed :", elapsed))
 	}
 
 	return &jsonResult{
@@ -343,6 +357,14 @@ func (p *httpHelper) Do() Result {
 	}
 }
 
+// ctxLog 带请求 context 中字段的日志，gin 的请求 context 会带上 request_id、trace_id
+func (p *httpHelper) ctxLog() *logger.FieldLogger {
+	if log == nil {
+		return logger.FromContext(p.req.Context())
+	}
+	return log.FromContext(p.req.Context())
+}
+
 func (p *httpHelper) error() error {
 	return p.Err
 }
@@ -360,7 +382,7 @@ func (p *httpHelper) DoStream() (*http.Response, error) {
 		if err != nil {
 			redStr := color.SetColor(color.Red, fmt.Sprintf("%v", err))
 			s := fmt.Sprintf("%s  io.ReadAll err: %s", p.Uuid, redStr)
-			log.Error(s)
+			p.ctxLog().Error(s)
 			return nil, fmt.Errorf("io.ReadAll err: %v", err)
 		}
 	}
@@ -374,7 +396,7 @@ func (p *httpHelper) DoStream() (*http.Response, error) {
 	if err != nil {
 		redStr := color.SetColor(color.Red, fmt.Sprintf("%v", err))
 		s := fmt.Sprintf("%s do http request err: %s", p.Uuid, redStr)
-		log.Error(s)
+		p.ctxLog().Error(s)
 		return nil, fmt.Errorf("do http request err: %v", err)
 	}
 	// 注意：不在此处关闭/读取 resp.Body，交由调用方处理。
@@ -468,3 +490,5 @@ func (p *errHelper) SetUploadFile(fileName string, fileSize int64) Helper { retu
 func (p *errHelper) SetTimeout(dialTimeout time.Duration, totalTimeout time.Duration) Helper {
 	return p
 }
+
+func (p *errHelper) SetContext(ctx context.Context) Helper { return p }
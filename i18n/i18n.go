@@ -0,0 +1,431 @@
+// Package i18n 提供多语言消息目录：YAML/JSON 消息文件、复数规则和 Accept-Language 协商
+//
+// 消息文件按语言区分，文件名（去掉扩展名后最后一段）即语言，例如 zh.yaml、messages.en.json。
+// 嵌套的 key 会展开为点分形式，只包含复数分类（zero/one/two/few/many/other）的节点视为复数消息：
+//
+//	param:
+//	  null: "param {name} is null."
+//	items:
+//	  one: "{count} item"
+//	  other: "{count} items"
+//
+// 消息中的 {name} 占位符由参数替换。
+package i18n
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+	"gopkg.in/yaml.v2"
+)
+
+// Params 消息参数，替换消息中的 {key} 占位符；包含 count 时按复数规则选择消息
+type Params map[string]interface{}
+
+// Message 待翻译的消息，可以作为 RetJson 的 message 参数在响应时按请求语言翻译
+type Message struct {
+	Key    string
+	Params Params
+}
+
+// M 创建待翻译消息，kv 为成对的参数名和参数值
+//
+//	i18n.M("param.null", "name", "id")
+func M(key string, kv ...interface{}) Message {
+	return Message{Key: key, Params: Pairs(kv...)}
+}
+
+// Pairs 把成对的参数名和参数值转换为 Params
+func Pairs(kv ...interface{}) Params {
+	if len(kv) == 0 {
+		return nil
+	}
+	p := make(Params, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		p[fmt.Sprint(kv[i])] = kv[i+1]
+	}
+	return p
+}
+
+// message 目录中的一条消息，普通消息只有 other
+type message struct {
+	forms map[string]string
+}
+
+var pluralForms = map[string]plural.Form{
+	"other": plural.Other,
+	"zero":  plural.Zero,
+	"one":   plural.One,
+	"two":   plural.Two,
+	"few":   plural.Few,
+	"many":  plural.Many,
+}
+
+var formNames = map[plural.Form]string{
+	plural.Other: "other",
+	plural.Zero:  "zero",
+	plural.One:   "one",
+	plural.Two:   "two",
+	plural.Few:   "few",
+	plural.Many:  "many",
+}
+
+// Bundle 多语言消息目录
+type Bundle struct {
+	mu          sync.RWMutex
+	defaultLang language.Tag
+	tags        []language.Tag
+	catalogs    map[language.Tag]map[string]*message
+	matcher     language.Matcher
+}
+
+// NewBundle 创建消息目录，defaultLang 为协商失败和缺少翻译时使用的语言
+func NewBundle(defaultLang string) *Bundle {
+	tag := language.Make(defaultLang)
+	b := &Bundle{
+		defaultLang: tag,
+		catalogs:    make(map[language.Tag]map[string]*message),
+	}
+	b.addTag(tag)
+	return b
+}
+
+// DefaultLang 默认语言
+func (b *Bundle) DefaultLang() string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.defaultLang.String()
+}
+
+// SetDefaultLang 修改默认语言
+func (b *Bundle) SetDefaultLang(lang string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.defaultLang = language.Make(lang)
+	b.addTag(b.defaultLang)
+	b.rebuildMatcher()
+}
+
+// Langs 已加载的语言
+func (b *Bundle) Langs() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	ret := make([]string, 0, len(b.tags))
+	for _, t := range b.tags {
+		ret = append(ret, t.String())
+	}
+	return ret
+}
+
+// addTag 记录语言并保证默认语言排第一，调用方需持有写锁
+func (b *Bundle) addTag(tag language.Tag) {
+	for _, t := range b.tags {
+		if t == tag {
+			return
+		}
+	}
+	b.tags = append(b.tags, tag)
+	b.rebuildMatcher()
+}
+
+func (b *Bundle) rebuildMatcher() {
+	tags := []language.Tag{b.defaultLang}
+	for _, t := range b.tags {
+		if t != b.defaultLang {
+			tags = append(tags, t)
+		}
+	}
+	b.tags = tags
+	b.matcher = language.NewMatcher(tags)
+}
+
+// LoadFile 加载消息文件，按扩展名识别 .yaml/.yml/.json，语言取自文件名
+func (b *Bundle) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return b.LoadBytes(langFromFileName(path), formatFromFileName(path), data)
+}
+
+// LoadFS 加载目录下的全部消息文件，可用于 embed.FS
+func (b *Bundle) LoadFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		format := formatFromFileName(e.Name())
+		if format == "" {
+			continue
+		}
+		name := e.Name()
+		if dir != "" && dir != "." {
+			name = dir + "/" + name
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		if err = b.LoadBytes(langFromFileName(e.Name()), format, data); err != nil {
+			return fmt.Errorf("load %s err: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// LoadBytes 加载消息内容，format 为 yaml 或 json，已存在的 key 会被覆盖
+func (b *Bundle) LoadBytes(lang string, format string, data []byte) error {
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return fmt.Errorf("invalid language %q: %v", lang, err)
+	}
+	var raw map[string]interface{}
+	switch format {
+	case "yaml", "yml":
+		var m yaml.MapSlice
+		if err = yaml.Unmarshal(data, &m); err != nil {
+			return err
+		}
+		raw = mapSliceToMap(m)
+	case "json":
+		if err = json.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+	default:
+		return errors.New("unsupported message format: " + format)
+	}
+
+	messages := make(map[string]*message)
+	if err = flatten("", raw, messages); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	catalog := b.catalogs[tag]
+	if catalog == nil {
+		catalog = make(map[string]*message)
+		b.catalogs[tag] = catalog
+	}
+	for k, v := range messages {
+		catalog[k] = v
+	}
+	b.addTag(tag)
+	return nil
+}
+
+// AddMessages 直接添加普通消息
+func (b *Bundle) AddMessages(lang string, messages map[string]string) error {
+	raw := make(map[string]interface{}, len(messages))
+	for k, v := range messages {
+		raw[k] = v
+	}
+	data, _ := json.Marshal(raw)
+	return b.LoadBytes(lang, "json", data)
+}
+
+// Match 根据 Accept-Language 选择最合适的已加载语言
+func (b *Bundle) Match(acceptLanguage string) string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if acceptLanguage == "" {
+		return b.defaultLang.String()
+	}
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return b.defaultLang.String()
+	}
+	_, index, confidence := b.matcher.Match(tags...)
+	if confidence == language.No {
+		return b.defaultLang.String()
+	}
+	return b.tags[index].String()
+}
+
+// T 翻译消息，缺少翻译时依次回退到默认语言、key 本身
+func (b *Bundle) T(lang string, key string, params Params) string {
+	tag := language.Make(lang)
+	b.mu.RLock()
+	msg, msgTag := b.lookup(tag, key)
+	b.mu.RUnlock()
+	if msg == nil {
+		return replaceParams(key, params)
+	}
+	return replaceParams(msg.pick(msgTag, params), params)
+}
+
+// Tm 翻译 Message
+func (b *Bundle) Tm(lang string, m Message) string {
+	return b.T(lang, m.Key, m.Params)
+}
+
+// lookup 依次查找 lang、lang 的父语言、默认语言，调用方需持有读锁
+func (b *Bundle) lookup(tag language.Tag, key string) (*message, language.Tag) {
+	for t := tag; ; t = t.Parent() {
+		if msg, ok := b.catalogs[t][key]; ok {
+			return msg, t
+		}
+		if t == language.Und {
+			break
+		}
+	}
+	if msg, ok := b.catalogs[b.defaultLang][key]; ok {
+		return msg, b.defaultLang
+	}
+	return nil, language.Und
+}
+
+// pick 按 count 参数和语言的复数规则选择消息
+func (m *message) pick(tag language.Tag, params Params) string {
+	if len(m.forms) == 1 {
+		return m.forms["other"]
+	}
+	count, ok := params["count"]
+	if !ok {
+		return m.forms["other"]
+	}
+	n, err := strconv.ParseFloat(fmt.Sprint(count), 64)
+	if err != nil {
+		return m.forms["other"]
+	}
+	// 显式提供 zero 时 0 总是使用 zero，不论该语言是否有 zero 分类
+	if s, ok := m.forms["zero"]; ok && n == 0 {
+		return s
+	}
+	if s, ok := m.forms[formNames[matchPlural(tag, n)]]; ok {
+		return s
+	}
+	return m.forms["other"]
+}
+
+// matchPlural 使用 CLDR 复数规则计算 n 的复数分类
+func matchPlural(tag language.Tag, n float64) plural.Form {
+	if n < 0 {
+		n = -n
+	}
+	s := strconv.FormatFloat(n, 'f', -1, 64)
+	i, frac := s, ""
+	if dot := strings.IndexByte(s, '.'); dot >= 0 {
+		i, frac = s[:dot], s[dot+1:]
+	}
+	iv, _ := strconv.Atoi(i)
+	fv, _ := strconv.Atoi(frac)
+	trimmed := strings.TrimRight(frac, "0")
+	tv, _ := strconv.Atoi(trimmed)
+	return plural.Cardinal.MatchPlural(tag, iv, len(frac), len(trimmed), fv, tv)
+}
+
+// replaceParams 替换 {key} 占位符
+func replaceParams(s string, params Params) string {
+	if len(params) == 0 || !strings.Contains(s, "{") {
+		return s
+	}
+	pairs := make([]string, 0, len(params)*2)
+	for k, v := range params {
+		pairs = append(pairs, "{"+k+"}", fmt.Sprint(v))
+	}
+	return strings.NewReplacer(pairs...).Replace(s)
+}
+
+// flatten 展开嵌套的 key，只包含复数分类的节点视为复数消息
+func flatten(prefix string, raw map[string]interface{}, out map[string]*message) error {
+	for k, v := range raw {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch val := v.(type) {
+		case string:
+			out[key] = &message{forms: map[string]string{"other": val}}
+		case map[string]interface{}:
+			if forms, ok := pluralMessage(val); ok {
+				out[key] = &message{forms: forms}
+				continue
+			}
+			if err := flatten(key, val, out); err != nil {
+				return err
+			}
+		default:
+			out[key] = &message{forms: map[string]string{"other": fmt.Sprint(val)}}
+		}
+	}
+	return nil
+}
+
+func pluralMessage(m map[string]interface{}) (map[string]string, bool) {
+	if _, ok := m["other"]; !ok {
+		return nil, false
+	}
+	forms := make(map[string]string, len(m))
+	for k, v := range m {
+		s, isStr := v.(string)
+		if _, isForm := pluralForms[k]; !isForm || !isStr {
+			return nil, false
+		}
+		forms[k] = s
+	}
+	return forms, true
+}
+
+func mapSliceToMap(ms yaml.MapSlice) map[string]interface{} {
+	m := make(map[string]interface{}, len(ms))
+	for _, item := range ms {
+		m[yamlKey(item.Key)] = convertYAML(item.Value)
+	}
+	return m
+}
+
+func convertYAML(v interface{}) interface{} {
+	switch val := v.(type) {
+	case yaml.MapSlice:
+		return mapSliceToMap(val)
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[yamlKey(k)] = convertYAML(item)
+		}
+		return m
+	default:
+		return v
+	}
+}
+
+// yamlKey YAML 中未加引号的 null、true 等 key 会被解析为对应类型，这里还原为字符串
+func yamlKey(k interface{}) string {
+	if k == nil {
+		return "null"
+	}
+	return fmt.Sprint(k)
+}
+
+// langFromFileName zh.yaml -> zh，messages.en-US.json -> en-US
+func langFromFileName(path string) string {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if i := strings.LastIndexByte(base, '.'); i >= 0 {
+		return base[i+1:]
+	}
+	return base
+}
+
+func formatFromFileName(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".json":
+		return "json"
+	}
+	return ""
+}
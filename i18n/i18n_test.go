@@ -0,0 +1,114 @@
+package i18n
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const enYAML = `
+param:
+  null: "param {name} is null."
+items:
+  zero: "no items"
+  one: "{count} item"
+  other: "{count} items"
+`
+
+const ruJSON = `{"files": {"one": "{count} файл", "few": "{count} файла", "many": "{count} файлов", "other": "{count} файла"}}`
+
+func newTestBundle(t *testing.T) *Bundle {
+	b := NewBundle("zh")
+	if err := b.AddMessages("zh", map[string]string{"param.null": "参数 {name} 为空。", "only.zh": "仅中文"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.LoadBytes("en", "yaml", []byte(enYAML)); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.LoadBytes("ru", "json", []byte(ruJSON)); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestBundle_T(t *testing.T) {
+	b := newTestBundle(t)
+	if s := b.T("en", "param.null", Pairs("name", "id")); s != "param id is null." {
+		t.Errorf("英文翻译错误: %s", s)
+	}
+	if s := b.T("zh", "param.null", Pairs("name", "id")); s != "参数 id 为空。" {
+		t.Errorf("中文翻译错误: %s", s)
+	}
+	if s := b.T("en-US", "param.null", Pairs("name", "id")); s != "param id is null." {
+		t.Errorf("en-US 应回退到 en: %s", s)
+	}
+	if s := b.T("en", "only.zh", nil); s != "仅中文" {
+		t.Errorf("缺少翻译应回退到默认语言: %s", s)
+	}
+	if s := b.T("en", "missing.key", nil); s != "missing.key" {
+		t.Errorf("缺少 key 应返回 key 本身: %s", s)
+	}
+	if s := b.Tm("en", M("param.null", "name", "x")); s != "param x is null." {
+		t.Errorf("Tm 翻译错误: %s", s)
+	}
+}
+
+func TestBundle_Plural(t *testing.T) {
+	b := newTestBundle(t)
+	cases := []struct {
+		lang  string
+		key   string
+		count interface{}
+		want  string
+	}{
+		{"en", "items", 0, "no items"},
+		{"en", "items", 1, "1 item"},
+		{"en", "items", 2, "2 items"},
+		{"en", "items", 1.5, "1.5 items"},
+		{"ru", "files", 1, "1 файл"},
+		{"ru", "files", 3, "3 файла"},
+		{"ru", "files", 5, "5 файлов"},
+		{"ru", "files", 21, "21 файл"},
+	}
+	for _, c := range cases {
+		if s := b.T(c.lang, c.key, Params{"count": c.count}); s != c.want {
+			t.Errorf("%s %s count=%v 期望 %q, 实际 %q", c.lang, c.key, c.count, c.want, s)
+		}
+	}
+}
+
+func TestBundle_Match(t *testing.T) {
+	b := newTestBundle(t)
+	cases := map[string]string{
+		"":                          "zh",
+		"en-US,en;q=0.9":            "en",
+		"zh-CN,zh;q=0.9,en;q=0.8":   "zh",
+		"fr-FR,en;q=0.5":            "en",
+		"fr-FR":                     "zh",
+		"ru;q=0.3,en;q=0.8":         "en",
+		"invalid language header!!": "zh",
+	}
+	for header, want := range cases {
+		if got := b.Match(header); got != want {
+			t.Errorf("Accept-Language %q 期望 %s, 实际 %s", header, want, got)
+		}
+	}
+}
+
+func TestBundle_LoadFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "messages.ja.yaml")
+	if err := os.WriteFile(path, []byte("hello: こんにちは\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b := NewBundle("en")
+	if err := b.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if s := b.T("ja", "hello", nil); s != "こんにちは" {
+		t.Errorf("从文件加载失败: %s", s)
+	}
+	if err := b.LoadFS(os.DirFS(dir), "."); err != nil {
+		t.Errorf("LoadFS 失败: %v", err)
+	}
+}
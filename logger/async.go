@@ -0,0 +1,250 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 缓冲区满时的处理方式，用于 AsyncOpt.Overflow、LogOpt.AsyncOverflow
+const (
+	// OverflowBlock 阻塞写日志的协程，直到后台写出腾出空间，不丢日志
+	OverflowBlock = "block"
+	// OverflowDropOldest 丢弃缓冲区中最早的一条，保留最新的日志
+	OverflowDropOldest = "dropOldest"
+	// OverflowDrop 丢弃当前这条
+	OverflowDrop = "drop"
+)
+
+// 异步写入的默认值
+const (
+	defaultAsyncBufferSize    = 4096
+	defaultAsyncBatchSize     = 128
+	defaultAsyncFlushInterval = 200 * time.Millisecond
+)
+
+// AsyncOpt 异步写入配置
+type AsyncOpt struct {
+	// BufferSize 环形缓冲区可以容纳的日志条数，默认 4096
+	BufferSize int
+	// BatchSize 缓冲的日志达到该条数时立即写出，默认 128
+	BatchSize int
+	// FlushInterval 缓冲的日志最长等待时间，到时间不足 BatchSize 也写出，默认 200ms
+	FlushInterval time.Duration
+	// Overflow 缓冲区满时的处理方式 block、dropOldest、drop，默认 block
+	Overflow string
+}
+
+// AsyncStats 异步写入的统计
+type AsyncStats struct {
+	// Buffered 当前缓冲、尚未写出的条数
+	Buffered int
+	// Written 已写出的条数
+	Written uint64
+	// Dropped 缓冲区满被丢弃的条数
+	Dropped uint64
+	// Errors 写出失败的批次数
+	Errors uint64
+}
+
+// AsyncWriter 异步写入，日志先放入环形缓冲区，由后台协程批量写入下层 Writer，
+// 写日志的协程不再等待文件锁、切割和压缩。Sync、Close 会等待缓冲区写完
+type AsyncWriter struct {
+	w        io.Writer
+	batch    int
+	interval time.Duration
+	overflow string
+
+	mu       sync.Mutex
+	notFull  *sync.Cond
+	drained  *sync.Cond
+	ring     [][]byte
+	head     int
+	n        int
+	accepted uint64 // 放入缓冲区的条数
+	done     uint64 // 已写出或被丢弃的条数
+	closed   bool
+	stats    AsyncStats
+
+	wake chan struct{}
+	exit chan struct{}
+}
+
+// NewAsyncWriter 创建异步写入并启动后台协程，不再使用时需要 Close
+func NewAsyncWriter(w io.Writer, opt AsyncOpt) (*AsyncWriter, error) {
+	if opt.BufferSize <= 0 {
+		opt.BufferSize = defaultAsyncBufferSize
+	}
+	if opt.BatchSize <= 0 || opt.BatchSize > opt.BufferSize {
+		opt.BatchSize = min(defaultAsyncBatchSize, opt.BufferSize)
+	}
+	if opt.FlushInterval <= 0 {
+		opt.FlushInterval = defaultAsyncFlushInterval
+	}
+	overflow, err := parseOverflow(opt.Overflow)
+	if err != nil {
+		return nil, err
+	}
+	a := &AsyncWriter{
+		w:        w,
+		batch:    opt.BatchSize,
+		interval: opt.FlushInterval,
+		overflow: overflow,
+		ring:     make([][]byte, opt.BufferSize),
+		wake:     make(chan struct{}, 1),
+		exit:     make(chan struct{}),
+	}
+	a.notFull = sync.NewCond(&a.mu)
+	a.drained = sync.NewCond(&a.mu)
+	go a.run()
+	return a, nil
+}
+
+func parseOverflow(s string) (string, error) {
+	switch strings.ToLower(s) {
+	case "", strings.ToLower(OverflowBlock):
+		return OverflowBlock, nil
+	case strings.ToLower(OverflowDropOldest):
+		return OverflowDropOldest, nil
+	case strings.ToLower(OverflowDrop):
+		return OverflowDrop, nil
+	}
+	return "", fmt.Errorf("unknown overflow policy %q, want block, dropOldest or drop", s)
+}
+
+// Write 复制 p 放入缓冲区后立即返回。关闭后直接同步写入下层 Writer
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return a.w.Write(p)
+	}
+	for a.n == len(a.ring) {
+		switch a.overflow {
+		case OverflowDrop:
+			a.stats.Dropped++
+			a.mu.Unlock()
+			return len(p), nil
+		case OverflowDropOldest:
+			a.head = (a.head + 1) % len(a.ring)
+			a.n--
+			a.done++
+			a.stats.Dropped++
+		default:
+			a.signal()
+			a.notFull.Wait()
+			if a.closed {
+				a.mu.Unlock()
+				return a.w.Write(p)
+			}
+		}
+	}
+	i := (a.head + a.n) % len(a.ring)
+	a.ring[i] = append(a.ring[i][:0], p...)
+	a.n++
+	a.accepted++
+	full := a.n >= a.batch
+	a.mu.Unlock()
+	if full {
+		a.signal()
+	}
+	return len(p), nil
+}
+
+// signal 唤醒后台协程写出缓冲区
+func (a *AsyncWriter) signal() {
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (a *AsyncWriter) run() {
+	defer close(a.exit)
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	var buf []byte
+	for {
+		select {
+		case <-a.wake:
+		case <-ticker.C:
+		}
+		buf = a.flush(buf)
+		a.mu.Lock()
+		stop := a.closed && a.n == 0
+		a.mu.Unlock()
+		if stop {
+			return
+		}
+	}
+}
+
+// flush 把缓冲区中的日志合并为一次写入，buf 为复用的合并缓冲
+func (a *AsyncWriter) flush(buf []byte) []byte {
+	a.mu.Lock()
+	n := a.n
+	if n == 0 {
+		a.mu.Unlock()
+		return buf
+	}
+	buf = buf[:0]
+	for i := 0; i < n; i++ {
+		buf = append(buf, a.ring[(a.head+i)%len(a.ring)]...)
+	}
+	a.head = (a.head + n) % len(a.ring)
+	a.n = 0
+	a.notFull.Broadcast()
+	a.mu.Unlock()
+
+	_, err := a.w.Write(buf)
+
+	a.mu.Lock()
+	a.done += uint64(n)
+	if err != nil {
+		a.stats.Errors++
+	} else {
+		a.stats.Written += uint64(n)
+	}
+	a.drained.Broadcast()
+	a.mu.Unlock()
+	return buf
+}
+
+// Sync 等待调用前写入的日志全部写出
+func (a *AsyncWriter) Sync() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	target := a.accepted
+	for a.done < target {
+		a.signal()
+		a.drained.Wait()
+	}
+	return nil
+}
+
+// Close 写出缓冲区中的日志，停止后台协程并关闭下层 Writer
+func (a *AsyncWriter) Close() error {
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		a.notFull.Broadcast()
+	}
+	a.mu.Unlock()
+	a.signal()
+	<-a.exit
+	if closer, ok := a.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Stats 异步写入的统计，可用于监控丢弃的日志
+func (a *AsyncWriter) Stats() AsyncStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	s := a.stats
+	s.Buffered = a.n
+	return s
+}
@@ -0,0 +1,192 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gateWriter 在 gate 关闭前阻塞写入，用来模拟慢磁盘
+type gateWriter struct {
+	gate chan struct{}
+
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	writes int
+	closed bool
+}
+
+func newGateWriter(open bool) *gateWriter {
+	w := &gateWriter{gate: make(chan struct{})}
+	if open {
+		close(w.gate)
+	}
+	return w
+}
+
+func (w *gateWriter) Write(p []byte) (int, error) {
+	<-w.gate
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes++
+	return w.buf.Write(p)
+}
+
+func (w *gateWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	return nil
+}
+
+func (w *gateWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+// waitBuffered 等待后台协程取走缓冲区中的日志
+func waitBuffered(t *testing.T, a *AsyncWriter, n int) {
+	deadline := time.Now().Add(time.Second)
+	for a.Stats().Buffered != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("buffered %d, want %d", a.Stats().Buffered, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestAsyncWriter_BatchAndSync(t *testing.T) {
+	w := newGateWriter(true)
+	a, err := NewAsyncWriter(w, AsyncOpt{BatchSize: 100, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	for i := 0; i < 10; i++ {
+		_, _ = a.Write([]byte("x\n"))
+	}
+	if err := a.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if w.String() != strings.Repeat("x\n", 10) || w.writes != 1 {
+		t.Errorf("Sync 应一次写出全部日志: %d %q", w.writes, w.String())
+	}
+	if s := a.Stats(); s.Written != 10 || s.Buffered != 0 || s.Dropped != 0 {
+		t.Errorf("stats: %+v", s)
+	}
+}
+
+func TestAsyncWriter_FlushInterval(t *testing.T) {
+	w := newGateWriter(true)
+	a, _ := NewAsyncWriter(w, AsyncOpt{BatchSize: 100, FlushInterval: 10 * time.Millisecond})
+	defer a.Close()
+	_, _ = a.Write([]byte("x\n"))
+	waitBuffered(t, a, 0)
+	deadline := time.Now().Add(time.Second)
+	for w.String() != "x\n" && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if w.String() != "x\n" {
+		t.Errorf("不足一批的日志应按 FlushInterval 写出: %q", w.String())
+	}
+}
+
+func TestAsyncWriter_Overflow(t *testing.T) {
+	cases := []struct {
+		overflow string
+		want     string
+		dropped  uint64
+	}{
+		{OverflowDropOldest, "acd", 1},
+		{OverflowDrop, "abc", 1},
+		{OverflowBlock, "abcd", 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.overflow, func(t *testing.T) {
+			w := newGateWriter(false)
+			a, err := NewAsyncWriter(w, AsyncOpt{BufferSize: 2, BatchSize: 1, FlushInterval: time.Hour, Overflow: tc.overflow})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// a 被后台协程取走后阻塞在写入，b、c 占满缓冲区
+			_, _ = a.Write([]byte("a"))
+			waitBuffered(t, a, 0)
+			_, _ = a.Write([]byte("b"))
+			_, _ = a.Write([]byte("c"))
+
+			written := make(chan struct{})
+			go func() {
+				_, _ = a.Write([]byte("d"))
+				close(written)
+			}()
+			if tc.overflow == OverflowBlock {
+				select {
+				case <-written:
+					t.Fatal("block 时缓冲区满应阻塞")
+				case <-time.After(20 * time.Millisecond):
+				}
+				close(w.gate)
+				<-written
+			} else {
+				<-written
+				close(w.gate)
+			}
+			if err := a.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if w.String() != tc.want || a.Stats().Dropped != tc.dropped || !w.closed {
+				t.Errorf("got %q dropped %d, want %q dropped %d", w.String(), a.Stats().Dropped, tc.want, tc.dropped)
+			}
+		})
+	}
+}
+
+func TestAsyncWriter_WriteAfterClose(t *testing.T) {
+	w := newGateWriter(true)
+	a, _ := NewAsyncWriter(w, AsyncOpt{})
+	_, _ = a.Write([]byte("a"))
+	_ = a.Close()
+	_, _ = a.Write([]byte("b"))
+	if err := a.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if w.String() != "ab" {
+		t.Errorf("关闭后应同步写入: %q", w.String())
+	}
+	if _, err := NewAsyncWriter(w, AsyncOpt{Overflow: "spill"}); err == nil {
+		t.Error("未知的 Overflow 应返回错误")
+	}
+}
+
+func TestNewLogOpt_Async(t *testing.T) {
+	lp, err := NewLogOpt("async_v2", &LogOpt{FileName: "log/async_v2.log", Async: 1, AsyncFlushInterval: time.Hour, Format: FormatLogfmt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Unregister("async_v2")
+	lp.Info("async hello")
+	if err := lp.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(lp.FileName)
+	if err != nil || !strings.Contains(string(data), "async hello") {
+		t.Errorf("Sync 后日志应写入文件: %v %s", err, data)
+	}
+
+	// FATAL 退出前应写出缓冲区
+	exitFunc = func(int) {}
+	defer func() { exitFunc = os.Exit }()
+	lp.Fatal("async fatal")
+	data, _ = os.ReadFile(lp.FileName)
+	if !strings.Contains(string(data), "async fatal") {
+		t.Errorf("FATAL 应在退出前写出: %s", data)
+	}
+	if s := lp.AsyncStats(); s.Written != 2 || s.Dropped != 0 {
+		t.Errorf("stats: %+v", s)
+	}
+	_ = lp.Close()
+}
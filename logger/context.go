@@ -0,0 +1,153 @@
+package logger
+
+import (
+	"context"
+	"io"
+)
+
+// 常用的上下文字段名
+const (
+	FieldRequestID = "request_id"
+	FieldUser      = "user"
+	FieldTraceID   = "trace_id"
+)
+
+type contextKey struct{}
+
+// contextData context 中保存的日志和字段，每次 WithContext 都复制一份，不修改上层 context 的数据
+type contextData struct {
+	log    Log
+	fields []Field
+}
+
+func loadContext(ctx context.Context) *contextData {
+	if ctx == nil {
+		return nil
+	}
+	d, _ := ctx.Value(contextKey{}).(*contextData)
+	return d
+}
+
+// WithContext 返回带日志字段的 context，args 为 key1, value1, key2, value2 形式，也可以直接传 Field 或 slog.Attr。
+// 与已有字段同名时覆盖，之后用 FromContext 或 LogPoolV2.FromContext 记录的日志都会带上这些字段
+//
+//	ctx = logger.WithContext(ctx, logger.FieldUser, uid)
+//	logger.FromContext(ctx).Info("order created", "order", id)
+func WithContext(ctx context.Context, args ...any) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	old := loadContext(ctx)
+	d := &contextData{}
+	if old != nil {
+		d.log = old.log
+		d.fields = old.fields
+	}
+	for _, f := range fieldsFromArgs(nil, args) {
+		d.fields = setField(d.fields, f)
+	}
+	return context.WithValue(ctx, contextKey{}, d)
+}
+
+// setField 设置字段，同名时替换，总是返回新的切片
+func setField(fields []Field, f Field) []Field {
+	out := make([]Field, 0, len(fields)+1)
+	replaced := false
+	for _, old := range fields {
+		if old.Key == f.Key {
+			out = append(out, f)
+			replaced = true
+			continue
+		}
+		out = append(out, old)
+	}
+	if !replaced {
+		out = append(out, f)
+	}
+	return out
+}
+
+// WithRequestID 在 context 中设置 request_id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return WithContext(ctx, FieldRequestID, id)
+}
+
+// WithUser 在 context 中设置 user
+func WithUser(ctx context.Context, user any) context.Context {
+	return WithContext(ctx, FieldUser, user)
+}
+
+// WithTraceID 在 context 中设置 trace_id
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return WithContext(ctx, FieldTraceID, id)
+}
+
+// WithLogger 在 context 中设置 FromContext 使用的日志，例如 gin 把 api 日志放入请求的 context
+func WithLogger(ctx context.Context, l Log) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	d := &contextData{log: l}
+	if old := loadContext(ctx); old != nil {
+		d.fields = old.fields
+	}
+	return context.WithValue(ctx, contextKey{}, d)
+}
+
+// ContextFields context 中的日志字段，返回值不能修改
+func ContextFields(ctx context.Context) []Field {
+	if d := loadContext(ctx); d != nil {
+		return d.fields
+	}
+	return nil
+}
+
+// ContextArgs context 中的日志字段，按 key1, value1, key2, value2 展开，便于传给 slog、klog 等其它日志库
+func ContextArgs(ctx context.Context) []any {
+	fields := ContextFields(ctx)
+	args := make([]any, 0, 2*len(fields))
+	for _, f := range fields {
+		args = append(args, f.Key, f.Value)
+	}
+	return args
+}
+
+// ContextValue context 中名为 key 的字段
+func ContextValue(ctx context.Context, key string) (any, bool) {
+	for _, f := range ContextFields(ctx) {
+		if f.Key == key {
+			return f.Value, true
+		}
+	}
+	return nil, false
+}
+
+// RequestID context 中的 request_id，没有时为空
+func RequestID(ctx context.Context) string {
+	v, _ := ContextValue(ctx, FieldRequestID)
+	s, _ := v.(string)
+	return s
+}
+
+// discard 没有任何日志时 FromContext 使用，丢弃全部日志
+var discard = newCore("", io.Discard, FATAL_LEVEL, false, TextEncoder{})
+
+// FromContext 返回带 context 中字段的子日志。日志使用 WithLogger 设置的日志，没有设置时使用第一个创建的日志，
+// 都没有时丢弃
+func FromContext(ctx context.Context) *FieldLogger {
+	var l Log
+	if d := loadContext(ctx); d != nil && d.log != nil {
+		l = d.log
+	} else if first, err := First(); err == nil {
+		l = first
+	}
+	if l == nil {
+		return &FieldLogger{pool: discard}
+	}
+	return l.FromContext(ctx)
+}
+
+// FromContext 返回带 context 中字段的子日志，例如 request_id、user、trace_id
+func (c *core) FromContext(ctx context.Context) *FieldLogger {
+	return &FieldLogger{pool: c, fields: ContextFields(ctx)}
+}
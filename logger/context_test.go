@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestContext_Fields(t *testing.T) {
+	ctx := WithRequestID(context.Background(), "r1")
+	ctx = WithContext(ctx, FieldUser, "alice", "tenant", 7)
+	child := WithContext(ctx, FieldUser, "bob")
+
+	if RequestID(child) != "r1" {
+		t.Errorf("request_id 应继承: %v", ContextFields(child))
+	}
+	if v, _ := ContextValue(child, FieldUser); v != "bob" {
+		t.Errorf("同名字段应覆盖, 实际 %v", v)
+	}
+	// 上层 context 不受影响
+	if v, _ := ContextValue(ctx, FieldUser); v != "alice" {
+		t.Errorf("上层 context 被修改: %v", v)
+	}
+	if got := ContextArgs(child); len(got) != 6 || got[0] != FieldRequestID || got[3] != "bob" {
+		t.Errorf("ContextArgs: %v", got)
+	}
+	if ContextFields(context.Background()) != nil || RequestID(nil) != "" {
+		t.Error("没有字段时应为空")
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	lp, buf := newBufferLog(t, "context_log", FormatLogfmt)
+	ctx := WithLogger(WithTraceID(context.Background(), "t1"), lp)
+	ctx = WithRequestID(ctx, "r1")
+
+	FromContext(ctx).Info("order created", "order", 42)
+	lp.FromContext(ctx).WithTag("db").Warn("slow")
+	slog.New(lp.Handler()).InfoContext(ctx, "via slog")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("期望 3 行: %q", buf.String())
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, "trace_id=t1 request_id=r1") {
+			t.Errorf("应带 context 字段: %s", line)
+		}
+		if !strings.Contains(line, "context_test.go:") {
+			t.Errorf("调用位置: %s", line)
+		}
+	}
+	if !strings.HasSuffix(lines[0], "order=42") || !strings.Contains(lines[1], "tag=db") {
+		t.Errorf("字段顺序: %q", lines)
+	}
+
+	// ctx 为 nil 时不能 panic
+	FromContext(nil).WithCallerSkip(1).Info("dropped")
+}
@@ -0,0 +1,405 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Chairou/toolbox/util/color"
+)
+
+// Log 统一的日志接口，LogPool、LogPoolV2 都实现该接口，gin.Logger 也是该接口
+type Log interface {
+	Trace(v ...any)
+	Tracef(format string, v ...any)
+	Debug(v ...any)
+	Debugf(format string, v ...any)
+	Info(v ...any)
+	Infof(format string, v ...any)
+	Warn(v ...any)
+	Warnf(format string, v ...any)
+	Error(v ...any)
+	Errorf(format string, v ...any)
+	// Fatal 记录日志后调用 os.Exit(1)
+	Fatal(v ...any)
+	Fatalf(format string, v ...any)
+	// With 创建带固定字段的子日志
+	With(args ...any) *FieldLogger
+	// FromContext 创建带 context 中字段的子日志，见 WithContext
+	FromContext(ctx context.Context) *FieldLogger
+	SetLevel(level int) error
+	GetLevel() int
+	// Sync 等待异步写入的日志写出
+	Sync() error
+	Close() error
+}
+
+// exitFunc FATAL 日志写入后调用，测试中替换
+var exitFunc = os.Exit
+
+// levelRank 级别的先后顺序。为兼容已有配置，DEBUG、INFO、ERROR 的数值保持 0、1、2 不变，
+// 新增的级别不能按数值比较，统一通过 levelRank 比较
+func levelRank(level int) int {
+	switch level {
+	case TRACE_LEVEL:
+		return 0
+	case DEBUG_LEVEL:
+		return 1
+	case INFO_LEVEL:
+		return 2
+	case WARN_LEVEL:
+		return 3
+	case ERROR_LEVEL:
+		return 4
+	case FATAL_LEVEL:
+		return 5
+	}
+	return -1
+}
+
+// ValidLevel 是否为合法的日志级别
+func ValidLevel(level int) bool {
+	return levelRank(level) >= 0
+}
+
+// LevelEnabled 按级别的先后顺序比较，level 不低于 min 时返回 true。
+// 级别的数值不是按严重程度递增的（WARN_LEVEL 为 3，ERROR_LEVEL 为 2），比较级别都应使用该函数
+func LevelEnabled(level, min int) bool {
+	return levelRank(level) >= levelRank(min)
+}
+
+// core 日志的核心实现，LogPool、LogPoolV2 只是在它外面保留了原有的字段和构造函数
+type core struct {
+	name    string
+	level   AtomicLevel
+	console atomic.Bool
+	// pkgLevels 按调用方包路径设置的级别，见 SetPackageLevel
+	pkgLevels atomic.Pointer[pkgLevels]
+
+	mu       sync.Mutex
+	out      io.Writer
+	encoder  Encoder
+	sinks    []Sink
+	redactor *Redactor
+	sampler  atomic.Pointer[sampler]
+}
+
+func newCore(name string, out io.Writer, level int, console bool, enc Encoder) *core {
+	c := &core{name: name, out: out, encoder: enc}
+	_ = c.level.SetLevel(level)
+	c.console.Store(console)
+	return c
+}
+
+// enabled 该级别的日志是否需要输出。按包设置了级别时，这里只按最低的级别粗略判断，output 中再按调用方判断
+func (c *core) enabled(level int) bool {
+	if c.level.Enabled(level) {
+		return true
+	}
+	p := c.pkgLevels.Load()
+	return p != nil && LevelEnabled(level, p.min)
+}
+
+// callerEnabled 按调用位置 pc 所在包的级别判断是否输出
+func (c *core) callerEnabled(level int, pc uintptr) bool {
+	p := c.pkgLevels.Load()
+	if p == nil {
+		return c.level.Enabled(level)
+	}
+	return LevelEnabled(level, p.level(pc, c.level.Level()))
+}
+
+// GetLevel 当前日志级别
+func (c *core) GetLevel() int {
+	return c.level.Level()
+}
+
+func (c *core) setLevel(level int) error {
+	return c.level.SetLevel(level)
+}
+
+// SetEncoder 设置日志编码器，见 TextEncoder、LogfmtEncoder、JSONEncoder
+func (c *core) SetEncoder(enc Encoder) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.encoder = enc
+}
+
+// output 编码并写入一条日志。skip 为调用位置相对 output 的栈帧数：
+// Debug、Infof 等原有方法为 3，记录调用者的上一层，便于 gin.Context 等封装记录业务代码的位置
+func (c *core) output(skip int, level int, tag string, msg string, fields []Field) {
+	pc, file, line, ok := runtime.Caller(skip)
+	if !ok {
+		file, line = "???", 0
+	} else if !c.callerEnabled(level, pc) {
+		return
+	}
+	if s := c.sampler.Load(); s != nil && level != FATAL_LEVEL && !s.allow(level, tag, msg) {
+		return
+	}
+	c.write(&Record{
+		Time:   time.Now(),
+		Level:  level,
+		Logger: c.name,
+		Tag:    tag,
+		Caller: filepath.Base(file) + ":" + strconv.Itoa(line),
+		Msg:    msg,
+		Fields: fields,
+	})
+	if level == FATAL_LEVEL {
+		_ = c.Sync()
+		exitFunc(1)
+	}
+}
+
+func (c *core) write(r *Record) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	c.mu.Lock()
+	enc, out, sinks, redactor := c.encoder, c.out, c.sinks, c.redactor
+	c.mu.Unlock()
+	if redactor != nil {
+		redactor.Redact(r)
+	}
+	if enc == nil {
+		enc = TextEncoder{}
+	}
+	*buf = enc.Encode(*buf, r)
+	if out != nil {
+		_, _ = out.Write(*buf)
+	}
+	if c.console.Load() {
+		s := strings.TrimSuffix(r.Msg, "\n")
+		if r.Tag != "" {
+			s = r.Tag + " " + s
+		}
+		for _, f := range r.Fields {
+			s += " " + string(appendLogfmtPair(nil, f.Key, f.Value))
+		}
+		switch {
+		case levelRank(r.Level) >= levelRank(ERROR_LEVEL):
+			s = color.SetColor(color.Red, s)
+		case r.Level == WARN_LEVEL:
+			s = color.SetColor(color.Yellow, s)
+		}
+		log.Println(s)
+	}
+	for _, sink := range sinks {
+		if sink.Enabled(r.Level) {
+			_ = sink.Write(r)
+		}
+	}
+}
+
+// SetRedactor 设置脱敏规则，在写入日志文件、控制台和 Sink 之前执行，为 nil 时不脱敏
+func (c *core) SetRedactor(r *Redactor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.redactor = r
+}
+
+// SetSampling 设置日志采样，只使用 Logger 为空或等于日志名的配置，没有参数时关闭采样，见 SampleOpt
+func (c *core) SetSampling(opts ...SampleOpt) error {
+	s, err := newSampler(c.name, opts, c.write)
+	if err != nil {
+		return err
+	}
+	c.sampler.Store(s)
+	return nil
+}
+
+// AddSink 增加输出目标，日志同时写入日志文件和全部 Sink，见 NewWriterSink、NewConsoleSink、NewSyslogSink、NewMemorySink
+func (c *core) AddSink(s Sink) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sinks := make([]Sink, len(c.sinks), len(c.sinks)+1)
+	copy(sinks, c.sinks)
+	c.sinks = append(sinks, s)
+}
+
+// Sync 等待异步写入的日志全部写出，同步写入时直接返回
+func (c *core) Sync() error {
+	c.mu.Lock()
+	out := c.out
+	c.mu.Unlock()
+	if s, ok := out.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// AsyncStats 异步写入的统计，同步写入时为零值
+func (c *core) AsyncStats() AsyncStats {
+	c.mu.Lock()
+	out := c.out
+	c.mu.Unlock()
+	if a, ok := out.(*AsyncWriter); ok {
+		return a.Stats()
+	}
+	return AsyncStats{}
+}
+
+// Close 关闭日志文件和全部 Sink，之后再写入时会重新打开。异步写入时先写出缓冲区中的日志
+func (c *core) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	if closer, ok := c.out.(io.Closer); ok {
+		err = closer.Close()
+	}
+	for _, sink := range c.sinks {
+		if e := sink.Close(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// 下面每个级别有四种写法：Xxx(v...)、Xxxf(format, v...)、XxxTag(tag, v...)、XxxfTag(tag, format, v...)
+
+func (c *core) TracefTag(tag string, format string, v ...any) {
+	if c.enabled(TRACE_LEVEL) {
+		c.output(3, TRACE_LEVEL, tag, fmt.Sprintf(format, v...), nil)
+	}
+}
+
+func (c *core) Tracef(format string, v ...any) {
+	if c.enabled(TRACE_LEVEL) {
+		c.output(3, TRACE_LEVEL, "", fmt.Sprintf(format, v...), nil)
+	}
+}
+
+func (c *core) TraceTag(tag string, v ...any) {
+	if c.enabled(TRACE_LEVEL) {
+		c.output(3, TRACE_LEVEL, tag, fmt.Sprintln(v...), nil)
+	}
+}
+
+func (c *core) Trace(v ...any) {
+	if c.enabled(TRACE_LEVEL) {
+		c.output(3, TRACE_LEVEL, "", fmt.Sprintln(v...), nil)
+	}
+}
+
+func (c *core) DebugfTag(tag string, format string, v ...any) {
+	if c.enabled(DEBUG_LEVEL) {
+		c.output(3, DEBUG_LEVEL, tag, fmt.Sprintf(format, v...), nil)
+	}
+}
+
+func (c *core) Debugf(format string, v ...any) {
+	if c.enabled(DEBUG_LEVEL) {
+		c.output(3, DEBUG_LEVEL, "", fmt.Sprintf(format, v...), nil)
+	}
+}
+
+func (c *core) DebugTag(tag string, v ...any) {
+	if c.enabled(DEBUG_LEVEL) {
+		c.output(3, DEBUG_LEVEL, tag, fmt.Sprintln(v...), nil)
+	}
+}
+
+func (c *core) Debug(v ...any) {
+	if c.enabled(DEBUG_LEVEL) {
+		c.output(3, DEBUG_LEVEL, "", fmt.Sprintln(v...), nil)
+	}
+}
+
+func (c *core) InfofTag(tag string, format string, v ...any) {
+	if c.enabled(INFO_LEVEL) {
+		c.output(3, INFO_LEVEL, tag, fmt.Sprintf(format, v...), nil)
+	}
+}
+
+func (c *core) Infof(format string, v ...any) {
+	if c.enabled(INFO_LEVEL) {
+		c.output(3, INFO_LEVEL, "", fmt.Sprintf(format, v...), nil)
+	}
+}
+
+func (c *core) InfoTag(tag string, v ...any) {
+	if c.enabled(INFO_LEVEL) {
+		c.output(3, INFO_LEVEL, tag, fmt.Sprintln(v...), nil)
+	}
+}
+
+func (c *core) Info(v ...any) {
+	if c.enabled(INFO_LEVEL) {
+		c.output(3, INFO_LEVEL, "", fmt.Sprintln(v...), nil)
+	}
+}
+
+func (c *core) WarnfTag(tag string, format string, v ...any) {
+	if c.enabled(WARN_LEVEL) {
+		c.output(3, WARN_LEVEL, tag, fmt.Sprintf(format, v...), nil)
+	}
+}
+
+func (c *core) Warnf(format string, v ...any) {
+	if c.enabled(WARN_LEVEL) {
+		c.output(3, WARN_LEVEL, "", fmt.Sprintf(format, v...), nil)
+	}
+}
+
+func (c *core) WarnTag(tag string, v ...any) {
+	if c.enabled(WARN_LEVEL) {
+		c.output(3, WARN_LEVEL, tag, fmt.Sprintln(v...), nil)
+	}
+}
+
+func (c *core) Warn(v ...any) {
+	if c.enabled(WARN_LEVEL) {
+		c.output(3, WARN_LEVEL, "", fmt.Sprintln(v...), nil)
+	}
+}
+
+func (c *core) ErrorfTag(tag string, format string, v ...any) {
+	if c.enabled(ERROR_LEVEL) {
+		c.output(3, ERROR_LEVEL, tag, fmt.Sprintf(format, v...), nil)
+	}
+}
+
+func (c *core) Errorf(format string, v ...any) {
+	if c.enabled(ERROR_LEVEL) {
+		c.output(3, ERROR_LEVEL, "", fmt.Sprintf(format, v...), nil)
+	}
+}
+
+func (c *core) ErrorTag(tag string, v ...any) {
+	if c.enabled(ERROR_LEVEL) {
+		c.output(3, ERROR_LEVEL, tag, fmt.Sprintln(v...), nil)
+	}
+}
+
+func (c *core) Error(v ...any) {
+	if c.enabled(ERROR_LEVEL) {
+		c.output(3, ERROR_LEVEL, "", fmt.Sprintln(v...), nil)
+	}
+}
+
+// FATAL 日志总是输出，写入后调用 os.Exit(1)
+
+func (c *core) FatalfTag(tag string, format string, v ...any) {
+	c.output(3, FATAL_LEVEL, tag, fmt.Sprintf(format, v...), nil)
+}
+
+func (c *core) Fatalf(format string, v ...any) {
+	c.output(3, FATAL_LEVEL, "", fmt.Sprintf(format, v...), nil)
+}
+
+func (c *core) FatalTag(tag string, v ...any) {
+	c.output(3, FATAL_LEVEL, tag, fmt.Sprintln(v...), nil)
+}
+
+func (c *core) Fatal(v ...any) {
+	c.output(3, FATAL_LEVEL, "", fmt.Sprintln(v...), nil)
+}
@@ -0,0 +1,118 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+var (
+	_ Log = (*LogPool)(nil)
+	_ Log = (*LogPoolV2)(nil)
+)
+
+func TestCore_Levels(t *testing.T) {
+	lp, buf := newBufferLog(t, "core_levels", "")
+	if err := lp.SetLevel(WARN_LEVEL); err != nil {
+		t.Fatal(err)
+	}
+	lp.Info("info")
+	lp.Warnf("warn %d", 1)
+	lp.Error("error")
+	lp.Trace("trace")
+	out := buf.String()
+	if strings.Contains(out, "info") || strings.Contains(out, "trace") {
+		t.Errorf("WARN 级别不应输出 INFO、TRACE: %s", out)
+	}
+	if !strings.Contains(out, "WARN: ") || !strings.Contains(out, "ERROR: ") {
+		t.Errorf("WARN 级别应输出 WARN、ERROR: %s", out)
+	}
+
+	buf.Reset()
+	_ = lp.SetLevel(TRACE_LEVEL)
+	lp.TraceTag("T", "trace")
+	if !strings.HasPrefix(buf.String(), "TRACE: ") || lp.GetLevel() != TRACE_LEVEL || lp.Level != TRACE_LEVEL {
+		t.Errorf("TRACE 级别应输出全部日志: %s", buf.String())
+	}
+}
+
+func TestLevelEnabled(t *testing.T) {
+	// WARN_LEVEL 的数值大于 ERROR_LEVEL，但严重程度更低
+	if !LevelEnabled(ERROR_LEVEL, WARN_LEVEL) || LevelEnabled(WARN_LEVEL, ERROR_LEVEL) {
+		t.Errorf("WARN 级别应输出 ERROR，ERROR 级别不应输出 WARN")
+	}
+	if !LevelEnabled(FATAL_LEVEL, ERROR_LEVEL) || LevelEnabled(TRACE_LEVEL, DEBUG_LEVEL) {
+		t.Errorf("级别顺序应为 TRACE < DEBUG < INFO < WARN < ERROR < FATAL")
+	}
+}
+
+func TestCore_Fatal(t *testing.T) {
+	lp, buf := newBufferLog(t, "core_fatal", FormatJSON)
+	code := -1
+	exitFunc = func(c int) { code = c }
+	defer func() { exitFunc = os.Exit }()
+	_ = lp.SetLevel(FATAL_LEVEL)
+	lp.Error("hidden")
+	lp.Fatalf("boom %d", 1)
+	if code != 1 || !strings.Contains(buf.String(), `"level":"FATAL"`) || strings.Contains(buf.String(), "hidden") {
+		t.Errorf("FATAL 应写入日志后退出: %d %s", code, buf.String())
+	}
+}
+
+// wrapper 模拟 gin.Context 对日志的封装，记录的位置应为调用 wrapper 的代码
+func wrapper(l Log, msg string) {
+	l.Info(msg)
+}
+
+func TestCore_CallerThroughInterface(t *testing.T) {
+	lp, err := NewLogPool("core_caller", "log/core_caller.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lp.Close()
+	buf := &bytes.Buffer{}
+	lp.out = buf
+	wrapper(lp, "hello")
+	if !strings.Contains(buf.String(), "core_test.go:") {
+		t.Errorf("应记录调用封装函数的位置: %s", buf.String())
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	lp, err := NewLogPool("registry_v1", "log/registry_v1.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lp.Close()
+	if l, err := Get("registry_v1"); err != nil || l != Log(lp) {
+		t.Errorf("Get 应返回已注册的日志: %v", err)
+	}
+	if _, err = Get("registry_none"); !errors.Is(err, ErrLoggerNotFound) {
+		t.Errorf("不存在的日志应返回 ErrLoggerNotFound: %v", err)
+	}
+	if _, err = NewLogOpt("registry_v1", &LogOpt{FileName: "log/registry_v2.log"}); err == nil {
+		t.Errorf("名字已被其它类型使用时应返回错误")
+	}
+	if GetLogNameV2("registry_v1") != nil {
+		t.Errorf("类型不匹配时应返回 nil")
+	}
+	if err = Register("registry_v1", lp); err == nil {
+		t.Errorf("重复注册应返回错误")
+	}
+	found := false
+	for _, name := range Names() {
+		found = found || name == "registry_v1"
+	}
+	if !found {
+		t.Errorf("Names 应包含已注册的日志: %v", Names())
+	}
+	if first, err := First(); err != nil || first == nil {
+		t.Errorf("First 应返回第一个日志: %v", err)
+	}
+	Unregister("registry_v1")
+	if GetLogName("registry_v1") != nil {
+		t.Errorf("Unregister 后不应再能获取")
+	}
+}
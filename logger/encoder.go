@@ -0,0 +1,286 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Field 结构化日志的一个字段
+type Field struct {
+	Key   string
+	Value any
+}
+
+// F 创建字段
+func F(key string, value any) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Record 一条日志记录，交给 Encoder 编码
+type Record struct {
+	Time   time.Time
+	Level  int
+	Logger string
+	Tag    string
+	// Caller 调用位置，形如 file.go:12
+	Caller string
+	Msg    string
+	Fields []Field
+}
+
+// Encoder 日志编码器，把一条记录追加到 buf 并返回，结尾需要换行
+type Encoder interface {
+	Encode(buf []byte, r *Record) []byte
+}
+
+// 日志格式，用于 LogOpt.Format
+const (
+	FormatText   = "text"
+	FormatLogfmt = "logfmt"
+	FormatJSON   = "json"
+)
+
+// NewEncoder 按格式名创建编码器，为空时使用 text
+func NewEncoder(format string) (Encoder, error) {
+	switch strings.ToLower(format) {
+	case "", FormatText:
+		return TextEncoder{}, nil
+	case FormatLogfmt:
+		return LogfmtEncoder{}, nil
+	case FormatJSON:
+		return JSONEncoder{}, nil
+	}
+	return nil, fmt.Errorf("unknown log format %q, want text, logfmt or json", format)
+}
+
+// LevelName 日志级别名，例如 INFO
+func LevelName(level int) string {
+	switch level {
+	case TRACE_LEVEL:
+		return "TRACE"
+	case DEBUG_LEVEL:
+		return "DEBUG"
+	case INFO_LEVEL:
+		return "INFO"
+	case WARN_LEVEL:
+		return "WARN"
+	case ERROR_LEVEL:
+		return "ERROR"
+	case FATAL_LEVEL:
+		return "FATAL"
+	}
+	return "LEVEL(" + strconv.Itoa(level) + ")"
+}
+
+// TextEncoder 原有的文本格式：INFO: 2006-01-02 15:04:05 file.go:12: tag msg key=value
+type TextEncoder struct{}
+
+func (TextEncoder) Encode(buf []byte, r *Record) []byte {
+	buf = append(buf, LevelName(r.Level)...)
+	buf = append(buf, ": "...)
+	buf = r.Time.AppendFormat(buf, "2006-01-02 15:04:05 ")
+	buf = append(buf, r.Caller...)
+	buf = append(buf, ": "...)
+	if r.Tag != "" {
+		buf = append(buf, r.Tag...)
+		buf = append(buf, ' ')
+	}
+	buf = append(buf, strings.TrimSuffix(r.Msg, "\n")...)
+	for _, f := range r.Fields {
+		buf = append(buf, ' ')
+		buf = appendLogfmtPair(buf, f.Key, f.Value)
+	}
+	return append(buf, '\n')
+}
+
+// LogfmtEncoder logfmt 格式：time=... level=INFO logger=api caller=file.go:12 msg="..." key=value
+type LogfmtEncoder struct{}
+
+func (LogfmtEncoder) Encode(buf []byte, r *Record) []byte {
+	buf = append(buf, "time="...)
+	buf = r.Time.AppendFormat(buf, time.RFC3339Nano)
+	buf = append(buf, " level="...)
+	buf = append(buf, LevelName(r.Level)...)
+	if r.Logger != "" {
+		buf = append(buf, ' ')
+		buf = appendLogfmtPair(buf, "logger", r.Logger)
+	}
+	if r.Tag != "" {
+		buf = append(buf, ' ')
+		buf = appendLogfmtPair(buf, "tag", r.Tag)
+	}
+	buf = append(buf, " caller="...)
+	buf = append(buf, r.Caller...)
+	buf = append(buf, ' ')
+	buf = appendLogfmtPair(buf, "msg", strings.TrimSuffix(r.Msg, "\n"))
+	for _, f := range r.Fields {
+		buf = append(buf, ' ')
+		buf = appendLogfmtPair(buf, f.Key, f.Value)
+	}
+	return append(buf, '\n')
+}
+
+func appendLogfmtPair(buf []byte, key string, value any) []byte {
+	buf = appendLogfmtString(buf, key)
+	buf = append(buf, '=')
+	return appendLogfmtString(buf, fieldString(value))
+}
+
+// appendLogfmtString 含空白、引号、等号或为空的值加引号
+func appendLogfmtString(buf []byte, s string) []byte {
+	if s == "" || strings.ContainsAny(s, " \t\r\n\"=") || !utf8.ValidString(s) {
+		return strconv.AppendQuote(buf, s)
+	}
+	return append(buf, s...)
+}
+
+// fieldString 字段值转为字符串，time.Duration、error、fmt.Stringer 使用各自的字符串形式
+func fieldString(v any) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case nil:
+		return "<nil>"
+	case error:
+		return x.Error()
+	case fmt.Stringer:
+		return x.String()
+	}
+	return fmt.Sprint(v)
+}
+
+// JSONEncoder JSON 格式，每条日志一行：
+// {"time":"...","level":"INFO","logger":"api","tag":"x","caller":"file.go:12","msg":"...","key":"value"}
+type JSONEncoder struct{}
+
+func (JSONEncoder) Encode(buf []byte, r *Record) []byte {
+	buf = append(buf, `{"time":"`...)
+	buf = r.Time.AppendFormat(buf, time.RFC3339Nano)
+	buf = append(buf, `","level":"`...)
+	buf = append(buf, LevelName(r.Level)...)
+	buf = append(buf, '"')
+	if r.Logger != "" {
+		buf = appendJSONPair(buf, "logger", r.Logger)
+	}
+	if r.Tag != "" {
+		buf = appendJSONPair(buf, "tag", r.Tag)
+	}
+	buf = appendJSONPair(buf, "caller", r.Caller)
+	buf = appendJSONPair(buf, "msg", strings.TrimSuffix(r.Msg, "\n"))
+	for _, f := range r.Fields {
+		buf = appendJSONPair(buf, f.Key, f.Value)
+	}
+	return append(buf, "}\n"...)
+}
+
+func appendJSONPair(buf []byte, key string, value any) []byte {
+	buf = append(buf, ',')
+	buf = appendJSONString(buf, key)
+	buf = append(buf, ':')
+	return appendJSONValue(buf, value)
+}
+
+func appendJSONValue(buf []byte, v any) []byte {
+	switch x := v.(type) {
+	case string:
+		return appendJSONString(buf, x)
+	case bool:
+		return strconv.AppendBool(buf, x)
+	case int:
+		return strconv.AppendInt(buf, int64(x), 10)
+	case int64:
+		return strconv.AppendInt(buf, x, 10)
+	case int32:
+		return strconv.AppendInt(buf, int64(x), 10)
+	case uint:
+		return strconv.AppendUint(buf, uint64(x), 10)
+	case uint64:
+		return strconv.AppendUint(buf, x, 10)
+	case uint32:
+		return strconv.AppendUint(buf, uint64(x), 10)
+	case float64:
+		return appendJSONFloat(buf, x, 64)
+	case float32:
+		return appendJSONFloat(buf, float64(x), 32)
+	case time.Time:
+		buf = append(buf, '"')
+		buf = x.AppendFormat(buf, time.RFC3339Nano)
+		return append(buf, '"')
+	case nil:
+		return append(buf, "null"...)
+	case error, fmt.Stringer:
+		return appendJSONString(buf, fieldString(x))
+	case json.Marshaler:
+		if b, err := x.MarshalJSON(); err == nil {
+			return append(buf, b...)
+		}
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return appendJSONString(buf, fmt.Sprint(v))
+	}
+	return append(buf, b...)
+}
+
+// appendJSONFloat NaN 和 Inf 不是合法的 JSON 数字，按字符串输出
+func appendJSONFloat(buf []byte, f float64, bits int) []byte {
+	s := strconv.FormatFloat(f, 'g', -1, bits)
+	if s == "NaN" || strings.HasSuffix(s, "Inf") {
+		return appendJSONString(buf, s)
+	}
+	return append(buf, s...)
+}
+
+func appendJSONString(buf []byte, s string) []byte {
+	b, _ := json.Marshal(s)
+	return append(buf, b...)
+}
+
+// fieldsFromArgs 把 key1, value1, key2, value2 形式的参数转为字段，参数也可以直接是 Field 或 slog.Attr。
+// 缺少值的 key 记为 !BADKEY，与 slog 一致
+func fieldsFromArgs(fields []Field, args []any) []Field {
+	for len(args) > 0 {
+		switch x := args[0].(type) {
+		case Field:
+			fields = append(fields, x)
+			args = args[1:]
+		case slog.Attr:
+			fields = appendAttr(fields, "", x)
+			args = args[1:]
+		case string:
+			if len(args) == 1 {
+				fields = append(fields, Field{Key: "!BADKEY", Value: x})
+				return fields
+			}
+			fields = append(fields, Field{Key: x, Value: args[1]})
+			args = args[2:]
+		default:
+			fields = append(fields, Field{Key: "!BADKEY", Value: x})
+			args = args[1:]
+		}
+	}
+	return fields
+}
+
+// appendAttr 把 slog.Attr 转为字段，分组展开为 group.key
+func appendAttr(fields []Field, prefix string, a slog.Attr) []Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			fields = appendAttr(fields, prefix, ga)
+		}
+		return fields
+	}
+	return append(fields, Field{Key: prefix + a.Key, Value: a.Value.Any()})
+}
@@ -0,0 +1,309 @@
+package logger
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AtomicLevel 可以并发读写的日志级别，零值为 DEBUG_LEVEL
+type AtomicLevel struct {
+	v atomic.Int32
+}
+
+// NewAtomicLevel 创建级别为 level 的 AtomicLevel，level 不合法时使用 DEBUG_LEVEL
+func NewAtomicLevel(level int) *AtomicLevel {
+	l := &AtomicLevel{}
+	_ = l.SetLevel(level)
+	return l
+}
+
+// Level 当前级别
+func (l *AtomicLevel) Level() int {
+	return int(l.v.Load())
+}
+
+// SetLevel 修改级别，可选 TRACE_LEVEL、DEBUG_LEVEL、INFO_LEVEL、WARN_LEVEL、ERROR_LEVEL、FATAL_LEVEL
+func (l *AtomicLevel) SetLevel(level int) error {
+	if !ValidLevel(level) {
+		return fmt.Errorf("level must be one of TRACE_LEVEL, DEBUG_LEVEL, INFO_LEVEL, WARN_LEVEL, ERROR_LEVEL, FATAL_LEVEL, got %d", level)
+	}
+	l.v.Store(int32(level))
+	return nil
+}
+
+// Enabled 该级别的日志是否需要输出
+func (l *AtomicLevel) Enabled(level int) bool {
+	return LevelEnabled(level, l.Level())
+}
+
+func (l *AtomicLevel) String() string {
+	return LevelName(l.Level())
+}
+
+// pkgLevel 一个包路径前缀的级别
+type pkgLevel struct {
+	prefix string
+	level  int
+}
+
+// pkgLevels 按调用方包路径设置的级别，修改时整体替换
+type pkgLevels struct {
+	// rules 按前缀从长到短排序，优先使用最长的匹配
+	rules []pkgLevel
+	// min 最低的级别，低于它的日志不需要查找调用方
+	min int
+}
+
+// level 调用位置 pc 所在包的级别，没有匹配的前缀时为 base
+func (p *pkgLevels) level(pc uintptr, base int) int {
+	pkg := callerPackage(pc)
+	for _, r := range p.rules {
+		if matchPackage(pkg, r.prefix) {
+			return r.level
+		}
+	}
+	return base
+}
+
+// callerPackages 调用位置到包路径的缓存，调用位置的数量有限
+var callerPackages sync.Map
+
+// callerPackage 调用位置所在的包路径，例如 github.com/acme/myapp/payment
+func callerPackage(pc uintptr) string {
+	if v, ok := callerPackages.Load(pc); ok {
+		return v.(string)
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	// 函数名形如 github.com/acme/myapp/payment.(*Service).Pay
+	name := frame.Function
+	slash := strings.LastIndexByte(name, '/')
+	if dot := strings.IndexByte(name[slash+1:], '.'); dot >= 0 {
+		name = name[:slash+1+dot]
+	}
+	callerPackages.Store(pc, name)
+	return name
+}
+
+// matchPackage 包路径 pkg 是否在 prefix 下，prefix 可以省略开头的部分，例如 myapp/payment 匹配
+// github.com/acme/myapp/payment 和 github.com/acme/myapp/payment/refund，不匹配 myapp/paymentx
+func matchPackage(pkg, prefix string) bool {
+	for {
+		i := strings.Index(pkg, prefix)
+		if i < 0 {
+			return false
+		}
+		end := i + len(prefix)
+		if (i == 0 || pkg[i-1] == '/') && (end == len(pkg) || pkg[end] == '/') {
+			return true
+		}
+		pkg = pkg[i+1:]
+	}
+}
+
+// SetPackageLevel 设置调用方包路径在 prefix 下的日志的级别，优先于日志本身的级别，可以更低也可以更高，
+// 例如只对 myapp/payment 开启 DEBUG。prefix 可以省略模块路径的开头部分，有多个匹配时使用最长的。
+// 调用方与日志中记录的 caller 相同
+func (c *core) SetPackageLevel(prefix string, level int) error {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return fmt.Errorf("logger %s: package prefix is empty", c.name)
+	}
+	if !ValidLevel(level) {
+		return fmt.Errorf("logger %s: invalid level %d for package %s", c.name, level, prefix)
+	}
+	c.updatePackageLevels(func(m map[string]int) { m[prefix] = level })
+	return nil
+}
+
+// DeletePackageLevel 删除 SetPackageLevel 设置的级别
+func (c *core) DeletePackageLevel(prefix string) {
+	c.updatePackageLevels(func(m map[string]int) { delete(m, strings.Trim(prefix, "/")) })
+}
+
+// PackageLevels SetPackageLevel 设置的级别，key 为包路径前缀
+func (c *core) PackageLevels() map[string]int {
+	m := map[string]int{}
+	if p := c.pkgLevels.Load(); p != nil {
+		for _, r := range p.rules {
+			m[r.prefix] = r.level
+		}
+	}
+	return m
+}
+
+func (c *core) updatePackageLevels(update func(m map[string]int)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	m := c.PackageLevels()
+	update(m)
+	if len(m) == 0 {
+		c.pkgLevels.Store(nil)
+		return
+	}
+	p := &pkgLevels{min: FATAL_LEVEL}
+	for prefix, level := range m {
+		p.rules = append(p.rules, pkgLevel{prefix: prefix, level: level})
+		if !LevelEnabled(level, p.min) {
+			p.min = level
+		}
+	}
+	sort.Slice(p.rules, func(i, j int) bool {
+		if len(p.rules[i].prefix) != len(p.rules[j].prefix) {
+			return len(p.rules[i].prefix) > len(p.rules[j].prefix)
+		}
+		return p.rules[i].prefix < p.rules[j].prefix
+	})
+	c.pkgLevels.Store(p)
+}
+
+// packageLeveler 支持按包设置级别的日志，LogPool、LogPoolV2 都支持
+type packageLeveler interface {
+	SetPackageLevel(prefix string, level int) error
+	DeletePackageLevel(prefix string)
+	PackageLevels() map[string]int
+}
+
+// revertKey 自动恢复的对象，pkg 为空表示日志本身的级别
+type revertKey struct {
+	logger, pkg string
+}
+
+type levelRevert struct {
+	timer   *time.Timer
+	at      time.Time
+	restore func()
+}
+
+// levelReverts 等待自动恢复的级别修改，锁同时保证修改级别和记录恢复值的顺序
+var levelReverts = struct {
+	sync.Mutex
+	m map[revertKey]*levelRevert
+}{m: map[revertKey]*levelRevert{}}
+
+// ChangeLevel 运行时修改名为 name 的日志的级别，pkg 不为空时修改该包路径前缀的级别，见 SetPackageLevel。
+// revertAfter 大于 0 时到期自动恢复为修改前的级别，例如线上临时开启 10 分钟 DEBUG；到期前再次修改会重新计时，
+// 恢复的仍是第一次修改前的级别。revertAfter 为 0 时是永久修改，同时取消等待中的恢复
+func ChangeLevel(name, pkg string, level int, revertAfter time.Duration) error {
+	l, err := Get(name)
+	if err != nil {
+		return err
+	}
+	pkg = strings.Trim(pkg, "/")
+	levelReverts.Lock()
+	defer levelReverts.Unlock()
+	var restore func()
+	if pkg == "" {
+		old := l.GetLevel()
+		if err := l.SetLevel(level); err != nil {
+			return err
+		}
+		restore = func() { _ = l.SetLevel(old) }
+	} else {
+		pl, ok := l.(packageLeveler)
+		if !ok {
+			return fmt.Errorf("logger %s (%T) does not support package levels", name, l)
+		}
+		old, had := pl.PackageLevels()[pkg]
+		if err := pl.SetPackageLevel(pkg, level); err != nil {
+			return err
+		}
+		restore = func() {
+			if had {
+				_ = pl.SetPackageLevel(pkg, old)
+			} else {
+				pl.DeletePackageLevel(pkg)
+			}
+		}
+	}
+	key := revertKey{logger: name, pkg: pkg}
+	if r := levelReverts.m[key]; r != nil {
+		r.timer.Stop()
+		restore = r.restore
+		delete(levelReverts.m, key)
+	}
+	if revertAfter > 0 {
+		r := &levelRevert{at: time.Now().Add(revertAfter), restore: restore}
+		r.timer = time.AfterFunc(revertAfter, func() {
+			levelReverts.Lock()
+			defer levelReverts.Unlock()
+			if levelReverts.m[key] == r {
+				delete(levelReverts.m, key)
+				r.restore()
+			}
+		})
+		levelReverts.m[key] = r
+	}
+	return nil
+}
+
+// DeletePackageLevel 删除名为 name 的日志中包路径前缀 pkg 的级别，并取消等待中的恢复
+func DeletePackageLevel(name, pkg string) error {
+	l, err := Get(name)
+	if err != nil {
+		return err
+	}
+	pl, ok := l.(packageLeveler)
+	if !ok {
+		return fmt.Errorf("logger %s (%T) does not support package levels", name, l)
+	}
+	pkg = strings.Trim(pkg, "/")
+	levelReverts.Lock()
+	defer levelReverts.Unlock()
+	key := revertKey{logger: name, pkg: pkg}
+	if r := levelReverts.m[key]; r != nil {
+		r.timer.Stop()
+		delete(levelReverts.m, key)
+	}
+	pl.DeletePackageLevel(pkg)
+	return nil
+}
+
+// PackageLevel 一个包路径前缀的级别，见 LoggerLevels
+type PackageLevel struct {
+	Package string `json:"package"`
+	Level   string `json:"level"`
+	// RevertAt 自动恢复的时间，不会自动恢复时为空
+	RevertAt *time.Time `json:"revertAt,omitempty"`
+}
+
+// LoggerLevels 一个日志当前的级别，见 Levels
+type LoggerLevels struct {
+	Logger   string         `json:"logger"`
+	Level    string         `json:"level"`
+	RevertAt *time.Time     `json:"revertAt,omitempty"`
+	Packages []PackageLevel `json:"packages,omitempty"`
+}
+
+// Levels 全部已注册日志的级别，按名字排序
+func Levels() []LoggerLevels {
+	levelReverts.Lock()
+	defer levelReverts.Unlock()
+	revertAt := func(name, pkg string) *time.Time {
+		if r := levelReverts.m[revertKey{logger: name, pkg: pkg}]; r != nil {
+			at := r.at
+			return &at
+		}
+		return nil
+	}
+	var out []LoggerLevels
+	for _, name := range Names() {
+		l, err := Get(name)
+		if err != nil {
+			continue
+		}
+		ll := LoggerLevels{Logger: name, Level: LevelName(l.GetLevel()), RevertAt: revertAt(name, "")}
+		if pl, ok := l.(packageLeveler); ok {
+			for prefix, level := range pl.PackageLevels() {
+				ll.Packages = append(ll.Packages, PackageLevel{Package: prefix, Level: LevelName(level), RevertAt: revertAt(name, prefix)})
+			}
+			sort.Slice(ll.Packages, func(i, j int) bool { return ll.Packages[i].Package < ll.Packages[j].Package })
+		}
+		out = append(out, ll)
+	}
+	return out
+}
@@ -0,0 +1,111 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAtomicLevel(t *testing.T) {
+	l := NewAtomicLevel(WARN_LEVEL)
+	if !l.Enabled(ERROR_LEVEL) || l.Enabled(INFO_LEVEL) || l.String() != "WARN" {
+		t.Errorf("WARN 级别: %s", l)
+	}
+	if err := l.SetLevel(99); err == nil || l.Level() != WARN_LEVEL {
+		t.Error("不合法的级别应返回错误且不修改")
+	}
+}
+
+func TestMatchPackage(t *testing.T) {
+	cases := []struct {
+		pkg, prefix string
+		want        bool
+	}{
+		{"github.com/acme/myapp/payment", "myapp/payment", true},
+		{"github.com/acme/myapp/payment/refund", "myapp/payment", true},
+		{"github.com/acme/myapp/paymentx", "myapp/payment", false},
+		{"github.com/acme/notmyapp/payment", "myapp/payment", false},
+		{"github.com/acme/myapp/payment", "github.com/acme/myapp", true},
+		{"main", "main", true},
+	}
+	for _, c := range cases {
+		if got := matchPackage(c.pkg, c.prefix); got != c.want {
+			t.Errorf("matchPackage(%q, %q) = %v", c.pkg, c.prefix, got)
+		}
+	}
+}
+
+// 调用位置与记录的 caller 一致：结构化方法是直接调用方，兼容的 Debug 等方法是调用方的上一层
+func TestPackageLevel(t *testing.T) {
+	lp, buf := newBufferLog(t, "package_level", FormatLogfmt)
+	_ = lp.SetLevel(INFO_LEVEL)
+
+	lp.Debugw("hidden")
+	if err := lp.SetPackageLevel("Chairou/toolbox/logger", DEBUG_LEVEL); err != nil {
+		t.Fatal(err)
+	}
+	lp.Debugw("pkg debug")
+	lp.With("k", 1).Debug("field debug")
+	// 其它包的前缀不影响本包
+	_ = lp.SetPackageLevel("myapp/payment", TRACE_LEVEL)
+	lp.With().Trace("hidden")
+	// 更长的前缀优先，也可以提高级别
+	_ = lp.SetPackageLevel("github.com/Chairou/toolbox/logger", ERROR_LEVEL)
+	lp.Warnw("hidden")
+	lp.Errorw("pkg error")
+
+	out := buf.String()
+	if strings.Contains(out, "hidden") || strings.Count(out, "\n") != 3 {
+		t.Errorf("按包的级别: %s", out)
+	}
+	lp.DeletePackageLevel("github.com/Chairou/toolbox/logger")
+	lp.DeletePackageLevel("Chairou/toolbox/logger")
+	lp.DeletePackageLevel("myapp/payment")
+	if lp.pkgLevels.Load() != nil {
+		t.Error("全部删除后不再按包判断")
+	}
+	if err := lp.SetPackageLevel("", DEBUG_LEVEL); err == nil {
+		t.Error("空的前缀应返回错误")
+	}
+}
+
+func TestChangeLevel_Revert(t *testing.T) {
+	lp, _ := newBufferLog(t, "change_level", "")
+	defer Unregister("change_level")
+	_ = lp.SetLevel(INFO_LEVEL)
+
+	if err := ChangeLevel("change_level", "", DEBUG_LEVEL, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	// 到期前再次修改，恢复的仍是第一次修改前的 INFO
+	if err := ChangeLevel("change_level", "", TRACE_LEVEL, 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := ChangeLevel("change_level", "myapp/payment", DEBUG_LEVEL, 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, ll := range Levels() {
+		if ll.Logger == "change_level" {
+			found = ll.Level == "TRACE" && ll.RevertAt != nil && len(ll.Packages) == 1 && ll.Packages[0].RevertAt != nil
+		}
+	}
+	if !found || lp.GetLevel() != TRACE_LEVEL {
+		t.Errorf("Levels: %+v", Levels())
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) && (lp.GetLevel() != INFO_LEVEL || len(lp.PackageLevels()) != 0) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if lp.GetLevel() != INFO_LEVEL || len(lp.PackageLevels()) != 0 {
+		t.Errorf("应恢复为 INFO 且删除包的级别: %d %v", lp.GetLevel(), lp.PackageLevels())
+	}
+
+	if err := ChangeLevel("change_level", "", 99, 0); err == nil {
+		t.Error("不合法的级别应返回错误")
+	}
+	if err := ChangeLevel("no_such_logger", "", DEBUG_LEVEL, 0); err == nil {
+		t.Error("不存在的日志应返回错误")
+	}
+}
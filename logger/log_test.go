@@ -60,5 +60,5 @@ func TestSetPrefix(t *testing.T) {
 	if err != nil {
 		t.Error("NewLogPool err:", err)
 	}
-	log.infoLogger.Printf("[%s] %s: %s\n", "WARN", "2021-10-01 10:01:00", "This is a warning message.")
+	log.Infof("[%s] %s: %s", "WARN", "2021-10-01 10:01:00", "This is a warning message.")
 }
@@ -1,280 +1,131 @@
 package logger
 
 import (
-	"errors"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"sync"
-
-	"github.com/Chairou/toolbox/util/color"
 )
 
-var nameLogMap sync.Map
-var intLogMap sync.Map
-
+// 日志级别。为兼容已有配置，DEBUG_LEVEL、INFO_LEVEL、ERROR_LEVEL 保持原来的 0、1、2，新增的级别排在后面，
+// 数值与严重程度的顺序不一致：WARN_LEVEL(3) 比 ERROR_LEVEL(2) 轻，FATAL_LEVEL(4) 最重。
+// 配置中的 level: 3 表示 WARN，不要用 level >= ERROR_LEVEL 这样的数值比较，使用 LevelEnabled
 var (
+	TRACE_LEVEL int = -1
 	DEBUG_LEVEL int = 0
 	INFO_LEVEL  int = 1
 	ERROR_LEVEL int = 2
+	WARN_LEVEL  int = 3
+	FATAL_LEVEL int = 4
 )
 
-type LogIntFileName struct {
-	Lock     sync.RWMutex
-	orderNum int
-}
-
-var logInit LogIntFileName
+// createMu 保证同名日志只创建一次
+var createMu sync.Mutex
 
+// LogPool 第一版日志池，字段和方法保持兼容，实现在 core 中。
+// Level、PrintConsole 只用于读取，修改请使用 SetLevel、SetPrintConsole
 type LogPool struct {
 	Fd           *os.File
 	Name         string
 	FileName     string
-	Level        int
+	Level        int // 兼容保留，运行时可能被 ChangeLevel 修改，并发读取请使用 GetLevel
 	Path         string
 	PrintConsole bool
-	infoLogger   *Logger
-	debugLogger  *Logger
-	errorLogger  *Logger
-}
-
-func init() {
-	logInit.init()
+	*core
 }
 
+// NewLogPool 创建日志池并注册，同名的 LogPool 已存在时直接返回；fileName 为相对路径时相对于当前目录
 func NewLogPool(name string, fileName string) (*LogPool, error) {
-	inst, ok := nameLogMap.Load(name)
-	if ok {
-		return inst.(*LogPool), nil
-	} else {
-		inst := &LogPool{}
-		inst.Path, _ = os.Getwd()
-		inst.Name = name
-		inst.FileName = fileName
-		inst.Level = DEBUG_LEVEL
-		inst.PrintConsole = false
-
-		dir1 := filepath.Dir(fileName)
-		// 检测目录是否存在，不存在则创建（使用 MkdirAll 支持多层目录）
-		if _, err := os.Stat(dir1); os.IsNotExist(err) {
-			err := os.MkdirAll(dir1, os.ModePerm)
-			if err != nil {
-				return nil, fmt.Errorf("logger mkdir failed, err: %w, dir: %s", err, dir1)
-			}
-		}
-
-		var logFileName string
-		if dir1 == "." {
-			logFileName = inst.Path + "/" + inst.FileName
-		} else {
-			logFileName = fileName
+	createMu.Lock()
+	defer createMu.Unlock()
+	if inst, err := Get(name); err == nil {
+		if lp, ok := inst.(*LogPool); ok {
+			return lp, nil
 		}
-
-		fd, err := os.OpenFile(logFileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0664)
+		return nil, fmt.Errorf("logger %s already registered as %T", name, inst)
+	}
+	inst := &LogPool{}
+	inst.Path, _ = os.Getwd()
+	inst.Name = name
+	inst.FileName = fileName
+	inst.Level = DEBUG_LEVEL
+	inst.PrintConsole = false
+
+	dir1 := filepath.Dir(fileName)
+	// 检测目录是否存在，不存在则创建（使用 MkdirAll 支持多层目录）
+	if _, err := os.Stat(dir1); os.IsNotExist(err) {
+		err := os.MkdirAll(dir1, os.ModePerm)
 		if err != nil {
-			return nil, err
-		}
-
-		infoLog := New(fd, "INFO: ", log.Ldate|log.Ltime|log.Lshortfile)
-		debugLog := New(fd, "DEBUG: ", log.Ldate|log.Ltime|log.Lshortfile)
-		errorLog := New(fd, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile)
-
-		inst.Fd = fd
-		inst.Path, _ = os.Getwd()
-		inst.infoLogger = infoLog
-		inst.debugLogger = debugLog
-		inst.errorLogger = errorLog
-
-		lumberjackLogger := &Loggerj{
-			Filename:   logFileName,
-			MaxSize:    500, // megabytes
-			MaxBackups: 10,
-			MaxAge:     31,    //days
-			Compress:   false, // disabled by default
+			return nil, fmt.Errorf("logger mkdir failed, err: %w, dir: %s", err, dir1)
 		}
-		// 设置日志分割
-		debugLog.SetOutput(lumberjackLogger)
-		infoLog.SetOutput(lumberjackLogger)
-		errorLog.SetOutput(lumberjackLogger)
-
-		nameLogMap.Store(name, inst)
-		logInit.SaveIntLogMap(inst)
-
-		return inst, nil
 	}
-}
 
-func GetLog() *LogPool {
-	inst := GetLogNum(1)
-	if inst == nil {
-		fmt.Println("GetLogNum| get logger from logInit failed")
-		return nil
-	}
-	return inst
-}
-
-func GetLogName(name string) *LogPool {
-	inst, ok := nameLogMap.Load(name)
-	if ok {
-		return inst.(*LogPool)
+	var logFileName string
+	if dir1 == "." {
+		logFileName = inst.Path + "/" + inst.FileName
 	} else {
-		fmt.Println("GetLogName| get logger from nameLogMap failed")
-		return nil
+		logFileName = fileName
 	}
-}
 
-func GetLogNum(logNumber int) *LogPool {
-	inst, ok := intLogMap.Load(logNumber)
-	if ok {
-		return inst.(*LogPool)
-	} else {
-		fmt.Println("GetLogNum| get logger from logInit failed")
-		return nil
+	fd, err := os.OpenFile(logFileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0664)
+	if err != nil {
+		return nil, err
 	}
-}
+	inst.Fd = fd
 
-func (c *LogIntFileName) init() {
-	c.Lock.Lock()
-	c.orderNum = 1
-	c.Lock.Unlock()
-}
-
-func (c *LogIntFileName) SaveIntLogMap(inst *LogPool) {
-	c.Lock.Lock()
-	defer c.Lock.Unlock()
-	intLogMap.Store(c.orderNum, inst)
-	c.orderNum += 1
-}
-
-func (c *LogPool) SetPrintConsole(isEnable bool) {
-	c.PrintConsole = isEnable
-}
-
-func (c *LogPool) DebugfTag(tag string, format string, v ...any) {
-	if c.Level <= DEBUG_LEVEL {
-		s := tag + " " + fmt.Sprintf(format, v...)
-		_ = c.debugLogger.Output(3, s)
-		if c.PrintConsole {
-			log.Println(s)
-		}
+	// 设置日志分割
+	lumberjackLogger := &Loggerj{
+		Filename:   logFileName,
+		MaxSize:    500, // megabytes
+		MaxBackups: 10,
+		MaxAge:     31,    //days
+		Compress:   false, // disabled by default
 	}
-}
-func (c *LogPool) Debugf(format string, v ...any) {
-	if c.Level <= DEBUG_LEVEL {
-		s := fmt.Sprintf(format, v...)
-		_ = c.debugLogger.Output(3, s)
-		if c.PrintConsole {
-			log.Println(s)
-		}
-	}
-}
-
-func (c *LogPool) DebugTag(tag string, v ...any) {
-	if c.Level <= DEBUG_LEVEL {
-		s := tag + " " + fmt.Sprintln(v...)
-		_ = c.debugLogger.Output(3, s)
-		if c.PrintConsole {
-			log.Println(s)
-		}
-	}
-}
-func (c *LogPool) Debug(v ...any) {
-	if c.Level <= DEBUG_LEVEL {
-		s := fmt.Sprintln(v...)
-		_ = c.debugLogger.Output(3, s)
-		if c.PrintConsole {
-			log.Println(s)
-		}
-	}
-}
-
-func (c *LogPool) InfofTag(tag string, format string, v ...any) {
-	if c.Level <= INFO_LEVEL {
-		s := tag + " " + fmt.Sprintf(format, v...)
-		_ = c.infoLogger.Output(3, s)
-		if c.PrintConsole {
-			log.Println(s)
-		}
-	}
-}
-func (c *LogPool) Infof(format string, v ...any) {
-	if c.Level <= INFO_LEVEL {
-		s := fmt.Sprintf(format, v...)
-		_ = c.infoLogger.Output(3, s)
-		if c.PrintConsole {
-			log.Println(s)
-		}
-	}
-}
+	inst.core = newCore(name, lumberjackLogger, inst.Level, inst.PrintConsole, TextEncoder{})
 
-func (c *LogPool) InfoTag(tag string, v ...any) {
-	if c.Level <= INFO_LEVEL {
-		s := tag + " " + fmt.Sprintln(v...)
-		_ = c.infoLogger.Output(3, s)
-		if c.PrintConsole {
-			log.Println(s)
-		}
-	}
-}
-
-func (c *LogPool) Info(v ...any) {
-	if c.Level <= INFO_LEVEL {
-		s := fmt.Sprintln(v...)
-		_ = c.infoLogger.Output(3, s)
-		if c.PrintConsole {
-			log.Println(s)
-		}
+	if err = Register(name, inst); err != nil {
+		_ = fd.Close()
+		return nil, err
 	}
+	return inst, nil
 }
 
-func (c *LogPool) ErrorfTag(tag string, format string, v ...any) {
-	s := tag + " " + fmt.Sprintf(format, v...)
-	coloredStr := color.SetColor(color.Red, s)
-	_ = c.errorLogger.Output(3, s)
-	if c.PrintConsole {
-		log.Println(coloredStr)
-	}
+// GetLog 获取第一个创建的 LogPool，不存在时返回 nil，需要错误信息时使用 First
+func GetLog() *LogPool {
+	return GetLogNum(1)
 }
 
-func (c *LogPool) Errorf(format string, v ...any) {
-	s := fmt.Sprintf(format, v...)
-	coloredStr := color.SetColor(color.Red, s)
-	_ = c.errorLogger.Output(3, s)
-	if c.PrintConsole {
-		log.Println(coloredStr)
-	}
+// GetLogName 按名字获取 LogPool，不存在时返回 nil，需要错误信息时使用 Get
+func GetLogName(name string) *LogPool {
+	inst, _ := lookup[*LogPool](name)
+	return inst
 }
 
-func (c *LogPool) ErrorTag(tag string, v ...any) {
-	s := tag + " " + fmt.Sprintln(v...)
-	coloredStr := color.SetColor(color.Red, s)
-	_ = c.errorLogger.Output(3, s)
-	if c.PrintConsole {
-		log.Println(coloredStr)
-	}
+// GetLogNum 按创建顺序获取第 logNumber 个 LogPool，从 1 开始，不存在时返回 nil
+func GetLogNum(logNumber int) *LogPool {
+	inst, _ := lookupNum[*LogPool](logNumber)
+	return inst
 }
 
-func (c *LogPool) Error(v ...any) {
-	s := fmt.Sprintln(v...)
-	coloredStr := color.SetColor(color.Red, s)
-	_ = c.errorLogger.Output(3, s)
-	if c.PrintConsole {
-		log.Println(coloredStr)
-	}
+func (c *LogPool) SetPrintConsole(isEnable bool) {
+	c.PrintConsole = isEnable
+	c.console.Store(isEnable)
 }
 
+// SetLevel 设置日志级别，可选 TRACE_LEVEL、DEBUG_LEVEL、INFO_LEVEL、WARN_LEVEL、ERROR_LEVEL、FATAL_LEVEL
 func (c *LogPool) SetLevel(level int) error {
-	if level >= DEBUG_LEVEL && level <= ERROR_LEVEL {
-		c.Level = level
-		nameLogMap.Store(c.Name, c)
-		return nil
+	if err := c.setLevel(level); err != nil {
+		return err
 	}
-	return errors.New("level must be DEBUG_LEVEL, INFO_LEVEL, ERROR_LEVEL")
+	c.Level = level
+	return nil
 }
 
 // Close 关闭日志池，释放文件描述符
 func (c *LogPool) Close() error {
+	if c.core != nil {
+		_ = c.core.Close()
+	}
 	if c.Fd != nil {
 		return c.Fd.Close()
 	}
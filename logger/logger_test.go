@@ -36,8 +36,8 @@ func TestNewLogPool_Basic(t *testing.T) {
 	if lp.Fd == nil {
 		t.Error("期望 Fd 不为 nil")
 	}
-	if lp.infoLogger == nil || lp.debugLogger == nil || lp.errorLogger == nil {
-		t.Error("期望所有 logger 实例不为 nil")
+	if lp.core == nil {
+		t.Error("期望 core 不为 nil")
 	}
 }
 
@@ -255,15 +255,18 @@ func TestSetLevel_Invalid(t *testing.T) {
 	}
 	defer lp.Close()
 
-	// 测试无效级别
-	err = lp.SetLevel(-1)
+	// 测试无效级别，-1 为 TRACE_LEVEL，3、4 为 WARN_LEVEL、FATAL_LEVEL
+	err = lp.SetLevel(-2)
 	if err == nil {
-		t.Error("SetLevel(-1) 应返回错误")
+		t.Error("SetLevel(-2) 应返回错误")
 	}
 
-	err = lp.SetLevel(3)
+	err = lp.SetLevel(5)
 	if err == nil {
-		t.Error("SetLevel(3) 应返回错误")
+		t.Error("SetLevel(5) 应返回错误")
+	}
+	if lp.Level != DEBUG_LEVEL {
+		t.Errorf("无效级别不应修改 Level, 实际=%d", lp.Level)
 	}
 }
 
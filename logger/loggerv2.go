@@ -3,51 +3,73 @@ package logger
 import (
 	"errors"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
-	"sync"
+	"time"
 
-	"github.com/Chairou/toolbox/util/color"
+	"github.com/Chairou/toolbox/conf"
 )
 
-var nameLogMap2 sync.Map
-var intLogMap2 sync.Map
-
-// LogIntFileNameV2 日志文件编号管理器，用于为日志池分配递增编号
-type LogIntFileNameV2 struct {
-	Lock     sync.RWMutex
-	orderNum int
-}
-
-var logInitV2 LogIntFileNameV2
-
-// LogPoolV2 日志池，封装了日志文件描述符和多级别日志记录器
+// LogPoolV2 第二版日志池，通过 LogOpt 配置，字段和方法保持兼容，实现在 core 中。
+// Level、PrintConsole 只用于读取，修改请使用 SetLevel、SetPrintConsole
 type LogPoolV2 struct {
 	Fd           *os.File
 	Name         string
 	FileName     string
-	Level        int
+	Level        int // 兼容保留，运行时可能被 ChangeLevel 修改，并发读取请使用 GetLevel
 	PrintConsole int
-	infoLogger   *Logger
-	debugLogger  *Logger
-	errorLogger  *Logger
+	*core
 }
 
 // LogOpt 日志配置选项
 type LogOpt struct {
 	FileName     string `config:"fileName"`     // 日志文件名或路径
-	Level        int    `config:"level"`        // 日志级别，可选 DEBUG_LEVEL、INFO_LEVEL、ERROR_LEVEL
+	Level        int    `config:"level"`        // 日志级别，可选 TRACE_LEVEL、DEBUG_LEVEL、INFO_LEVEL、WARN_LEVEL、ERROR_LEVEL、FATAL_LEVEL
 	MaxSizeMB    int    `config:"maxSizeMB"`    // 单个日志文件最大大小（MB）
 	MaxBackups   int    `config:"maxBackups"`   // 最大保留的旧日志文件数量
 	MaxAgeDay    int    `config:"maxAgeDay"`    // 旧日志文件最大保留天数
 	Compress     int    `config:"compress"`     // 是否压缩旧日志文件，默认不压缩
 	PrintConsole int    `config:"printConsole"` // 是否同时输出到控制台
-}
-
-func init() {
-	logInitV2.init()
+	Format       string `config:"format"`       // 日志格式 text、logfmt、json，默认 text
+	Profile      string `config:"profile"`      // 运行环境，不为空时没有设置的 Level、PrintConsole 使用环境默认值，见 conf.ProfileDefaults
+	// LevelSet Level 是否为显式设置的值。Profile 不为空时零值的 Level 视为没有设置，需要固定为 DEBUG_LEVEL 时设置为 true
+	LevelSet bool `config:"levelSet"`
+	// PrintConsoleSet PrintConsole 是否为显式设置的值。Profile 不为空时零值的 PrintConsole 视为没有设置，需要固定不输出时设置为 true
+	PrintConsoleSet bool `config:"printConsoleSet"`
+	// Rotate 切割方式 size、hourly、daily，默认 size。按时间切割时如果设置了 MaxSizeMB，超过大小也会切割
+	Rotate string `config:"rotate"`
+	// RotateTimezone 按时间切割和备份文件名使用的时区，例如 Asia/Shanghai，按时间切割时默认本地时区
+	RotateTimezone string `config:"rotateTimezone"`
+	// BackupPattern 备份文件名，{name} 为不含扩展名的文件名，{time} 为时间，{ext} 为扩展名，默认 {name}-{time}{ext}
+	BackupPattern string `config:"backupPattern"`
+	// BackupTimeFormat 备份文件名中时间的格式，默认 daily 为 2006-01-02，hourly 为 2006-01-02T15，size 为 2006-01-02T15-04-05.000
+	BackupTimeFormat string `config:"backupTimeFormat"`
+	// Async 是否异步写入，1 开启。开启后日志先写入缓冲区由后台批量写出，Sync、Close 等待缓冲区写完
+	Async int `config:"async"`
+	// AsyncBufferSize 异步缓冲区可以容纳的日志条数，默认 4096
+	AsyncBufferSize int `config:"asyncBufferSize"`
+	// AsyncBatchSize 缓冲达到该条数时立即写出，默认 128
+	AsyncBatchSize int `config:"asyncBatchSize"`
+	// AsyncFlushInterval 缓冲的最长等待时间，默认 200ms
+	AsyncFlushInterval time.Duration `config:"asyncFlushInterval"`
+	// AsyncOverflow 缓冲区满时的处理方式 block、dropOldest、drop，默认 block，丢弃的条数见 AsyncStats
+	AsyncOverflow string `config:"asyncOverflow"`
+	// Sinks 除日志文件外的其它输出，例如只记录 ERROR 的文件、stderr、syslog，见 SinkOpt
+	Sinks []SinkOpt `config:"sinks"`
+	// Redact 是否脱敏，1 开启内置的 token、邮箱、手机号、身份证号和密码等字段的规则，见 DefaultRedactRules
+	Redact int `config:"redact"`
+	// RedactMask 内置规则统一使用的脱敏方式 full、partial、hash，为空时使用各规则的默认方式
+	RedactMask string `config:"redactMask"`
+	// RedactHashKey hash 脱敏方式使用的 HMAC 密钥，RedactMask 为 hash 时必填，应与其它密钥一样从加密配置或环境变量读取
+	RedactHashKey string `config:"redactHashKey"`
+	// RedactKeys 额外需要脱敏的字段名，例如 bankCard
+	RedactKeys []string `config:"redactKeys"`
+	// RedactPatterns 额外需要脱敏的正则，有分组时只替换第一个分组
+	RedactPatterns []string `config:"redactPatterns"`
+	// Sampling 日志采样，同一条消息每个周期先输出 First 条，之后每 Thereafter 条输出一条，可以按级别和日志名配置，见 SampleOpt
+	Sampling []SampleOpt `config:"sampling"`
 }
 
 // NewLogOpt 根据配置选项创建日志池实例，如果同名实例已存在则直接返回
@@ -55,8 +77,22 @@ func NewLogOpt(name string, opt *LogOpt) (*LogPoolV2, error) {
 	if opt.FileName == "" {
 		return nil, errors.New("logger file name or path is empty")
 	}
-	if inst, ok := nameLogMap2.Load(name); ok {
-		return inst.(*LogPoolV2), nil
+	createMu.Lock()
+	defer createMu.Unlock()
+	if inst, err := Get(name); err == nil {
+		if lp, ok := inst.(*LogPoolV2); ok {
+			return lp, nil
+		}
+		return nil, fmt.Errorf("logger %s already registered as %T", name, inst)
+	}
+	encoder, err := NewEncoder(opt.Format)
+	if err != nil {
+		return nil, err
+	}
+	// 先检查不需要打开文件的配置，出错时不需要清理
+	redactor, err := newRedactor(opt)
+	if err != nil {
+		return nil, err
 	}
 	pathFileName, err := safePath("./", opt.FileName)
 	if err != nil {
@@ -64,14 +100,28 @@ func NewLogOpt(name string, opt *LogOpt) (*LogPoolV2, error) {
 	}
 
 	level := opt.Level
-	if level < DEBUG_LEVEL || level > ERROR_LEVEL {
+	if !ValidLevel(level) {
 		level = DEBUG_LEVEL
 	}
+	printConsole := opt.PrintConsole
+	if opt.Profile != "" {
+		profile, err := conf.ParseProfile(opt.Profile)
+		if err != nil {
+			return nil, err
+		}
+		// 显式设置的值优先于环境默认值
+		if level == DEBUG_LEVEL && !opt.LevelSet {
+			level = profileLevel(profile)
+		}
+		if printConsole == 0 && !opt.PrintConsoleSet && profile.Defaults().LogConsole {
+			printConsole = 1
+		}
+	}
 	inst := &LogPoolV2{
 		Name:         name,
 		FileName:     pathFileName,
 		Level:        level,
-		PrintConsole: opt.PrintConsole,
+		PrintConsole: printConsole,
 	}
 
 	dir2 := filepath.Dir(pathFileName)
@@ -87,217 +137,118 @@ func NewLogOpt(name string, opt *LogOpt) (*LogPoolV2, error) {
 	if err != nil {
 		return nil, err
 	}
-	infoLog := New(fd, "INFO: ", log.Ldate|log.Ltime|log.Lshortfile)
-	debugLog := New(fd, "DEBUG: ", log.Ldate|log.Ltime|log.Lshortfile)
-	errorLog := New(fd, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile)
-
 	inst.Fd = fd
-	inst.infoLogger = infoLog
-	inst.debugLogger = debugLog
-	inst.errorLogger = errorLog
 
-	lumberjackLogger := &Loggerj{
-		Filename:   logFileName,
+	lumberjackLogger, err := newLoggerj(logFileName, opt)
+	if err != nil {
+		_ = fd.Close()
+		return nil, err
+	}
+
+	var out io.Writer = lumberjackLogger
+	if opt.Async == 1 {
+		out, err = NewAsyncWriter(lumberjackLogger, AsyncOpt{
+			BufferSize:    opt.AsyncBufferSize,
+			BatchSize:     opt.AsyncBatchSize,
+			FlushInterval: opt.AsyncFlushInterval,
+			Overflow:      opt.AsyncOverflow,
+		})
+		if err != nil {
+			_ = lumberjackLogger.Close()
+			_ = fd.Close()
+			return nil, err
+		}
+	}
+
+	// 设置日志分割
+	inst.core = newCore(name, out, level, printConsole == 1, encoder)
+	inst.redactor = redactor
+	if err = inst.SetSampling(opt.Sampling...); err != nil {
+		_ = inst.core.Close()
+		_ = fd.Close()
+		return nil, err
+	}
+	for _, so := range opt.Sinks {
+		sink, err := newSink(so, opt)
+		if err != nil {
+			_ = inst.core.Close()
+			_ = fd.Close()
+			return nil, err
+		}
+		inst.AddSink(sink)
+	}
+
+	if err = Register(name, inst); err != nil {
+		_ = inst.core.Close()
+		_ = fd.Close()
+		return nil, err
+	}
+	return inst, nil
+}
+
+// newLoggerj 按 LogOpt 的切割配置创建 Loggerj，主日志文件和 file 类型的 Sink 共用
+func newLoggerj(fileName string, opt *LogOpt) (*Loggerj, error) {
+	l := &Loggerj{
+		Filename:   fileName,
 		MaxSize:    500, // megabytes
 		MaxBackups: 10,
 		MaxAge:     31,    //days
 		Compress:   false, // disabled by default
 	}
 	if opt.MaxSizeMB != 0 {
-		lumberjackLogger.MaxSize = opt.MaxSizeMB
+		l.MaxSize = opt.MaxSizeMB
 	}
 	if opt.MaxBackups != 0 {
-		lumberjackLogger.MaxBackups = opt.MaxBackups
+		l.MaxBackups = opt.MaxBackups
 	}
 	if opt.MaxAgeDay != 0 {
-		lumberjackLogger.MaxAge = opt.MaxAgeDay
+		l.MaxAge = opt.MaxAgeDay
 	}
 	if opt.Compress == 1 {
-		lumberjackLogger.Compress = true
+		l.Compress = true
 	}
-
-	// 设置日志分割
-	debugLog.SetOutput(lumberjackLogger)
-	infoLog.SetOutput(lumberjackLogger)
-	errorLog.SetOutput(lumberjackLogger)
-
-	nameLogMap2.Store(name, inst)
-	logInitV2.SaveIntLogMap(inst)
-
-	return inst, nil
+	if err := applyRotate(l, opt); err != nil {
+		return nil, err
+	}
+	return l, nil
 }
 
-// GetLogV2 获取第一个创建的 v2 日志池实例
+// GetLogV2 获取第一个创建的 LogPoolV2，不存在时返回 nil，需要错误信息时使用 First
 func GetLogV2() *LogPoolV2 {
-	inst := GetLogNumV2(1)
-	if inst == nil {
-		fmt.Println("GetLogNum| get logger from logInit failed")
-		return nil
-	}
-	return inst
+	return GetLogNumV2(1)
 }
 
-// GetLogNameV2 根据名称获取 v2 日志池实例
+// GetLogNameV2 按名字获取 LogPoolV2，不存在时返回 nil，需要错误信息时使用 Get
 func GetLogNameV2(name string) *LogPoolV2 {
-	inst, ok := nameLogMap2.Load(name)
-	if ok {
-		return inst.(*LogPoolV2)
-	}
-	fmt.Println("GetLogName| get logger from nameLogMap failed")
-	return nil
+	inst, _ := lookup[*LogPoolV2](name)
+	return inst
 }
 
-// GetLogNumV2 根据编号获取 v2 日志池实例
+// GetLogNumV2 按创建顺序获取第 logNumber 个 LogPoolV2，从 1 开始，不存在时返回 nil
 func GetLogNumV2(logNumber int) *LogPoolV2 {
-	inst, ok := intLogMap2.Load(logNumber)
-	if ok {
-		return inst.(*LogPoolV2)
-	}
-	fmt.Println("GetLogNum| get logger from logInit failed")
-	return nil
-}
-
-func (c *LogIntFileNameV2) init() {
-	c.Lock.Lock()
-	c.orderNum = 1
-	c.Lock.Unlock()
-}
-
-func (c *LogIntFileNameV2) SaveIntLogMap(inst *LogPoolV2) {
-	c.Lock.Lock()
-	defer c.Lock.Unlock()
-	intLogMap2.Store(c.orderNum, inst)
-	c.orderNum++
+	inst, _ := lookupNum[*LogPoolV2](logNumber)
+	return inst
 }
 
 func (c *LogPoolV2) SetPrintConsole(isEnable int) {
 	c.PrintConsole = isEnable
+	c.console.Store(isEnable == 1)
 }
 
-func (c *LogPoolV2) DebugfTag(tag string, format string, v ...any) {
-	if c.Level <= DEBUG_LEVEL {
-		s := tag + " " + fmt.Sprintf(format, v...)
-		_ = c.debugLogger.Output(3, s)
-		if c.PrintConsole == 1 {
-			log.Println(s)
-		}
-	}
-}
-func (c *LogPoolV2) Debugf(format string, v ...any) {
-	if c.Level <= DEBUG_LEVEL {
-		s := fmt.Sprintf(format, v...)
-		_ = c.debugLogger.Output(3, s)
-		if c.PrintConsole == 1 {
-			log.Println(s)
-		}
-	}
-}
-
-func (c *LogPoolV2) DebugTag(tag string, v ...any) {
-	if c.Level <= DEBUG_LEVEL {
-		s := tag + " " + fmt.Sprintln(v...)
-		_ = c.debugLogger.Output(3, s)
-		if c.PrintConsole == 1 {
-			log.Println(s)
-		}
-	}
-}
-func (c *LogPoolV2) Debug(v ...any) {
-	if c.Level <= DEBUG_LEVEL {
-		s := fmt.Sprintln(v...)
-		_ = c.debugLogger.Output(3, s)
-		if c.PrintConsole == 1 {
-			log.Println(s)
-		}
-	}
-}
-
-func (c *LogPoolV2) InfofTag(tag string, format string, v ...any) {
-	if c.Level <= INFO_LEVEL {
-		s := tag + " " + fmt.Sprintf(format, v...)
-		_ = c.infoLogger.Output(3, s)
-		if c.PrintConsole == 1 {
-			log.Println(s)
-		}
-	}
-}
-func (c *LogPoolV2) Infof(format string, v ...any) {
-	if c.Level <= INFO_LEVEL {
-		s := fmt.Sprintf(format, v...)
-		_ = c.infoLogger.Output(3, s)
-		if c.PrintConsole == 1 {
-			log.Println(s)
-		}
-	}
-}
-
-func (c *LogPoolV2) InfoTag(tag string, v ...any) {
-	if c.Level <= INFO_LEVEL {
-		s := tag + " " + fmt.Sprintln(v...)
-		_ = c.infoLogger.Output(3, s)
-		if c.PrintConsole == 1 {
-			log.Println(s)
-		}
-	}
-}
-
-func (c *LogPoolV2) Info(v ...any) {
-	if c.Level <= INFO_LEVEL {
-		s := fmt.Sprintln(v...)
-		_ = c.infoLogger.Output(3, s)
-		if c.PrintConsole == 1 {
-			log.Println(s)
-		}
-	}
-}
-
-func (c *LogPoolV2) ErrorfTag(tag string, format string, v ...any) {
-	s := tag + " " + fmt.Sprintf(format, v...)
-	coloredStr := color.SetColor(color.Red, s)
-	_ = c.errorLogger.Output(3, s)
-	if c.PrintConsole == 1 {
-		log.Println(coloredStr)
-	}
-}
-
-func (c *LogPoolV2) Errorf(format string, v ...any) {
-	s := fmt.Sprintf(format, v...)
-	coloredStr := color.SetColor(color.Red, s)
-	_ = c.errorLogger.Output(3, s)
-	if c.PrintConsole == 1 {
-		log.Println(coloredStr)
-	}
-}
-
-func (c *LogPoolV2) ErrorTag(tag string, v ...any) {
-	s := tag + " " + fmt.Sprintln(v...)
-	coloredStr := color.SetColor(color.Red, s)
-	_ = c.errorLogger.Output(3, s)
-	if c.PrintConsole == 1 {
-		log.Println(coloredStr)
-	}
-}
-
-func (c *LogPoolV2) Error(v ...any) {
-	s := fmt.Sprintln(v...)
-	coloredStr := color.SetColor(color.Red, s)
-	_ = c.errorLogger.Output(3, s)
-	if c.PrintConsole == 1 {
-		log.Println(coloredStr)
-	}
-}
-
+// SetLevel 设置日志级别，可选 TRACE_LEVEL、DEBUG_LEVEL、INFO_LEVEL、WARN_LEVEL、ERROR_LEVEL、FATAL_LEVEL
 func (c *LogPoolV2) SetLevel(level int) error {
-	if level >= DEBUG_LEVEL && level <= ERROR_LEVEL {
-		c.Level = level
-		nameLogMap2.Store(c.Name, c)
-		return nil
+	if err := c.setLevel(level); err != nil {
+		return err
 	}
-	return errors.New("level must be DEBUG_LEVEL, INFO_LEVEL, ERROR_LEVEL")
+	c.Level = level
+	return nil
 }
 
 // Close 关闭日志池，释放文件描述符
 func (c *LogPoolV2) Close() error {
+	if c.core != nil {
+		_ = c.core.Close()
+	}
 	if c.Fd != nil {
 		return c.Fd.Close()
 	}
@@ -3,6 +3,7 @@ package logger
 import (
 	"testing"
 
+	"github.com/Chairou/toolbox/conf"
 	"github.com/Chairou/toolbox/util/conv"
 	"github.com/jinzhu/copier"
 )
@@ -98,3 +99,50 @@ func TestInitLog2(t *testing.T) {
 	t.Log("InitLog| logger initialized successfully")
 	logInst.Info("test info message")
 }
+
+func TestNewLogOpt_Profile(t *testing.T) {
+	opt := &LogOpt{FileName: "log/profile_release.log", Profile: "release"}
+	lp, err := NewLogOpt("profile_release", opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lp.Close()
+	if lp.Level != INFO_LEVEL || lp.PrintConsole != 0 {
+		t.Errorf("release 应为 INFO 级别且不输出到控制台: %d %d", lp.Level, lp.PrintConsole)
+	}
+	lp.SetProfile(conf.ProfileDev)
+	if lp.Level != DEBUG_LEVEL || lp.PrintConsole != 1 {
+		t.Errorf("dev 应为 DEBUG 级别且输出到控制台: %d %d", lp.Level, lp.PrintConsole)
+	}
+
+	opt = &LogOpt{FileName: "log/profile_err.log", Level: ERROR_LEVEL, Profile: "release"}
+	lp, err = NewLogOpt("profile_err", opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lp.Close()
+	if lp.Level != ERROR_LEVEL {
+		t.Errorf("显式设置的级别不应被覆盖: %d", lp.Level)
+	}
+	opt = &LogOpt{FileName: "log/profile_debug.log", Level: DEBUG_LEVEL, LevelSet: true, Profile: "release"}
+	lp, err = NewLogOpt("profile_debug", opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lp.Close()
+	if lp.Level != DEBUG_LEVEL {
+		t.Errorf("LevelSet 时零值的级别不应被覆盖: %d", lp.Level)
+	}
+	opt = &LogOpt{FileName: "log/profile_dev.log", PrintConsoleSet: true, Profile: "dev"}
+	lp, err = NewLogOpt("profile_dev", opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lp.Close()
+	if lp.PrintConsole != 0 {
+		t.Errorf("显式关闭的控制台输出不应被打开: %d", lp.PrintConsole)
+	}
+	if _, err = NewLogOpt("profile_bad", &LogOpt{FileName: "log/profile_bad.log", Profile: "prod"}); err == nil {
+		t.Errorf("未注册的环境应返回错误")
+	}
+}
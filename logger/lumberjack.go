@@ -30,6 +30,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -76,6 +77,15 @@ var _ io.WriteCloser = (*Loggerj)(nil)
 // time, which may differ from the last time that file was written to.
 //
 // If MaxBackups and MaxAge are both 0, no old log files will be deleted.
+//
+// # Rotation Policies
+//
+// By default files are rotated by size only. Set Policy to rotate by time
+// (hourly, or daily at midnight in Location) or by a combination of size and
+// time, and BackupPattern and BackupTimeFormat to control backup names, e.g.
+// `app-2026-10-18.log`.  If a backup with the same name already exists, a
+// counter is added: `app-2026-10-18.1.log`.  Cleanup only considers files that
+// match the pattern.
 type Loggerj struct {
 	// Filename is the file to write logs to.  Backup log files will be retained
 	// in the same directory.  It uses <processname>-lumberjack.log in
@@ -107,9 +117,30 @@ type Loggerj struct {
 	// using gzip. The default is not to perform compression.
 	Compress bool `json:"compress" yaml:"compress"`
 
-	size int64
-	file *os.File
-	mu   sync.Mutex
+	// Policy decides when the log file is rotated. The default is to rotate
+	// when MaxSize is exceeded. See SizePolicy, TimePolicy and CombinedPolicy.
+	Policy RotatePolicy `json:"-" yaml:"-"`
+
+	// Location is the time zone used by time based policies and backup
+	// names. It overrides LocalTime when set.
+	Location *time.Location `json:"-" yaml:"-"`
+
+	// BackupPattern is the name of backup files, where {name} is the filename
+	// without the extension, {time} is the backup time and {ext} is the
+	// extension. It defaults to `{name}-{time}{ext}`.
+	BackupPattern string `json:"backuppattern" yaml:"backuppattern"`
+
+	// BackupTimeFormat is the time.Time format of {time} in BackupPattern.
+	// It defaults to `2006-01-02T15-04-05.000`.
+	BackupTimeFormat string `json:"backuptimeformat" yaml:"backuptimeformat"`
+
+	size   int64
+	opened time.Time
+	file   *os.File
+	mu     sync.Mutex
+
+	// clock replaces currentTime in tests.
+	clock func() time.Time
 
 	millCh    chan bool
 	startMill sync.Once
@@ -137,7 +168,7 @@ func (l *Loggerj) Write(p []byte) (n int, err error) {
 	defer l.mu.Unlock()
 
 	writeLen := int64(len(p))
-	if writeLen > l.max() {
+	if l.Policy == nil && writeLen > l.max() {
 		return 0, fmt.Errorf(
 			"write length %d exceeds maximum file size %d", writeLen, l.max(),
 		)
@@ -149,7 +180,7 @@ func (l *Loggerj) Write(p []byte) (n int, err error) {
 		}
 	}
 
-	if l.size+writeLen > l.max() {
+	if l.shouldRotate(writeLen) {
 		if err := l.rotate(); err != nil {
 			return 0, err
 		}
@@ -161,6 +192,15 @@ func (l *Loggerj) Write(p []byte) (n int, err error) {
 	return n, err
 }
 
+// shouldRotate reports whether the current file must be rotated before
+// writing writeLen bytes.
+func (l *Loggerj) shouldRotate(writeLen int64) bool {
+	if l.Policy == nil {
+		return l.size+writeLen > l.max()
+	}
+	return l.Policy.ShouldRotate(l.size, writeLen, l.opened, l.now())
+}
+
 // Close implements io.Closer, and closes the current logfile.
 func (l *Loggerj) Close() error {
 	l.mu.Lock()
@@ -218,7 +258,11 @@ func (l *Loggerj) openNew() error {
 		// Copy the mode off the old logfile.
 		mode = info.Mode()
 		// move the existing file
-		newname := backupName(name, l.LocalTime)
+		opened := l.opened
+		if opened.IsZero() {
+			opened = info.ModTime()
+		}
+		newname := l.backupName(name, opened)
 		if err := os.Rename(name, newname); err != nil {
 			return fmt.Errorf("can't rename log file: %s", err)
 		}
@@ -238,24 +282,65 @@ func (l *Loggerj) openNew() error {
 	}
 	l.file = f
 	l.size = 0
+	l.opened = l.now()
 	return nil
 }
 
-// backupName creates a new filename from the given name, inserting a timestamp
-// between the filename and the extension, using the local time if requested
-// (otherwise UTC).
-func backupName(name string, local bool) string {
+// backupName creates a new filename from the given name according to
+// BackupPattern, using the backup time of the policy for a file opened at
+// opened.  If the name is taken (e.g. a daily backup rotated twice by size), a
+// counter is inserted after the timestamp.
+func (l *Loggerj) backupName(name string, opened time.Time) string {
 	dir := filepath.Dir(name)
-	filename := filepath.Base(name)
-	ext := filepath.Ext(filename)
-	prefix := filename[:len(filename)-len(ext)]
+	now := l.now()
+	t := now
+	if l.Policy != nil {
+		t = l.Policy.BackupTime(opened.In(now.Location()), now)
+	}
+	prefix, suffix := l.prefixAndExt()
+	base := prefix + t.Format(l.timeFormat())
+	newname := filepath.Join(dir, base+suffix)
+	for i := 1; l.backupExists(newname); i++ {
+		newname = filepath.Join(dir, fmt.Sprintf("%s.%d%s", base, i, suffix))
+	}
+	return newname
+}
+
+// backupExists reports whether the backup or its compressed version exists.
+func (l *Loggerj) backupExists(name string) bool {
+	if _, err := os_Stat(name); err == nil {
+		return true
+	}
+	_, err := os_Stat(name + compressSuffix)
+	return err == nil
+}
+
+// now returns the current time in the time zone of backup names.
+func (l *Loggerj) now() time.Time {
 	t := currentTime()
-	if !local {
-		t = t.UTC()
+	if l.clock != nil {
+		t = l.clock()
+	}
+	switch {
+	case l.Location != nil:
+		return t.In(l.Location)
+	case l.LocalTime:
+		return t.Local()
 	}
+	return t.UTC()
+}
 
-	timestamp := t.Format(backupTimeFormat)
-	return filepath.Join(dir, fmt.Sprintf("%s-%s%s", prefix, timestamp, ext))
+// location returns the time zone of backup names.
+func (l *Loggerj) location() *time.Location {
+	return l.now().Location()
+}
+
+// timeFormat returns the time.Time format of {time} in backup names.
+func (l *Loggerj) timeFormat() string {
+	if l.BackupTimeFormat != "" {
+		return l.BackupTimeFormat
+	}
+	return backupTimeFormat
 }
 
 // openExistingOrNew opens the logfile if it exists and if the current write
@@ -273,7 +358,11 @@ func (l *Loggerj) openExistingOrNew(writeLen int) error {
 		return fmt.Errorf("error getting log file info: %s", err)
 	}
 
-	if info.Size()+int64(writeLen) >= l.max() {
+	if l.Policy == nil && info.Size()+int64(writeLen) >= l.max() {
+		return l.rotate()
+	}
+	if l.Policy != nil && l.Policy.ShouldRotate(info.Size(), int64(writeLen), info.ModTime().In(l.location()), l.now()) {
+		l.opened = info.ModTime()
 		return l.rotate()
 	}
 
@@ -285,6 +374,7 @@ func (l *Loggerj) openExistingOrNew(writeLen int) error {
 	}
 	l.file = file
 	l.size = info.Size()
+	l.opened = info.ModTime()
 	return nil
 }
 
@@ -335,7 +425,7 @@ func (l *Loggerj) millRunOnce() error {
 	}
 	if l.MaxAge > 0 {
 		diff := time.Duration(int64(24*time.Hour) * int64(l.MaxAge))
-		cutoff := currentTime().Add(-1 * diff)
+		cutoff := l.now().Add(-1 * diff)
 
 		var remaining []logInfo
 		for _, f := range files {
@@ -410,12 +500,12 @@ func (l *Loggerj) oldLogFiles() ([]logInfo, error) {
 		if f.IsDir() {
 			continue
 		}
-		if t, err := l.timeFromName(f.Name(), prefix, ext); err == nil {
-			logFiles = append(logFiles, logInfo{t, f})
+		if t, seq, err := l.parseBackupName(f.Name(), prefix, ext); err == nil {
+			logFiles = append(logFiles, logInfo{t, seq, f})
 			continue
 		}
-		if t, err := l.timeFromName(f.Name(), prefix, ext+compressSuffix); err == nil {
-			logFiles = append(logFiles, logInfo{t, f})
+		if t, seq, err := l.parseBackupName(f.Name(), prefix, ext+compressSuffix); err == nil {
+			logFiles = append(logFiles, logInfo{t, seq, f})
 			continue
 		}
 		// error parsing means that the suffix at the end was not generated
@@ -427,18 +517,75 @@ func (l *Loggerj) oldLogFiles() ([]logInfo, error) {
 	return logFiles, nil
 }
 
+// BackupFile is a rotated backup of a Loggerj file, see Backups.
+type BackupFile struct {
+	// Path is the path of the backup, in the same directory as Filename.
+	Path string
+	// Time is the rotation time encoded in the backup name.
+	Time time.Time
+	// Seq is the counter added when several backups share Time, 0 if none.
+	Seq     int
+	Size    int64
+	ModTime time.Time
+	// Compressed reports whether the backup is gzip compressed.
+	Compressed bool
+}
+
+// Backups returns the backup files of the Logger from oldest to newest,
+// without the current log file. BackupPattern, BackupTimeFormat and Location
+// must match the ones used when the backups were written.
+func (l *Loggerj) Backups() ([]BackupFile, error) {
+	files, err := l.oldLogFiles()
+	if err != nil {
+		return nil, err
+	}
+	backups := make([]BackupFile, len(files))
+	for i, f := range files {
+		// oldLogFiles is sorted from newest to oldest
+		backups[len(files)-1-i] = BackupFile{
+			Path:       filepath.Join(l.dir(), f.Name()),
+			Time:       f.timestamp,
+			Seq:        f.seq,
+			Size:       f.Size(),
+			ModTime:    f.ModTime(),
+			Compressed: strings.HasSuffix(f.Name(), compressSuffix),
+		}
+	}
+	return backups, nil
+}
+
 // timeFromName extracts the formatted time from the filename by stripping off
 // the filename's prefix and extension. This prevents someone's filename from
 // confusing time.parse.
 func (l *Loggerj) timeFromName(filename, prefix, ext string) (time.Time, error) {
-	if !strings.HasPrefix(filename, prefix) {
-		return time.Time{}, errors.New("mismatched prefix")
+	t, _, err := l.parseBackupName(filename, prefix, ext)
+	return t, err
+}
+
+// parseBackupName is timeFromName that also returns the counter added by
+// backupName, 0 if there is none.
+func (l *Loggerj) parseBackupName(filename, prefix, ext string) (time.Time, int, error) {
+	if len(filename) < len(prefix)+len(ext) || !strings.HasPrefix(filename, prefix) {
+		return time.Time{}, 0, errors.New("mismatched prefix")
 	}
 	if !strings.HasSuffix(filename, ext) {
-		return time.Time{}, errors.New("mismatched extension")
+		return time.Time{}, 0, errors.New("mismatched extension")
 	}
 	ts := filename[len(prefix) : len(filename)-len(ext)]
-	return time.Parse(backupTimeFormat, ts)
+	layout, loc := l.timeFormat(), l.location()
+	if t, err := time.ParseInLocation(layout, ts, loc); err == nil {
+		return t, 0, nil
+	}
+	i := strings.LastIndexByte(ts, '.')
+	if i < 0 {
+		return time.Time{}, 0, errors.New("mismatched timestamp")
+	}
+	seq, err := strconv.Atoi(ts[i+1:])
+	if err != nil || seq <= 0 {
+		return time.Time{}, 0, errors.New("mismatched counter")
+	}
+	t, err := time.ParseInLocation(layout, ts[:i], loc)
+	return t, seq, err
 }
 
 // max returns the maximum size in bytes of log files before rolling.
@@ -454,13 +601,18 @@ func (l *Loggerj) dir() string {
 	return filepath.Dir(l.filename())
 }
 
-// prefixAndExt returns the filename part and extension part from the Logger's
-// filename.
+// prefixAndExt returns the parts of backup names before and after the
+// timestamp, from BackupPattern and the Logger's filename.
 func (l *Loggerj) prefixAndExt() (prefix, ext string) {
 	filename := filepath.Base(l.filename())
-	ext = filepath.Ext(filename)
-	prefix = filename[:len(filename)-len(ext)] + "-"
-	return prefix, ext
+	fileExt := filepath.Ext(filename)
+	pattern := l.BackupPattern
+	if checkBackupPattern(pattern) != nil || pattern == "" {
+		pattern = defaultBackupPattern
+	}
+	prefix, ext, _ = strings.Cut(pattern, "{time}")
+	r := strings.NewReplacer("{name}", filename[:len(filename)-len(fileExt)], "{ext}", fileExt)
+	return r.Replace(prefix), r.Replace(ext)
 }
 
 // compressLogFile compresses the given log file, removing the
@@ -522,6 +674,7 @@ func compressLogFile(src, dst string) (err error) {
 // timestamp.
 type logInfo struct {
 	timestamp time.Time
+	seq       int
 	os.FileInfo
 }
 
@@ -529,6 +682,9 @@ type logInfo struct {
 type byFormatTime []logInfo
 
 func (b byFormatTime) Less(i, j int) bool {
+	if b[i].timestamp.Equal(b[j].timestamp) {
+		return b[i].seq > b[j].seq
+	}
 	return b[i].timestamp.After(b[j].timestamp)
 }
 
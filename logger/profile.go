@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Chairou/toolbox/conf"
+)
+
+// ParseLevel 解析日志级别名 trace、debug、info、warn、error、fatal，不区分大小写。
+// 返回的数值不能直接比较严重程度，例如 warn 为 3 而 error 为 2，见 LevelEnabled
+func ParseLevel(s string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "trace":
+		return TRACE_LEVEL, nil
+	case "debug":
+		return DEBUG_LEVEL, nil
+	case "info":
+		return INFO_LEVEL, nil
+	case "warn", "warning":
+		return WARN_LEVEL, nil
+	case "error":
+		return ERROR_LEVEL, nil
+	case "fatal":
+		return FATAL_LEVEL, nil
+	}
+	return DEBUG_LEVEL, fmt.Errorf("unknown log level %q", s)
+}
+
+// profileLevel 环境默认的日志级别
+func profileLevel(p conf.Profile) int {
+	level, err := ParseLevel(p.Defaults().LogLevel)
+	if err != nil {
+		return DEBUG_LEVEL
+	}
+	return level
+}
+
+// SetProfile 按环境设置日志级别和是否输出到控制台，例如 release 为 INFO 级别且不输出到控制台
+func (c *LogPool) SetProfile(p conf.Profile) {
+	_ = c.SetLevel(profileLevel(p))
+	c.SetPrintConsole(p.Defaults().LogConsole)
+}
+
+// SetProfile 按环境设置日志级别和是否输出到控制台，例如 release 为 INFO 级别且不输出到控制台
+func (c *LogPoolV2) SetProfile(p conf.Profile) {
+	_ = c.SetLevel(profileLevel(p))
+	if p.Defaults().LogConsole {
+		c.SetPrintConsole(1)
+	} else {
+		c.SetPrintConsole(0)
+	}
+}
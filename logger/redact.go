@@ -0,0 +1,287 @@
+package logger
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/Chairou/toolbox/util/check"
+)
+
+// 脱敏方式，用于 RedactRule.Mask、LogOpt.RedactMask
+const (
+	// MaskFull 全部替换为 ******
+	MaskFull = "full"
+	// MaskPartial 保留首尾各四分之一，中间替换为 *，例如 13*******78，邮箱只处理 @ 之前的部分
+	MaskPartial = "partial"
+	// MaskHash 替换为 HMAC-SHA256 的前 16 位，可以关联同一个值但看不到原文。
+	// 必须设置密钥（RedactRule.HashKey、LogOpt.RedactHashKey），手机号、身份证号等取值范围小，不加密钥的 hash 可以被穷举还原
+	MaskHash = "hash"
+)
+
+const fullMask = "******"
+
+// RedactRule 脱敏规则，Keys 和 Pattern 至少设置一个
+type RedactRule struct {
+	Name string
+	// Keys 敏感的字段名，不区分大小写。结构化日志中同名字段的值整体脱敏，
+	// 消息中 key=value、key: value、"key":"value" 形式的值也会脱敏
+	Keys []string
+	// Pattern 消息和字符串字段中需要脱敏的内容，有分组时只替换第一个匹配到内容的分组
+	Pattern string
+	// Validate 匹配后再校验，返回 false 的不脱敏，例如身份证号的格式校验
+	Validate func(s string) bool
+	// Mask 脱敏方式 full、partial、hash，默认 full
+	Mask string
+	// HashKey hash 方式使用的 HMAC 密钥，Mask 为 hash 时必填，需要保密，更换后同一个值的结果也会变化
+	HashKey []byte
+}
+
+// 内置的检测规则，见 DefaultRedactRules
+var (
+	// RedactToken Authorization 中的 Bearer token 和 JWT
+	RedactToken = RedactRule{
+		Name:    "token",
+		Pattern: `(?i)\bbearer\s+([A-Za-z0-9\-._~+/]+=*)|\b(eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*)`,
+		Mask:    MaskFull,
+	}
+	// RedactEmail 邮箱
+	RedactEmail = RedactRule{
+		Name:    "email",
+		Pattern: `\b[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}\b`,
+		Mask:    MaskPartial,
+	}
+	// RedactMobile 中国大陆手机号
+	RedactMobile = RedactRule{
+		Name:    "mobile",
+		Pattern: `\b1[3-9]\d{9}\b`,
+		Mask:    MaskPartial,
+	}
+	// RedactIDCard 18 位身份证号，使用 check.IsValidIDCardNumber 校验，避免误伤订单号等长数字
+	RedactIDCard = RedactRule{
+		Name:    "idcard",
+		Pattern: `\b\d{17}[\dXx]\b`,
+		Validate: func(s string) bool {
+			return check.IsValidIDCardNumber(strings.ToUpper(s))
+		},
+		Mask: MaskPartial,
+	}
+	// RedactSecretKeys 常见的密码、密钥、token、cookie 字段
+	RedactSecretKeys = RedactRule{
+		Name: "keys",
+		Keys: []string{"password", "passwd", "pwd", "secret", "token", "access_token", "refresh_token",
+			"authorization", "cookie", "set-cookie", "api_key", "apikey", "x-api-key"},
+		Mask: MaskFull,
+	}
+)
+
+// DefaultRedactRules 内置的规则：token、邮箱、手机号、身份证号和常见的敏感字段
+func DefaultRedactRules() []RedactRule {
+	return []RedactRule{RedactToken, RedactEmail, RedactMobile, RedactIDCard, RedactSecretKeys}
+}
+
+// redactMask 脱敏方式和 hash 使用的密钥
+type redactMask struct {
+	mode    string
+	hashKey []byte
+}
+
+type redactPattern struct {
+	re       *regexp.Regexp
+	validate func(string) bool
+	mask     redactMask
+}
+
+// Redactor 按规则脱敏日志，在编码和写入任何 Sink 之前执行，见 SetRedactor
+type Redactor struct {
+	keys     map[string]redactMask // 小写的字段名 -> 脱敏方式
+	patterns []redactPattern
+}
+
+// NewRedactor 编译脱敏规则，规则按顺序执行
+func NewRedactor(rules ...RedactRule) (*Redactor, error) {
+	r := &Redactor{keys: map[string]redactMask{}}
+	for _, rule := range rules {
+		mask := redactMask{mode: rule.Mask}
+		switch mask.mode {
+		case "":
+			mask.mode = MaskFull
+		case MaskFull, MaskPartial:
+		case MaskHash:
+			if len(rule.HashKey) == 0 {
+				return nil, fmt.Errorf("redact rule %s: hash mask requires a hash key", rule.Name)
+			}
+			mask.hashKey = rule.HashKey
+		default:
+			return nil, fmt.Errorf("redact rule %s: unknown mask %q, want full, partial or hash", rule.Name, rule.Mask)
+		}
+		if len(rule.Keys) == 0 && rule.Pattern == "" {
+			return nil, fmt.Errorf("redact rule %s: keys and pattern are both empty", rule.Name)
+		}
+		if rule.Pattern != "" {
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("redact rule %s: %w", rule.Name, err)
+			}
+			r.patterns = append(r.patterns, redactPattern{re: re, validate: rule.Validate, mask: mask})
+		}
+		if len(rule.Keys) > 0 {
+			quoted := make([]string, len(rule.Keys))
+			for i, k := range rule.Keys {
+				r.keys[strings.ToLower(k)] = mask
+				quoted[i] = regexp.QuoteMeta(k)
+			}
+			re := regexp.MustCompile(`(?i)["']?\b(?:` + strings.Join(quoted, "|") + `)\b["']?\s*[:=]\s*\[?\s*(?:"([^"]*)"|'([^']*)'|([^\s"'&,;\]}]+))`)
+			r.patterns = append(r.patterns, redactPattern{re: re, mask: mask})
+		}
+	}
+	return r, nil
+}
+
+// DefaultRedactor 使用 DefaultRedactRules 的 Redactor
+var DefaultRedactor = sync.OnceValue(func() *Redactor {
+	r, err := NewRedactor(DefaultRedactRules()...)
+	if err != nil {
+		panic(err)
+	}
+	return r
+})
+
+// newRedactor 按 LogOpt 创建 Redactor，没有开启脱敏也没有额外规则时返回 nil
+func newRedactor(opt *LogOpt) (*Redactor, error) {
+	var rules []RedactRule
+	if opt.Redact == 1 {
+		rules = DefaultRedactRules()
+		if opt.RedactMask != "" {
+			for i := range rules {
+				rules[i].Mask = opt.RedactMask
+			}
+		}
+	}
+	if len(opt.RedactKeys) > 0 {
+		rules = append(rules, RedactRule{Name: "redactKeys", Keys: opt.RedactKeys, Mask: opt.RedactMask})
+	}
+	for i, pattern := range opt.RedactPatterns {
+		rules = append(rules, RedactRule{Name: fmt.Sprintf("redactPatterns[%d]", i), Pattern: pattern, Mask: opt.RedactMask})
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	if opt.RedactHashKey != "" {
+		for i := range rules {
+			rules[i].HashKey = []byte(opt.RedactHashKey)
+		}
+	}
+	return NewRedactor(rules...)
+}
+
+// String 对字符串脱敏
+func (r *Redactor) String(s string) string {
+	for _, p := range r.patterns {
+		s = p.replace(s)
+	}
+	return s
+}
+
+func (p redactPattern) replace(s string) string {
+	matches := p.re.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		// 有分组时替换第一个匹配到内容的分组，分组都为空时不替换
+		start, end := m[0], m[1]
+		if len(m) > 2 {
+			start, end = -1, -1
+			for i := 2; i < len(m); i += 2 {
+				if m[i] >= 0 && m[i+1] > m[i] {
+					start, end = m[i], m[i+1]
+					break
+				}
+			}
+			if start < 0 {
+				continue
+			}
+		}
+		if p.validate != nil && !p.validate(s[start:end]) {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(p.mask.apply(s[start:end]))
+		last = end
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// Redact 对日志的 tag、消息和字段脱敏。字段可能与其它日志共用，有改动时复制一份
+func (r *Redactor) Redact(rec *Record) {
+	rec.Tag = r.String(rec.Tag)
+	rec.Msg = r.String(rec.Msg)
+	var fields []Field
+	for i, f := range rec.Fields {
+		v, changed := r.field(f)
+		if !changed {
+			continue
+		}
+		if fields == nil {
+			fields = append([]Field(nil), rec.Fields...)
+		}
+		fields[i].Value = v
+	}
+	if fields != nil {
+		rec.Fields = fields
+	}
+}
+
+// field 敏感字段整体脱敏，其它字符串类型的值按规则脱敏，数字等类型保持不变
+func (r *Redactor) field(f Field) (any, bool) {
+	if mask, ok := r.keys[strings.ToLower(f.Key)]; ok {
+		return mask.apply(fieldString(f.Value)), true
+	}
+	switch f.Value.(type) {
+	case string, error, fmt.Stringer:
+		s := fieldString(f.Value)
+		if rs := r.String(s); rs != s {
+			return rs, true
+		}
+	}
+	return f.Value, false
+}
+
+// apply 按脱敏方式替换 s
+func (m redactMask) apply(s string) string {
+	switch m.mode {
+	case MaskHash:
+		h := hmac.New(sha256.New, m.hashKey)
+		h.Write([]byte(s))
+		return "hmac:" + hex.EncodeToString(h.Sum(nil))[:16]
+	case MaskPartial:
+		if at := strings.LastIndexByte(s, '@'); at > 0 {
+			return maskPartial(s[:at]) + s[at:]
+		}
+		return maskPartial(s)
+	}
+	return fullMask
+}
+
+// maskPartial 保留首尾各四分之一，少于 4 个字符时全部替换
+func maskPartial(s string) string {
+	n := utf8.RuneCountInString(s)
+	if n < 4 {
+		return strings.Repeat("*", n)
+	}
+	keep := n / 4
+	runes := []rune(s)
+	return string(runes[:keep]) + strings.Repeat("*", n-2*keep) + string(runes[n-keep:])
+}
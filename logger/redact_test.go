@@ -0,0 +1,110 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactor_String(t *testing.T) {
+	r := DefaultRedactor()
+	cases := []struct {
+		in, want string
+	}{
+		{"map[Authorization:[Bearer abc.DEF-123] Accept:[*/*]]", "map[Authorization:[****** ******] Accept:[*/*]]"},
+		{`{"password":"p@ss w0rd","user":"bob"}`, `{"password":"******","user":"bob"}`},
+		{"login?token=abcdef&page=2", "login?token=******&page=2"},
+		{"[sid=xyz; uid=1]", "[sid=xyz; uid=1]"},
+		{"Cookie:[sid=xyz; uid=1]", "Cookie:[******; uid=1]"},
+		{"mail alice@example.com now", "mail a***e@example.com now"},
+		{"call 13812345678, 13912345678", "call 13*******78, 13*******78"},
+		{"id 11010519491231002X ok", "id 1101**********002X ok"},
+		// 不是合法身份证号的长数字保持不变
+		{"order 123456789012345678", "order 123456789012345678"},
+		{"jwt eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.sig", "jwt ******"},
+	}
+	for _, c := range cases {
+		if got := r.String(c.in); got != c.want {
+			t.Errorf("String(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestRedactor_Masks(t *testing.T) {
+	r, err := NewRedactor(
+		RedactRule{Name: "card", Pattern: `card=(\d+)`, Mask: MaskHash, HashKey: []byte("k1")},
+		RedactRule{Name: "keys", Keys: []string{"bankCard"}, Mask: MaskPartial},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := r.String("card=6222020000000000"), r.String("card=6222020000000000")
+	if a != b || !strings.HasPrefix(a, "card=hmac:") || len(a) != len("card=hmac:")+16 {
+		t.Errorf("hash 应稳定且不含原文: %q %q", a, b)
+	}
+	other, _ := NewRedactor(RedactRule{Name: "card", Pattern: `card=(\d+)`, Mask: MaskHash, HashKey: []byte("k2")})
+	if c := other.String("card=6222020000000000"); c == a {
+		t.Errorf("不同密钥的 hash 结果应不同: %q", c)
+	}
+	if got := r.String("bankcard: 6222020000000000"); got != "bankcard: 6222********0000" {
+		t.Errorf("partial: %q", got)
+	}
+
+	for _, rule := range []RedactRule{{Name: "empty"}, {Name: "mask", Pattern: "x", Mask: "stars"}, {Name: "re", Pattern: "("},
+		{Name: "nokey", Pattern: "x", Mask: MaskHash}} {
+		if _, err := NewRedactor(rule); err == nil {
+			t.Errorf("%s 应返回错误", rule.Name)
+		}
+	}
+}
+
+func TestRedactor_Record(t *testing.T) {
+	lp, buf := newBufferLog(t, "redact_record", FormatJSON)
+	mem := NewMemorySink(10, TRACE_LEVEL, nil)
+	lp.AddSink(mem)
+	lp.SetRedactor(DefaultRedactor())
+
+	child := lp.With("password", "secret1", "count", 3)
+	child.Info("user alice@example.com login", "Authorization", "Bearer xyz", "phone", "13812345678")
+	out := buf.String()
+	for _, leak := range []string{"secret1", "alice@", "xyz", "13812345678"} {
+		if strings.Contains(out, leak) {
+			t.Errorf("日志中不应出现 %q: %s", leak, out)
+		}
+	}
+	if !strings.Contains(out, `"count":3`) || !strings.Contains(out, `"password":"******"`) {
+		t.Errorf("非敏感字段保持原样: %s", out)
+	}
+	if line := mem.Lines()[0]; strings.Contains(line, "secret1") || strings.Contains(line, "alice@") {
+		t.Errorf("Sink 收到的也应是脱敏后的日志: %s", line)
+	}
+	// 共用的字段不能被修改
+	if child.fields[0].Value != "secret1" {
+		t.Errorf("With 的字段被修改: %v", child.fields)
+	}
+}
+
+func TestNewLogOpt_Redact(t *testing.T) {
+	lp, err := NewLogOpt("redact_v2", &LogOpt{FileName: "log/redact_v2.log", Redact: 1, RedactMask: MaskHash, RedactHashKey: "log-hash-key",
+		RedactKeys: []string{"bankCard"}, RedactPatterns: []string{`order-(\d+)`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Unregister("redact_v2")
+	mem := NewMemorySink(10, TRACE_LEVEL, nil)
+	lp.AddSink(mem)
+	lp.Infow("paid order-42 by 13812345678", "bankCard", "6222020000000000")
+	rec := mem.Records()[0]
+	if strings.Contains(rec.Msg, "42") || strings.Contains(rec.Msg, "13812345678") || !strings.Contains(rec.Msg, "hmac:") {
+		t.Errorf("消息应按 hash 脱敏: %s", rec.Msg)
+	}
+	if v := rec.Fields[0].Value.(string); !strings.HasPrefix(v, "hmac:") {
+		t.Errorf("bankCard 应脱敏: %s", v)
+	}
+
+	if _, err := NewLogOpt("redact_bad", &LogOpt{FileName: "log/redact_bad.log", Redact: 1, RedactMask: "stars"}); err == nil {
+		t.Error("未知的 RedactMask 应返回错误")
+	}
+	if _, err := NewLogOpt("redact_bad", &LogOpt{FileName: "log/redact_bad.log", Redact: 1, RedactMask: MaskHash}); err == nil {
+		t.Error("hash 方式没有密钥时应返回错误")
+	}
+}
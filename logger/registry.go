@@ -0,0 +1,110 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrLoggerNotFound 没有该名字或编号的日志
+var ErrLoggerNotFound = errors.New("logger not found")
+
+// registry 全部日志共用的注册表，按名字查找，按创建顺序编号
+var registry = struct {
+	sync.RWMutex
+	byName map[string]Log
+	order  []Log
+}{byName: make(map[string]Log)}
+
+// Register 注册日志，名字已被使用时返回错误。NewLogPool、NewLogOpt 创建的日志会自动注册
+func Register(name string, l Log) error {
+	registry.Lock()
+	defer registry.Unlock()
+	if old, ok := registry.byName[name]; ok {
+		return fmt.Errorf("logger %s already registered as %T", name, old)
+	}
+	registry.byName[name] = l
+	registry.order = append(registry.order, l)
+	return nil
+}
+
+// Unregister 移除日志，不会关闭日志文件
+func Unregister(name string) {
+	registry.Lock()
+	defer registry.Unlock()
+	l, ok := registry.byName[name]
+	if !ok {
+		return
+	}
+	delete(registry.byName, name)
+	for i, item := range registry.order {
+		if item == l {
+			registry.order = append(registry.order[:i], registry.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// Get 按名字获取日志
+func Get(name string) (Log, error) {
+	registry.RLock()
+	defer registry.RUnlock()
+	if l, ok := registry.byName[name]; ok {
+		return l, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrLoggerNotFound, name)
+}
+
+// First 第一个创建的日志
+func First() (Log, error) {
+	registry.RLock()
+	defer registry.RUnlock()
+	if len(registry.order) == 0 {
+		return nil, ErrLoggerNotFound
+	}
+	return registry.order[0], nil
+}
+
+// Names 全部日志的名字，按名字排序
+func Names() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+	names := make([]string, 0, len(registry.byName))
+	for name := range registry.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookup 按名字获取指定类型的日志，兼容 GetLogName、GetLogNameV2
+func lookup[T Log](name string) (T, error) {
+	var zero T
+	l, err := Get(name)
+	if err != nil {
+		return zero, err
+	}
+	t, ok := l.(T)
+	if !ok {
+		return zero, fmt.Errorf("logger %s is %T, not %T", name, l, zero)
+	}
+	return t, nil
+}
+
+// lookupNum 按创建顺序获取第 num 个指定类型的日志，从 1 开始，兼容 GetLogNum、GetLogNumV2
+func lookupNum[T Log](num int) (T, error) {
+	var zero T
+	registry.RLock()
+	defer registry.RUnlock()
+	n := 0
+	for _, l := range registry.order {
+		if t, ok := l.(T); ok {
+			n++
+			if n == num {
+				return t, nil
+			}
+		}
+	}
+	return zero, fmt.Errorf("%w: %T number %d", ErrLoggerNotFound, zero, num)
+}
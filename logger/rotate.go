@@ -0,0 +1,155 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RotatePolicy 日志切割策略，Loggerj 每次写入前询问是否需要切割。
+// 时间均为 Loggerj 时区下的时间，见 Loggerj.Location
+type RotatePolicy interface {
+	// ShouldRotate size 为当前文件大小，writeLen 为本次写入长度，opened 为当前文件开始写入的时间
+	ShouldRotate(size, writeLen int64, opened, now time.Time) bool
+	// BackupTime 切割出的备份文件名中使用的时间
+	BackupTime(opened, now time.Time) time.Time
+}
+
+// SizePolicy 按大小切割，写入后超过 MaxSize 字节时切割，备份文件名使用切割时间
+type SizePolicy struct {
+	MaxSize int64
+}
+
+func (p SizePolicy) ShouldRotate(size, writeLen int64, _, _ time.Time) bool {
+	return p.MaxSize > 0 && size+writeLen > p.MaxSize
+}
+
+func (p SizePolicy) BackupTime(_, now time.Time) time.Time {
+	return now
+}
+
+// RotateEvery 按时间切割的周期
+type RotateEvery string
+
+const (
+	// RotateHourly 每小时整点切割
+	RotateHourly RotateEvery = "hourly"
+	// RotateDaily 每天零点切割
+	RotateDaily RotateEvery = "daily"
+)
+
+// TimePolicy 按时间切割，进入新的小时或新的一天后第一次写入时切割。
+// 备份文件名使用文件所属周期的开始时间，例如 10 月 18 日的日志切割后为 app-2026-10-18.log
+type TimePolicy struct {
+	Every RotateEvery
+}
+
+func (p TimePolicy) ShouldRotate(_, _ int64, opened, now time.Time) bool {
+	if opened.IsZero() {
+		return false
+	}
+	return !p.periodStart(opened.In(now.Location())).Equal(p.periodStart(now))
+}
+
+func (p TimePolicy) BackupTime(opened, now time.Time) time.Time {
+	if opened.IsZero() {
+		return p.periodStart(now)
+	}
+	return p.periodStart(opened.In(now.Location()))
+}
+
+// periodStart t 所在周期的开始时间，按 t 的时区计算零点和整点
+func (p TimePolicy) periodStart(t time.Time) time.Time {
+	y, m, d := t.Date()
+	if p.Every == RotateHourly {
+		return time.Date(y, m, d, t.Hour(), 0, 0, 0, t.Location())
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// CombinedPolicy 组合策略，任一策略满足即切割，备份文件名使用各策略中最早的时间，
+// 例如按天且按大小切割时，同一天切出的文件为 app-2026-10-18.log、app-2026-10-18.1.log
+type CombinedPolicy []RotatePolicy
+
+func (p CombinedPolicy) ShouldRotate(size, writeLen int64, opened, now time.Time) bool {
+	for _, policy := range p {
+		if policy.ShouldRotate(size, writeLen, opened, now) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p CombinedPolicy) BackupTime(opened, now time.Time) time.Time {
+	t := now
+	for _, policy := range p {
+		if bt := policy.BackupTime(opened, now); bt.Before(t) {
+			t = bt
+		}
+	}
+	return t
+}
+
+// 切割方式，用于 LogOpt.Rotate
+const (
+	RotateSize = "size"
+)
+
+// 备份文件名，{name} 为不含扩展名的文件名，{time} 为切割时间，{ext} 为扩展名
+const (
+	defaultBackupPattern = "{name}-{time}{ext}"
+	dailyTimeFormat      = "2006-01-02"
+	hourlyTimeFormat     = "2006-01-02T15"
+)
+
+// checkBackupPattern 备份文件名必须包含且只包含一个 {time}
+func checkBackupPattern(pattern string) error {
+	if pattern != "" && strings.Count(pattern, "{time}") != 1 {
+		return fmt.Errorf("backup pattern %q must contain {time} exactly once", pattern)
+	}
+	return nil
+}
+
+// applyRotate 按 LogOpt 设置 Loggerj 的切割策略、时区和备份文件名。
+// 按时间切割时只有显式设置了 MaxSizeMB 才同时按大小切割
+func applyRotate(l *Loggerj, opt *LogOpt) error {
+	if err := checkBackupPattern(opt.BackupPattern); err != nil {
+		return err
+	}
+	l.BackupPattern = opt.BackupPattern
+	l.BackupTimeFormat = opt.BackupTimeFormat
+	if opt.RotateTimezone != "" {
+		loc, err := time.LoadLocation(opt.RotateTimezone)
+		if err != nil {
+			return fmt.Errorf("logger rotate timezone: %w", err)
+		}
+		l.Location = loc
+	}
+
+	var every RotateEvery
+	switch strings.ToLower(opt.Rotate) {
+	case "", RotateSize:
+		return nil
+	case string(RotateHourly):
+		every = RotateHourly
+		if l.BackupTimeFormat == "" {
+			l.BackupTimeFormat = hourlyTimeFormat
+		}
+	case string(RotateDaily):
+		every = RotateDaily
+		if l.BackupTimeFormat == "" {
+			l.BackupTimeFormat = dailyTimeFormat
+		}
+	default:
+		return fmt.Errorf("unknown rotate %q, want size, hourly or daily", opt.Rotate)
+	}
+	if l.Location == nil {
+		l.Location = time.Local
+	}
+	if opt.MaxSizeMB > 0 {
+		l.Policy = CombinedPolicy{TimePolicy{Every: every}, SizePolicy{MaxSize: l.max()}}
+	} else {
+		l.Policy = TimePolicy{Every: every}
+	}
+	return nil
+}
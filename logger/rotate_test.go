@@ -0,0 +1,176 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// fakeClock 可以手动调整的时钟
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func newRotateLog(t *testing.T, policy RotatePolicy, loc *time.Location, start time.Time) (*Loggerj, *fakeClock, string) {
+	dir := t.TempDir()
+	clock := &fakeClock{t: start}
+	l := &Loggerj{
+		Filename:         filepath.Join(dir, "app.log"),
+		Policy:           policy,
+		Location:         loc,
+		BackupTimeFormat: dailyTimeFormat,
+		clock:            clock.now,
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	return l, clock, dir
+}
+
+func dirNames(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestTimePolicy(t *testing.T) {
+	shanghai := time.FixedZone("CST", 8*3600)
+	opened := time.Date(2026, 10, 18, 23, 10, 0, 0, shanghai)
+	daily := TimePolicy{Every: RotateDaily}
+	if daily.ShouldRotate(0, 0, opened, opened.Add(30*time.Minute)) {
+		t.Error("同一天不应切割")
+	}
+	if !daily.ShouldRotate(0, 0, opened, opened.Add(time.Hour)) {
+		t.Error("跨天应切割")
+	}
+	// 上海的 10-19 00:30 是 UTC 的 10-18 16:30，只有按上海时区才切割
+	now := opened.Add(80 * time.Minute)
+	if !daily.ShouldRotate(0, 0, opened, now) || daily.ShouldRotate(0, 0, opened, now.UTC()) {
+		t.Error("应按时区的零点切割")
+	}
+	if got := daily.BackupTime(opened, opened.Add(time.Hour)); !got.Equal(time.Date(2026, 10, 18, 0, 0, 0, 0, shanghai)) {
+		t.Errorf("备份时间应为所属日期的零点: %v", got)
+	}
+
+	hourly := TimePolicy{Every: RotateHourly}
+	if hourly.ShouldRotate(0, 0, opened, opened.Add(40*time.Minute)) != false ||
+		hourly.ShouldRotate(0, 0, opened, opened.Add(50*time.Minute)) != true {
+		t.Error("应在整点切割")
+	}
+
+	combined := CombinedPolicy{daily, SizePolicy{MaxSize: 10}}
+	if !combined.ShouldRotate(8, 4, opened, opened) || combined.ShouldRotate(4, 4, opened, opened) {
+		t.Error("组合策略任一满足即切割")
+	}
+	if got := combined.BackupTime(opened, opened.Add(time.Minute)); !got.Equal(daily.BackupTime(opened, opened)) {
+		t.Errorf("组合策略应使用最早的时间: %v", got)
+	}
+}
+
+func TestLoggerj_DailyRotate(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	start := time.Date(2026, 10, 17, 10, 0, 0, 0, loc)
+	l, clock, dir := newRotateLog(t, TimePolicy{Every: RotateDaily}, loc, start)
+	for i := 0; i < 3; i++ {
+		if _, err := l.Write([]byte("line\n")); err != nil {
+			t.Fatal(err)
+		}
+		clock.t = clock.t.Add(24 * time.Hour)
+	}
+	want := []string{"app-2026-10-17.log", "app-2026-10-18.log", "app.log"}
+	if got := dirNames(t, dir); !equalStrings(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestLoggerj_CombinedRotateCounter(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	start := time.Date(2026, 10, 18, 10, 0, 0, 0, loc)
+	policy := CombinedPolicy{TimePolicy{Every: RotateDaily}, SizePolicy{MaxSize: 10}}
+	l, clock, dir := newRotateLog(t, policy, loc, start)
+	l.BackupPattern = "{name}.{time}{ext}"
+	for i := 0; i < 3; i++ {
+		if _, err := l.Write([]byte("0123456789")); err != nil {
+			t.Fatal(err)
+		}
+		clock.t = clock.t.Add(time.Minute)
+	}
+	want := []string{"app.2026-10-18.1.log", "app.2026-10-18.log", "app.log"}
+	if got := dirNames(t, dir); !equalStrings(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	files, err := l.oldLogFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].Name() != "app.2026-10-18.1.log" {
+		t.Errorf("同一天的备份应按序号从新到旧排序: %v", files)
+	}
+}
+
+func TestLoggerj_CleanupRespectsPattern(t *testing.T) {
+	start := time.Date(2026, 10, 6, 10, 0, 0, 0, time.UTC)
+	l, _, dir := newRotateLog(t, TimePolicy{Every: RotateDaily}, time.UTC, start)
+	l.MaxBackups = 3
+	l.MaxAge = 3
+	// 不符合备份文件名的文件不应被删除
+	names := []string{"app.log", "app-old.log", "other-2026-09-01.log", "app-2026-09-01.txt"}
+	for day := 1; day <= 5; day++ {
+		names = append(names, time.Date(2026, 10, day, 0, 0, 0, 0, time.UTC).Format("app-2006-01-02.log"))
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.millRunOnce(); err != nil {
+		t.Fatal(err)
+	}
+	// MaxBackups 保留 10-03 到 10-05，MaxAge 再删除 10-03 10:00 之前的 10-03
+	want := []string{"app-2026-09-01.txt", "app-2026-10-04.log", "app-2026-10-05.log", "app-old.log", "app.log", "other-2026-09-01.log"}
+	if got := dirNames(t, dir); !equalStrings(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestNewLogOpt_Rotate(t *testing.T) {
+	lp, err := NewLogOpt("rotate_daily", &LogOpt{FileName: "log/rotate_daily.log", Rotate: "daily", RotateTimezone: "UTC", MaxSizeMB: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Unregister("rotate_daily")
+	lj := lp.out.(*Loggerj)
+	if _, ok := lj.Policy.(CombinedPolicy); !ok || lj.Location != time.UTC || lj.BackupTimeFormat != dailyTimeFormat {
+		t.Errorf("daily 且设置 MaxSizeMB 时应同时按天和大小切割: %#v", lj)
+	}
+
+	for name, opt := range map[string]*LogOpt{
+		"rotate_bad":     {FileName: "log/rotate_bad.log", Rotate: "weekly"},
+		"rotate_bad_tz":  {FileName: "log/rotate_bad_tz.log", Rotate: "daily", RotateTimezone: "Mars/Olympus"},
+		"rotate_bad_pat": {FileName: "log/rotate_bad_pat.log", BackupPattern: "{name}{ext}"},
+	} {
+		if _, err := NewLogOpt(name, opt); err == nil {
+			t.Errorf("%s 应返回错误", name)
+		}
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,198 @@
+package logger
+
+import (
+	"fmt"
+	"hash/maphash"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// SampleOpt 日志采样配置：每个周期内同一条消息先输出 First 条，之后每 Thereafter 条输出一条，
+// 被丢弃的条数在周期结束时汇总输出一条。用于数据库故障等场景下大量相同的错误日志
+type SampleOpt struct {
+	// Logger 只对该名字的日志生效，为空时对全部日志生效
+	Logger string `config:"logger"`
+	// Level 生效的级别 trace、debug、info、warn、error，为空时对全部级别生效，FATAL 不采样
+	Level string `config:"level"`
+	// Interval 统计周期，默认 1s
+	Interval time.Duration `config:"interval"`
+	// First 每个周期内同一条消息全部输出的条数，必须大于 0
+	First int `config:"first"`
+	// Thereafter 超过 First 后每多少条输出一条，0 表示全部丢弃
+	Thereafter int `config:"thereafter"`
+}
+
+const defaultSampleInterval = time.Second
+
+// sampledLevels 可以采样的级别
+var sampledLevels = []int{TRACE_LEVEL, DEBUG_LEVEL, INFO_LEVEL, WARN_LEVEL, ERROR_LEVEL}
+
+// sampler 按级别采样，同一条消息按 tag 和消息内容区分
+type sampler struct {
+	levels map[int]*levelSampler
+}
+
+// newSampler 按配置创建采样器，只使用 Logger 为空或等于 name 的配置，后面的配置覆盖前面同级别的配置。
+// 没有生效的配置时返回 nil
+func newSampler(name string, opts []SampleOpt, emit func(r *Record)) (*sampler, error) {
+	s := &sampler{levels: map[int]*levelSampler{}}
+	for _, opt := range opts {
+		if opt.Logger != "" && opt.Logger != name {
+			continue
+		}
+		if opt.First <= 0 {
+			return nil, fmt.Errorf("logger sampling: first must be positive, got %d", opt.First)
+		}
+		if opt.Thereafter < 0 {
+			return nil, fmt.Errorf("logger sampling: thereafter must not be negative, got %d", opt.Thereafter)
+		}
+		if opt.Interval <= 0 {
+			opt.Interval = defaultSampleInterval
+		}
+		levels := sampledLevels
+		if opt.Level != "" {
+			level, err := ParseLevel(opt.Level)
+			if err != nil {
+				return nil, fmt.Errorf("logger sampling: %w", err)
+			}
+			if level == FATAL_LEVEL {
+				return nil, fmt.Errorf("logger sampling: FATAL logs are never sampled")
+			}
+			levels = []int{level}
+		}
+		for _, level := range levels {
+			s.levels[level] = &levelSampler{
+				level:      level,
+				logger:     name,
+				opt:        opt,
+				now:        time.Now,
+				emit:       emit,
+				seed:       maphash.MakeSeed(),
+				counts:     map[uint64]int{},
+				suppressed: map[uint64]*suppressedLog{},
+			}
+		}
+	}
+	if len(s.levels) == 0 {
+		return nil, nil
+	}
+	return s, nil
+}
+
+// allow 这条日志是否输出
+func (s *sampler) allow(level int, tag, msg string) bool {
+	ls := s.levels[level]
+	if ls == nil {
+		return true
+	}
+	return ls.allow(tag, msg)
+}
+
+// suppressedLog 一个周期内被丢弃的同一条消息
+type suppressedLog struct {
+	tag   string
+	msg   string
+	count int
+}
+
+// levelSampler 一个级别的采样状态，计数在每个周期开始时清零
+type levelSampler struct {
+	level  int
+	logger string
+	opt    SampleOpt
+	now    func() time.Time
+	emit   func(r *Record)
+	seed   maphash.Seed
+
+	mu         sync.Mutex
+	window     time.Time
+	counts     map[uint64]int
+	suppressed map[uint64]*suppressedLog
+	reporting  atomic.Bool
+}
+
+func (ls *levelSampler) allow(tag, msg string) bool {
+	var h maphash.Hash
+	h.SetSeed(ls.seed)
+	_, _ = h.WriteString(tag)
+	_ = h.WriteByte(0)
+	_, _ = h.WriteString(msg)
+	key := h.Sum64()
+
+	ls.mu.Lock()
+	now := ls.now()
+	if now.Sub(ls.window) >= ls.opt.Interval {
+		clear(ls.counts)
+		ls.window = now
+	}
+	n := ls.counts[key] + 1
+	ls.counts[key] = n
+	if n <= ls.opt.First || (ls.opt.Thereafter > 0 && (n-ls.opt.First)%ls.opt.Thereafter == 0) {
+		ls.mu.Unlock()
+		return true
+	}
+	e := ls.suppressed[key]
+	if e == nil {
+		e = &suppressedLog{tag: tag, msg: strings.TrimSuffix(msg, "\n")}
+		ls.suppressed[key] = e
+	}
+	e.count++
+	ls.mu.Unlock()
+
+	if ls.reporting.CompareAndSwap(false, true) {
+		go ls.report()
+	}
+	return false
+}
+
+// report 每个周期输出一次汇总，没有被丢弃的日志时退出，下次丢弃时再启动
+func (ls *levelSampler) report() {
+	ticker := time.NewTicker(ls.opt.Interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if ls.flush() == 0 {
+			ls.reporting.Store(false)
+			// 退出前再检查一次，避免漏掉刚刚丢弃的日志
+			if ls.pending() == 0 || !ls.reporting.CompareAndSwap(false, true) {
+				return
+			}
+		}
+	}
+}
+
+func (ls *levelSampler) pending() int {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return len(ls.suppressed)
+}
+
+// flush 输出汇总并清空，返回汇总的消息数
+func (ls *levelSampler) flush() int {
+	ls.mu.Lock()
+	suppressed := ls.suppressed
+	ls.suppressed = map[uint64]*suppressedLog{}
+	ls.mu.Unlock()
+	for _, e := range suppressed {
+		msg := e.msg
+		if len(msg) > 200 {
+			i := 200
+			for i > 0 && !utf8.RuneStart(msg[i]) {
+				i--
+			}
+			msg = msg[:i] + "..."
+		}
+		ls.emit(&Record{
+			Time:   ls.now(),
+			Level:  ls.level,
+			Logger: ls.logger,
+			Tag:    e.tag,
+			Caller: "sampler",
+			Msg:    fmt.Sprintf("log sampling suppressed %d logs in %s", e.count, ls.opt.Interval),
+			Fields: []Field{F("suppressed", e.count), F("sampled_msg", msg)},
+		})
+	}
+	return len(suppressed)
+}
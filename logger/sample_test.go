@@ -0,0 +1,110 @@
+package logger
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSampling_FirstThereafter(t *testing.T) {
+	lp, buf := newBufferLog(t, "sample_first", FormatLogfmt)
+	if err := lp.SetSampling(SampleOpt{Level: "error", First: 2, Thereafter: 3, Interval: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	clock := &fakeClock{t: time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)}
+	ls := lp.sampler.Load().levels[ERROR_LEVEL]
+	ls.now = clock.now
+
+	for i := 0; i < 10; i++ {
+		lp.Error("mysql down")
+		lp.Info("request")
+	}
+	lp.ErrorTag("cache", "mysql down")
+	// 第 1、2、5、8 条输出，INFO 不采样，tag 不同的消息单独计数
+	if got := strings.Count(buf.String(), `msg="mysql down"`); got != 5 {
+		t.Errorf("期望输出 5 条 ERROR, 实际 %d: %s", got, buf.String())
+	}
+	if got := strings.Count(buf.String(), "level=INFO"); got != 10 {
+		t.Errorf("INFO 不应采样, 实际 %d", got)
+	}
+
+	buf.Reset()
+	if n := ls.flush(); n != 1 {
+		t.Fatalf("应有 1 条汇总, 实际 %d", n)
+	}
+	if out := buf.String(); !strings.Contains(out, "level=ERROR") || !strings.Contains(out, "suppressed=6") || !strings.Contains(out, `sampled_msg="mysql down"`) {
+		t.Errorf("汇总: %s", out)
+	}
+
+	// 新的周期重新计数
+	buf.Reset()
+	clock.t = clock.t.Add(time.Hour)
+	lp.Error("mysql down")
+	lp.Error("mysql down")
+	if got := strings.Count(buf.String(), `msg="mysql down"`); got != 2 {
+		t.Errorf("新周期应重新计数, 实际 %d", got)
+	}
+
+	// 关闭采样
+	_ = lp.SetSampling()
+	if lp.sampler.Load() != nil {
+		t.Error("没有参数时应关闭采样")
+	}
+}
+
+func TestSampling_PeriodicSummary(t *testing.T) {
+	lp, _ := newBufferLog(t, "sample_summary", "")
+	mem := NewMemorySink(100, TRACE_LEVEL, nil)
+	lp.AddSink(mem)
+	if err := lp.SetSampling(SampleOpt{First: 1, Interval: 20 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		lp.Warn("disk slow")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, r := range mem.Records() {
+			if r.Caller == "sampler" && r.Level == WARN_LEVEL && r.Fields[0].Value == 4 {
+				return
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Errorf("应定期输出汇总: %v", mem.Lines())
+}
+
+func TestSampling_Config(t *testing.T) {
+	if s, err := newSampler("api", []SampleOpt{{Logger: "worker", First: 1}}, nil); s != nil || err != nil {
+		t.Errorf("其它日志名的配置不生效: %v %v", s, err)
+	}
+	for _, opt := range []SampleOpt{{First: 0}, {First: 1, Thereafter: -1}, {First: 1, Level: "fatal"}, {First: 1, Level: "loud"}} {
+		if _, err := newSampler("api", []SampleOpt{opt}, nil); err == nil {
+			t.Errorf("%+v 应返回错误", opt)
+		}
+	}
+
+	lp, err := NewLogOpt("sample_v2", &LogOpt{FileName: "log/sample_v2.log", Sampling: []SampleOpt{
+		{First: 100},
+		{Logger: "sample_v2", Level: "error", First: 10, Thereafter: 100},
+		{Logger: "other", Level: "warn", First: 1},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Unregister("sample_v2")
+	levels := lp.sampler.Load().levels
+	if levels[ERROR_LEVEL].opt.First != 10 || levels[WARN_LEVEL].opt.First != 100 || levels[WARN_LEVEL].opt.Interval != time.Second {
+		t.Errorf("按级别和日志名配置: error %+v, warn %+v", levels[ERROR_LEVEL].opt, levels[WARN_LEVEL].opt)
+	}
+
+	// 采样配置错误时关闭已经创建的异步写入
+	before := runtime.NumGoroutine()
+	if _, err := NewLogOpt("sample_bad", &LogOpt{FileName: "log/sample_bad.log", Async: 1, Sampling: []SampleOpt{{First: 0}}}); err == nil {
+		t.Error("采样配置错误应返回错误")
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("出错时异步写入的协程应退出: %d -> %d", before, n)
+	}
+}
@@ -0,0 +1,273 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/Chairou/toolbox/util/color"
+)
+
+// Sink 日志的一个输出目标，一个日志可以同时输出到多个 Sink，每个 Sink 有自己的最低级别和编码器。
+// Sink 在日志级别之上再过滤：低于日志级别的记录不会交给任何 Sink
+type Sink interface {
+	// Enabled 该级别的日志是否输出到这个 Sink
+	Enabled(level int) bool
+	// Write 输出一条日志，r 在返回后可能被复用，需要保留时复制
+	Write(r *Record) error
+	Close() error
+}
+
+// 内置的 Sink 类型，用于 SinkOpt.Type
+const (
+	SinkFile   = "file"
+	SinkStderr = "stderr"
+	SinkStdout = "stdout"
+	SinkSyslog = "syslog"
+	SinkMemory = "memory"
+)
+
+// SinkOpt Sink 配置，用于 LogOpt.Sinks
+type SinkOpt struct {
+	Type   string `config:"type"`   // file、stderr、stdout、syslog、memory
+	Level  string `config:"level"`  // 最低级别 trace、debug、info、warn、error、fatal，默认全部输出
+	Format string `config:"format"` // 日志格式 text、logfmt、json，默认 text，syslog 默认 logfmt
+	// FileName file 的文件名，切割配置与 LogOpt 相同
+	FileName string `config:"fileName"`
+	// Color stderr、stdout 是否按级别着色，1 开启
+	Color int `config:"color"`
+	// Network syslog 的网络 udp、tcp、unixgram、unix，默认 udp
+	Network string `config:"network"`
+	// Addr syslog 的地址，例如 127.0.0.1:514、/dev/log
+	Addr string `config:"addr"`
+	// AppName syslog 的 APP-NAME，默认为进程名
+	AppName string `config:"appName"`
+	// Facility syslog 的 facility，默认 1（user）
+	Facility int `config:"facility"`
+	// Size memory 保留的条数，默认 1000
+	Size int `config:"size"`
+}
+
+// sinkLevel 解析 Sink 的最低级别，为空时输出全部级别
+func sinkLevel(s string) (int, error) {
+	if s == "" {
+		return TRACE_LEVEL, nil
+	}
+	return ParseLevel(s)
+}
+
+// newSink 按配置创建 Sink，file 类型使用 opt 的切割配置
+func newSink(so SinkOpt, opt *LogOpt) (Sink, error) {
+	level, err := sinkLevel(so.Level)
+	if err != nil {
+		return nil, fmt.Errorf("logger sink %s: %w", so.Type, err)
+	}
+	format := so.Format
+	if format == "" && so.Type == SinkSyslog {
+		format = FormatLogfmt
+	}
+	enc, err := NewEncoder(format)
+	if err != nil {
+		return nil, fmt.Errorf("logger sink %s: %w", so.Type, err)
+	}
+	switch strings.ToLower(so.Type) {
+	case SinkFile:
+		if so.FileName == "" {
+			return nil, fmt.Errorf("logger sink file: fileName is empty")
+		}
+		fileName, err := safePath("./", so.FileName)
+		if err != nil {
+			return nil, fmt.Errorf("logger sink file: %w", err)
+		}
+		lj, err := newLoggerj(fileName, opt)
+		if err != nil {
+			return nil, err
+		}
+		return NewWriterSink(lj, level, enc), nil
+	case SinkStderr:
+		return NewConsoleSink(os.Stderr, level, enc, so.Color == 1), nil
+	case SinkStdout:
+		return NewConsoleSink(os.Stdout, level, enc, so.Color == 1), nil
+	case SinkSyslog:
+		return NewSyslogSink(SyslogOpt{
+			Network:  so.Network,
+			Addr:     so.Addr,
+			AppName:  so.AppName,
+			Facility: so.Facility,
+		}, level, enc)
+	case SinkMemory:
+		return NewMemorySink(so.Size, level, enc), nil
+	}
+	return nil, fmt.Errorf("unknown logger sink %q, want file, stderr, stdout, syslog or memory", so.Type)
+}
+
+// WriterSink 把日志编码后写入 io.Writer，例如文件
+type WriterSink struct {
+	level   int
+	encoder Encoder
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink 创建输出到 w 的 Sink，level 为最低级别，enc 为空时使用 TextEncoder
+func NewWriterSink(w io.Writer, level int, enc Encoder) *WriterSink {
+	if enc == nil {
+		enc = TextEncoder{}
+	}
+	return &WriterSink{level: level, encoder: enc, w: w}
+}
+
+func (s *WriterSink) Enabled(level int) bool {
+	return levelRank(level) >= levelRank(s.level)
+}
+
+func (s *WriterSink) Write(r *Record) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	*buf = s.encoder.Encode(*buf, r)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.w.Write(*buf)
+	return err
+}
+
+// Close 关闭下层的 Writer，文件再次写入时会重新打开
+func (s *WriterSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if closer, ok := s.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// ConsoleSink 输出到控制台，可以按级别着色：ERROR 及以上红色，WARN 黄色，DEBUG 及以下灰色
+type ConsoleSink struct {
+	level   int
+	encoder Encoder
+	color   bool
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewConsoleSink 创建输出到 w 的控制台 Sink，通常为 os.Stderr，Close 不会关闭 w
+func NewConsoleSink(w io.Writer, level int, enc Encoder, colored bool) *ConsoleSink {
+	if enc == nil {
+		enc = TextEncoder{}
+	}
+	return &ConsoleSink{level: level, encoder: enc, color: colored, w: w}
+}
+
+func (s *ConsoleSink) Enabled(level int) bool {
+	return levelRank(level) >= levelRank(s.level)
+}
+
+func (s *ConsoleSink) Write(r *Record) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	*buf = s.encoder.Encode(*buf, r)
+	line := string(*buf)
+	if s.color {
+		if c := levelColor(r.Level); c != "" {
+			line = color.SetColor(c, strings.TrimSuffix(line, "\n")) + "\n"
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := io.WriteString(s.w, line)
+	return err
+}
+
+func (s *ConsoleSink) Close() error {
+	return nil
+}
+
+// levelColor 级别对应的控制台颜色，INFO 不着色
+func levelColor(level int) string {
+	switch {
+	case levelRank(level) >= levelRank(ERROR_LEVEL):
+		return color.Red
+	case level == WARN_LEVEL:
+		return color.Yellow
+	case levelRank(level) <= levelRank(DEBUG_LEVEL):
+		return color.Gray
+	}
+	return ""
+}
+
+// MemorySink 在内存中保留最近的日志，用于测试断言或排查时查看
+type MemorySink struct {
+	level   int
+	encoder Encoder
+
+	mu      sync.Mutex
+	records []Record
+	next    int
+	full    bool
+}
+
+// NewMemorySink 创建保留最近 size 条日志的 Sink，size 为 0 时为 1000
+func NewMemorySink(size int, level int, enc Encoder) *MemorySink {
+	if size <= 0 {
+		size = 1000
+	}
+	if enc == nil {
+		enc = TextEncoder{}
+	}
+	return &MemorySink{level: level, encoder: enc, records: make([]Record, size)}
+}
+
+func (s *MemorySink) Enabled(level int) bool {
+	return levelRank(level) >= levelRank(s.level)
+}
+
+func (s *MemorySink) Write(r *Record) error {
+	rec := *r
+	rec.Fields = append([]Field(nil), r.Fields...)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[s.next] = rec
+	s.next = (s.next + 1) % len(s.records)
+	if s.next == 0 {
+		s.full = true
+	}
+	return nil
+}
+
+// Records 按时间顺序返回保留的日志
+func (s *MemorySink) Records() []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.full {
+		return append([]Record(nil), s.records[:s.next]...)
+	}
+	return append(append([]Record(nil), s.records[s.next:]...), s.records[:s.next]...)
+}
+
+// Lines 按时间顺序返回编码后的日志，不含结尾的换行
+func (s *MemorySink) Lines() []string {
+	records := s.Records()
+	lines := make([]string, 0, len(records))
+	var buf []byte
+	for i := range records {
+		buf = s.encoder.Encode(buf[:0], &records[i])
+		lines = append(lines, strings.TrimSuffix(string(buf), "\n"))
+	}
+	return lines
+}
+
+// Reset 清空保留的日志
+func (s *MemorySink) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.records)
+	s.next, s.full = 0, false
+}
+
+func (s *MemorySink) Close() error {
+	return nil
+}
@@ -0,0 +1,172 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Chairou/toolbox/util/color"
+)
+
+func TestSink_LevelRouting(t *testing.T) {
+	lp, buf := newBufferLog(t, "sink_routing", "")
+	all := NewMemorySink(10, TRACE_LEVEL, nil)
+	errOnly := NewMemorySink(10, ERROR_LEVEL, JSONEncoder{})
+	lp.AddSink(all)
+	lp.AddSink(errOnly)
+
+	lp.Debug("debug")
+	lp.InfoTag("order", "info")
+	lp.With("id", 7).Error("failed")
+	if got := len(all.Records()); got != 3 {
+		t.Errorf("全部级别的 Sink 应收到 3 条, 实际 %d", got)
+	}
+	lines := errOnly.Lines()
+	if len(lines) != 1 || !strings.Contains(lines[0], `"level":"ERROR"`) || !strings.Contains(lines[0], `"id":7`) {
+		t.Errorf("ERROR Sink 应只收到 ERROR 且使用自己的编码器: %q", lines)
+	}
+	if strings.Count(buf.String(), "\n") != 3 {
+		t.Errorf("日志文件不受 Sink 影响: %q", buf.String())
+	}
+
+	// Sink 在日志级别之上过滤
+	_ = lp.SetLevel(ERROR_LEVEL)
+	all.Reset()
+	lp.Info("hidden")
+	if len(all.Records()) != 0 {
+		t.Errorf("低于日志级别的记录不应交给 Sink: %v", all.Records())
+	}
+}
+
+func TestMemorySink_Ring(t *testing.T) {
+	s := NewMemorySink(2, TRACE_LEVEL, nil)
+	for _, msg := range []string{"a", "b", "c"} {
+		_ = s.Write(&Record{Level: INFO_LEVEL, Msg: msg})
+	}
+	records := s.Records()
+	if len(records) != 2 || records[0].Msg != "b" || records[1].Msg != "c" {
+		t.Errorf("应保留最近 2 条: %v", records)
+	}
+}
+
+func TestConsoleSink_Color(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewConsoleSink(&buf, TRACE_LEVEL, LogfmtEncoder{}, true)
+	_ = s.Write(&Record{Level: ERROR_LEVEL, Msg: "boom"})
+	_ = s.Write(&Record{Level: INFO_LEVEL, Msg: "plain"})
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], color.Red) || !strings.HasSuffix(lines[0], color.Reset) {
+		t.Errorf("ERROR 应为红色: %q", buf.String())
+	}
+	if strings.Contains(lines[1], "\033[") {
+		t.Errorf("INFO 不着色: %q", lines[1])
+	}
+}
+
+func TestSyslogSink_UDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer pc.Close()
+	s, err := NewSyslogSink(SyslogOpt{Addr: pc.LocalAddr().String(), AppName: "api", Hostname: "host1", Facility: 16}, INFO_LEVEL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.Enabled(DEBUG_LEVEL) {
+		t.Error("INFO 级别的 Sink 不应输出 DEBUG")
+	}
+	rec := &Record{Time: time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC), Level: ERROR_LEVEL, Tag: "order", Msg: "failed", Fields: []Field{F("id", 7)}}
+	if err := s.Write(rec); err != nil {
+		t.Fatal(err)
+	}
+	_ = pc.SetReadDeadline(time.Now().Add(time.Second))
+	b := make([]byte, 2048)
+	n, _, err := pc.ReadFrom(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// local0(16)*8 + err(3) = 131
+	want := "<131>1 2026-10-18T08:00:00Z host1 api " + strconv.Itoa(os.Getpid()) + " order - time="
+	if got := string(b[:n]); !strings.HasPrefix(got, want) || !strings.HasSuffix(got, `msg=failed id=7`) {
+		t.Errorf("got %q, want prefix %q", got, want)
+	}
+}
+
+func TestSyslogSink_TCPFraming(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+	s, err := NewSyslogSink(SyslogOpt{Network: "tcp", Addr: ln.Addr().String()}, TRACE_LEVEL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = s.Write(&Record{Level: INFO_LEVEL, Msg: "one"})
+	_ = s.Write(&Record{Level: INFO_LEVEL, Msg: "two"})
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	r := bufio.NewReader(conn)
+	for _, msg := range []string{"one", "two"} {
+		length, err := r.ReadString(' ')
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(length))
+		frame := make([]byte, n)
+		if _, err := io.ReadFull(r, frame); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(frame), "<14>1 ") || !strings.HasSuffix(string(frame), "msg="+msg) {
+			t.Errorf("octet counting 分帧错误: %q", frame)
+		}
+	}
+}
+
+func TestNewLogOpt_Sinks(t *testing.T) {
+	_ = os.Remove("log/sink_v2_error.log")
+	lp, err := NewLogOpt("sink_v2", &LogOpt{FileName: "log/sink_v2.log", Sinks: []SinkOpt{
+		{Type: SinkFile, FileName: "log/sink_v2_error.log", Level: "error", Format: FormatJSON},
+		{Type: SinkMemory, Level: "warn"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Unregister("sink_v2")
+	lp.Info("info")
+	lp.Warn("warn")
+	lp.Error("error")
+	_ = lp.Close()
+
+	data, err := os.ReadFile("log/sink_v2_error.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(data), "\n") != 1 || !strings.Contains(string(data), `"msg":"error"`) {
+		t.Errorf("只记录 ERROR 的文件: %s", data)
+	}
+	mem := lp.sinks[1].(*MemorySink)
+	if got := len(mem.Records()); got != 2 {
+		t.Errorf("WARN 级别的 memory Sink 应有 2 条, 实际 %d", got)
+	}
+
+	for _, so := range []SinkOpt{{Type: "kafka"}, {Type: SinkFile}, {Type: SinkMemory, Level: "loud"}, {Type: SinkSyslog}} {
+		if _, err := NewLogOpt("sink_bad", &LogOpt{FileName: "log/sink_bad.log", Sinks: []SinkOpt{so}}); err == nil {
+			t.Errorf("%+v 应返回错误", so)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// Handler 返回写入该日志池的 slog.Handler，级别、编码器和控制台输出跟随日志池：
+//
+//	slog.SetDefault(slog.New(log.Handler()))
+//
+// slog 的级别对应关系：低于 DEBUG 为 TRACE_LEVEL，DEBUG、INFO、WARN、ERROR 分别对应同名级别
+func (c *core) Handler() slog.Handler {
+	return &slogHandler{pool: c}
+}
+
+type slogHandler struct {
+	pool   *core
+	fields []Field
+	// group WithGroup 设置的分组前缀，形如 a.b.
+	group string
+}
+
+// slogLevel slog 级别转为日志池级别
+func slogLevel(level slog.Level) int {
+	switch {
+	case level < slog.LevelDebug:
+		return TRACE_LEVEL
+	case level < slog.LevelInfo:
+		return DEBUG_LEVEL
+	case level < slog.LevelWarn:
+		return INFO_LEVEL
+	case level < slog.LevelError:
+		return WARN_LEVEL
+	}
+	return ERROR_LEVEL
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.pool.enabled(slogLevel(level))
+}
+
+// Handle 记录日志，ctx 中用 WithContext 设置的字段放在最前面
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.PC != 0 && !h.pool.callerEnabled(slogLevel(r.Level), r.PC) {
+		return nil
+	}
+	ctxFields := ContextFields(ctx)
+	fields := make([]Field, 0, len(ctxFields)+len(h.fields)+r.NumAttrs())
+	fields = append(fields, ctxFields...)
+	fields = append(fields, h.fields...)
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttr(fields, h.group, a)
+		return true
+	})
+	caller := "???:0"
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		caller = filepath.Base(frame.File) + ":" + strconv.Itoa(frame.Line)
+	}
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	h.pool.write(&Record{
+		Time:   t,
+		Level:  slogLevel(r.Level),
+		Logger: h.pool.name,
+		Caller: caller,
+		Msg:    r.Message,
+		Fields: fields,
+	})
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := make([]Field, len(h.fields), len(h.fields)+len(attrs))
+	copy(fields, h.fields)
+	for _, a := range attrs {
+		fields = appendAttr(fields, h.group, a)
+	}
+	return &slogHandler{pool: h.pool, fields: fields, group: h.group}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{pool: h.pool, fields: h.fields, group: h.group + name + "."}
+}
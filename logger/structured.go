@@ -0,0 +1,111 @@
+package logger
+
+// With 创建带固定字段的子日志，参数为 key1, value1, key2, value2 形式，也可以直接传 Field 或 slog.Attr
+//
+//	log.With("user", u).Info("login", "latency", d)
+func (c *core) With(args ...any) *FieldLogger {
+	return &FieldLogger{pool: c, fields: fieldsFromArgs(nil, args)}
+}
+
+// WithTag 创建带 tag 的子日志
+func (c *core) WithTag(tag string) *FieldLogger {
+	return &FieldLogger{pool: c, tag: tag}
+}
+
+// Debugw 结构化 DEBUG 日志，args 为 key1, value1, key2, value2 形式
+func (c *core) Debugw(msg string, args ...any) {
+	if c.enabled(DEBUG_LEVEL) {
+		c.output(2, DEBUG_LEVEL, "", msg, fieldsFromArgs(nil, args))
+	}
+}
+
+// Infow 结构化 INFO 日志，args 为 key1, value1, key2, value2 形式
+func (c *core) Infow(msg string, args ...any) {
+	if c.enabled(INFO_LEVEL) {
+		c.output(2, INFO_LEVEL, "", msg, fieldsFromArgs(nil, args))
+	}
+}
+
+// Warnw 结构化 WARN 日志，args 为 key1, value1, key2, value2 形式
+func (c *core) Warnw(msg string, args ...any) {
+	if c.enabled(WARN_LEVEL) {
+		c.output(2, WARN_LEVEL, "", msg, fieldsFromArgs(nil, args))
+	}
+}
+
+// Errorw 结构化 ERROR 日志，args 为 key1, value1, key2, value2 形式
+func (c *core) Errorw(msg string, args ...any) {
+	if c.enabled(ERROR_LEVEL) {
+		c.output(2, ERROR_LEVEL, "", msg, fieldsFromArgs(nil, args))
+	}
+}
+
+// FieldLogger 带 tag 和固定字段的子日志，由 With 创建，级别和输出跟随所属的日志
+type FieldLogger struct {
+	pool   *core
+	tag    string
+	fields []Field
+	// skip 调用位置额外跳过的栈帧数，见 WithCallerSkip
+	skip int
+}
+
+// With 在当前字段的基础上追加字段，返回新的子日志
+func (l *FieldLogger) With(args ...any) *FieldLogger {
+	fields := make([]Field, len(l.fields), len(l.fields)+len(args))
+	copy(fields, l.fields)
+	return &FieldLogger{pool: l.pool, tag: l.tag, fields: fieldsFromArgs(fields, args), skip: l.skip}
+}
+
+// WithTag 返回使用新 tag 的子日志
+func (l *FieldLogger) WithTag(tag string) *FieldLogger {
+	return &FieldLogger{pool: l.pool, tag: tag, fields: l.fields, skip: l.skip}
+}
+
+// WithCallerSkip 记录的调用位置向上多跳过 n 层，用于在封装函数中记录业务代码的位置，例如 gin.Context.Info
+func (l *FieldLogger) WithCallerSkip(n int) *FieldLogger {
+	return &FieldLogger{pool: l.pool, tag: l.tag, fields: l.fields, skip: l.skip + n}
+}
+
+func (l *FieldLogger) Trace(msg string, args ...any) {
+	if l.pool.enabled(TRACE_LEVEL) {
+		l.pool.output(2+l.skip, TRACE_LEVEL, l.tag, msg, l.merge(args))
+	}
+}
+
+func (l *FieldLogger) Debug(msg string, args ...any) {
+	if l.pool.enabled(DEBUG_LEVEL) {
+		l.pool.output(2+l.skip, DEBUG_LEVEL, l.tag, msg, l.merge(args))
+	}
+}
+
+func (l *FieldLogger) Info(msg string, args ...any) {
+	if l.pool.enabled(INFO_LEVEL) {
+		l.pool.output(2+l.skip, INFO_LEVEL, l.tag, msg, l.merge(args))
+	}
+}
+
+func (l *FieldLogger) Warn(msg string, args ...any) {
+	if l.pool.enabled(WARN_LEVEL) {
+		l.pool.output(2+l.skip, WARN_LEVEL, l.tag, msg, l.merge(args))
+	}
+}
+
+func (l *FieldLogger) Error(msg string, args ...any) {
+	if l.pool.enabled(ERROR_LEVEL) {
+		l.pool.output(2+l.skip, ERROR_LEVEL, l.tag, msg, l.merge(args))
+	}
+}
+
+// Fatal 记录日志后调用 os.Exit(1)
+func (l *FieldLogger) Fatal(msg string, args ...any) {
+	l.pool.output(2+l.skip, FATAL_LEVEL, l.tag, msg, l.merge(args))
+}
+
+func (l *FieldLogger) merge(args []any) []Field {
+	if len(args) == 0 {
+		return l.fields
+	}
+	fields := make([]Field, len(l.fields), len(l.fields)+len(args))
+	copy(fields, l.fields)
+	return fieldsFromArgs(fields, args)
+}
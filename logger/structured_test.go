@@ -0,0 +1,128 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newBufferLog(t *testing.T, name, format string) (*LogPoolV2, *bytes.Buffer) {
+	t.Helper()
+	lp, err := NewLogOpt(name, &LogOpt{FileName: "log/" + name + ".log", Format: format})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = lp.Close() })
+	buf := &bytes.Buffer{}
+	lp.out = buf
+	return lp, buf
+}
+
+func TestLogPoolV2_Text(t *testing.T) {
+	lp, buf := newBufferLog(t, "structured_text", "")
+	lp.InfoTag("order", "created", 42)
+	lp.With("user", "alice").Info("login", "latency", 1500*time.Millisecond)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("期望 2 行, 实际: %q", buf.String())
+	}
+	if !strings.HasPrefix(lines[0], "INFO: ") || !strings.HasSuffix(lines[0], ": order created 42") {
+		t.Errorf("原有的文本格式不应改变: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], "structured_test.go:") || !strings.HasSuffix(lines[1], "login user=alice latency=1.5s") {
+		t.Errorf("结构化字段错误: %s", lines[1])
+	}
+}
+
+func TestLogPoolV2_Logfmt(t *testing.T) {
+	lp, buf := newBufferLog(t, "structured_logfmt", FormatLogfmt)
+	lp.WithTag("pay").Error("charge failed", "err", errors.New("card declined"), "amount", 12)
+	line := buf.String()
+	for _, want := range []string{"level=ERROR", "logger=structured_logfmt", "tag=pay", `msg="charge failed"`, `err="card declined"`, "amount=12"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("缺少 %s: %s", want, line)
+		}
+	}
+}
+
+func TestLogPoolV2_JSON(t *testing.T) {
+	lp, buf := newBufferLog(t, "structured_json", FormatJSON)
+	_ = lp.SetLevel(INFO_LEVEL)
+	lp.Debugw("hidden")
+	child := lp.With("user", "alice", F("id", 7))
+	child.With("step", 2).Info("pay", "ok", true, "odd")
+	child.Info("again")
+	lp.Errorf("legacy %d", 1)
+
+	dec := json.NewDecoder(buf)
+	var records []map[string]any
+	for dec.More() {
+		m := map[string]any{}
+		if err := dec.Decode(&m); err != nil {
+			t.Fatalf("不是合法的 JSON: %v %s", err, buf.String())
+		}
+		records = append(records, m)
+	}
+	if len(records) != 3 {
+		t.Fatalf("期望 3 条日志, 实际 %d", len(records))
+	}
+	r := records[0]
+	if r["level"] != "INFO" || r["logger"] != "structured_json" || r["msg"] != "pay" || r["user"] != "alice" ||
+		r["id"] != 7.0 || r["step"] != 2.0 || r["ok"] != true || r["!BADKEY"] != "odd" {
+		t.Errorf("字段错误: %v", r)
+	}
+	if !strings.HasPrefix(r["caller"].(string), "structured_test.go:") {
+		t.Errorf("caller 错误: %v", r["caller"])
+	}
+	if _, ok := records[1]["step"]; ok {
+		t.Errorf("子日志的字段不应影响父日志: %v", records[1])
+	}
+	if records[2]["level"] != "ERROR" || records[2]["msg"] != "legacy 1" {
+		t.Errorf("原有方法也应使用 JSON 格式: %v", records[2])
+	}
+}
+
+func TestLogPoolV2_Slog(t *testing.T) {
+	lp, buf := newBufferLog(t, "structured_slog", FormatJSON)
+	_ = lp.SetLevel(INFO_LEVEL)
+	logger := slog.New(lp.Handler()).With("svc", "api").WithGroup("req")
+	logger.Debug("hidden")
+	logger.Warn("slow", "ms", 300, slog.Group("user", "id", 1))
+
+	m := map[string]any{}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatalf("%v %s", err, buf.String())
+	}
+	if m["level"] != "WARN" || m["svc"] != "api" || m["req.ms"] != 300.0 || m["req.user.id"] != 1.0 {
+		t.Errorf("slog 字段错误: %v", m)
+	}
+	if !strings.HasPrefix(m["caller"].(string), "structured_test.go:") {
+		t.Errorf("caller 应为 slog 的调用位置: %v", m["caller"])
+	}
+}
+
+func TestNewEncoder(t *testing.T) {
+	if _, err := NewEncoder("xml"); err == nil {
+		t.Errorf("不支持的格式应返回错误")
+	}
+	if _, err := NewLogOpt("structured_bad", &LogOpt{FileName: "log/bad.log", Format: "xml"}); err == nil {
+		t.Errorf("不支持的格式应返回错误")
+	}
+}
+
+func BenchmarkLogPoolV2_JSON(b *testing.B) {
+	lp, err := NewLogOpt("structured_bench", &LogOpt{FileName: "log/bench.log", Format: FormatJSON})
+	if err != nil {
+		b.Fatal(err)
+	}
+	lp.out = &bytes.Buffer{}
+	child := lp.With("user", "alice")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		child.Info("request", "status", 200, "latency", time.Millisecond)
+	}
+}
@@ -0,0 +1,196 @@
+package logger
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SyslogOpt syslog Sink 的配置
+type SyslogOpt struct {
+	// Network udp、tcp、unixgram、unix，默认 udp。tcp、unix 按 RFC 6587 的 octet counting 分帧
+	Network string
+	// Addr 地址，例如 127.0.0.1:514、/dev/log
+	Addr string
+	// AppName APP-NAME，默认为进程名
+	AppName string
+	// Hostname HOSTNAME，默认为 os.Hostname
+	Hostname string
+	// Facility 默认 1（user），local0 到 local7 为 16 到 23
+	Facility int
+}
+
+// SyslogSink 按 RFC 5424 格式发送到 syslog，MSG 部分使用 Sink 的编码器，
+// 连接断开时下次写入重新连接
+type SyslogSink struct {
+	level   int
+	encoder Encoder
+	opt     SyslogOpt
+	stream  bool
+	procID  string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslogSink 创建 syslog Sink 并连接，enc 为空时使用 LogfmtEncoder
+func NewSyslogSink(opt SyslogOpt, level int, enc Encoder) (*SyslogSink, error) {
+	if opt.Network == "" {
+		opt.Network = "udp"
+	}
+	var stream bool
+	switch opt.Network {
+	case "udp", "udp4", "udp6", "unixgram":
+	case "tcp", "tcp4", "tcp6", "unix":
+		stream = true
+	default:
+		return nil, fmt.Errorf("logger syslog: unsupported network %q", opt.Network)
+	}
+	if opt.Addr == "" {
+		return nil, fmt.Errorf("logger syslog: addr is empty")
+	}
+	if opt.Facility < 0 || opt.Facility > 23 {
+		return nil, fmt.Errorf("logger syslog: facility %d out of range 0-23", opt.Facility)
+	}
+	if opt.Facility == 0 {
+		opt.Facility = 1
+	}
+	if opt.AppName == "" {
+		opt.AppName = filepath.Base(os.Args[0])
+	}
+	if opt.Hostname == "" {
+		opt.Hostname, _ = os.Hostname()
+	}
+	if enc == nil {
+		enc = LogfmtEncoder{}
+	}
+	s := &SyslogSink{
+		level:   level,
+		encoder: enc,
+		opt:     opt,
+		stream:  stream,
+		procID:  strconv.Itoa(os.Getpid()),
+	}
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *SyslogSink) connect() error {
+	conn, err := net.DialTimeout(s.opt.Network, s.opt.Addr, 5*time.Second)
+	if err != nil {
+		return fmt.Errorf("logger syslog: %w", err)
+	}
+	s.conn = conn
+	return nil
+}
+
+func (s *SyslogSink) Enabled(level int) bool {
+	return levelRank(level) >= levelRank(s.level)
+}
+
+func (s *SyslogSink) Write(r *Record) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	*buf = s.appendMessage(*buf, r)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return err
+		}
+	}
+	if _, err := s.conn.Write(*buf); err != nil {
+		// 重新连接后再试一次
+		_ = s.conn.Close()
+		s.conn = nil
+		if err := s.connect(); err != nil {
+			return err
+		}
+		_, err = s.conn.Write(*buf)
+		return err
+	}
+	return nil
+}
+
+// appendMessage 按 RFC 5424 编码：<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID - MSG，
+// MSGID 为日志的 tag，流式连接在前面加上长度
+func (s *SyslogSink) appendMessage(buf []byte, r *Record) []byte {
+	start := len(buf)
+	buf = append(buf, '<')
+	buf = strconv.AppendInt(buf, int64(s.opt.Facility*8+syslogSeverity(r.Level)), 10)
+	buf = append(buf, ">1 "...)
+	buf = r.Time.AppendFormat(buf, time.RFC3339Nano)
+	buf = append(buf, ' ')
+	buf = appendSyslogName(buf, s.opt.Hostname, 255)
+	buf = append(buf, ' ')
+	buf = appendSyslogName(buf, s.opt.AppName, 48)
+	buf = append(buf, ' ')
+	buf = append(buf, s.procID...)
+	buf = append(buf, ' ')
+	buf = appendSyslogName(buf, r.Tag, 32)
+	buf = append(buf, " - "...)
+	buf = s.encoder.Encode(buf, r)
+	buf = buf[:len(buf)-len("\n")]
+	if !s.stream {
+		return buf
+	}
+	frame := strconv.Itoa(len(buf)-start) + " "
+	buf = append(buf, frame...)
+	copy(buf[start+len(frame):], buf[start:len(buf)-len(frame)])
+	copy(buf[start:], frame)
+	return buf
+}
+
+// appendSyslogName HOSTNAME、APP-NAME、MSGID 只能是可打印的 ASCII，为空时为 -
+func appendSyslogName(buf []byte, s string, limit int) []byte {
+	if s == "" {
+		return append(buf, '-')
+	}
+	n := 0
+	for i := 0; i < len(s) && n < limit; i++ {
+		if c := s[i]; c > 32 && c < 127 {
+			buf = append(buf, c)
+			n++
+		}
+	}
+	if n == 0 {
+		return append(buf, '-')
+	}
+	return buf
+}
+
+// syslogSeverity 日志级别对应的 syslog severity
+func syslogSeverity(level int) int {
+	switch level {
+	case TRACE_LEVEL, DEBUG_LEVEL:
+		return 7
+	case INFO_LEVEL:
+		return 6
+	case WARN_LEVEL:
+		return 4
+	case ERROR_LEVEL:
+		return 3
+	case FATAL_LEVEL:
+		return 2
+	}
+	return 5
+}
+
+// Close 关闭连接，之后再写入时会重新连接
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
@@ -0,0 +1,121 @@
+// Package tools 读取、查询和跟踪 logger.Loggerj 写出的日志文件，轮转后的备份和 gzip 压缩的备份可以和当前文件一起读取。
+// 命令行工具见 logger/tools/logtool
+package tools
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Chairou/toolbox/logger"
+)
+
+// Files 按时间从旧到新返回日志的全部文件，备份在前，当前文件（存在时）在最后。
+// since 不为零时跳过最后修改时间早于 since 的备份，其中不会有 since 之后的日志
+func Files(l *logger.Loggerj, since time.Time) ([]string, error) {
+	if l.Filename == "" {
+		return nil, errors.New("logger tools: Filename is empty")
+	}
+	backups, err := l.Backups()
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, b := range backups {
+		if !since.IsZero() && b.ModTime.Before(since) {
+			continue
+		}
+		paths = append(paths, b.Path)
+	}
+	if _, err := os.Stat(l.Filename); err == nil {
+		paths = append(paths, l.Filename)
+	}
+	return paths, nil
+}
+
+// Open 按顺序读取多个日志文件，.gz 结尾的文件自动解压。文件在读到时才打开，已经被清理的文件跳过。
+// 文件最后没有换行时补一个，避免和下一个文件的第一行连在一起
+func Open(paths ...string) io.ReadCloser {
+	return &multiReader{paths: paths}
+}
+
+type multiReader struct {
+	paths []string
+	cur   io.Reader
+	close func() error
+	last  byte
+}
+
+func (m *multiReader) Read(p []byte) (int, error) {
+	for {
+		if m.cur == nil {
+			if len(m.paths) == 0 {
+				return 0, io.EOF
+			}
+			if err := m.next(); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		n, err := m.cur.Read(p)
+		if n > 0 {
+			m.last = p[n-1]
+			return n, nil
+		}
+		if err == io.EOF {
+			_ = m.Close()
+			if m.last != 0 && m.last != '\n' {
+				m.last = '\n'
+				p[0] = '\n'
+				return 1, nil
+			}
+			m.last = 0
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// next 打开下一个文件
+func (m *multiReader) next() error {
+	path := m.paths[0]
+	m.paths = m.paths[1:]
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		m.cur, m.close = f, f.Close
+		return nil
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("logger tools: %s: %w", path, err)
+	}
+	m.cur = gz
+	m.close = func() error {
+		_ = gz.Close()
+		return f.Close()
+	}
+	return nil
+}
+
+// Close 关闭正在读取的文件
+func (m *multiReader) Close() error {
+	if m.close == nil {
+		return nil
+	}
+	err := m.close()
+	m.cur, m.close = nil, nil
+	return err
+}
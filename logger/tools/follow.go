@@ -0,0 +1,188 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// FollowOpt Follow 的配置
+type FollowOpt struct {
+	// Lines 开始时先输出文件最后的行数，0 表示只输出之后写入的日志
+	Lines int
+	// Interval 检查新内容和轮转的间隔，默认 200ms
+	Interval time.Duration
+}
+
+const defaultFollowInterval = 200 * time.Millisecond
+
+// Tail 文件最后 n 行，不含换行
+func Tail(filename string, n int) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset, err := lastLinesOffset(f, fi.Size(), n)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, fi.Size()-offset)
+	if _, err := f.ReadAt(b, offset); err != nil && err != io.EOF {
+		return nil, err
+	}
+	s := strings.TrimSuffix(string(b), "\n")
+	if s == "" {
+		return nil, nil
+	}
+	return strings.Split(s, "\n"), nil
+}
+
+// Follow 类似 tail -F，持续把 filename 新写入的行（不含换行）交给 fn。
+// 文件被轮转（改名后重新创建）时读完旧文件再从新文件开头读取，被截断时从头读取，文件还不存在时等待创建。
+// ctx 取消时返回 ctx.Err()，fn 返回错误时停止并返回该错误
+func Follow(ctx context.Context, filename string, opt FollowOpt, fn func(line string) error) error {
+	if opt.Interval <= 0 {
+		opt.Interval = defaultFollowInterval
+	}
+	fw := &follower{filename: filename, fn: fn, buf: make([]byte, 32*1024)}
+	defer fw.close()
+	first := true
+	ticker := time.NewTicker(opt.Interval)
+	defer ticker.Stop()
+	for {
+		if fw.f == nil {
+			if err := fw.open(first, opt.Lines); err != nil {
+				return err
+			}
+		}
+		if fw.f != nil {
+			first = false
+			if err := fw.poll(); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+type follower struct {
+	filename string
+	fn       func(line string) error
+	f        *os.File
+	pos      int64
+	partial  string
+	buf      []byte
+}
+
+// open 打开文件，第一次打开时从最后 lines 行开始，轮转后的新文件从头开始。文件不存在时不报错
+func (fw *follower) open(first bool, lines int) error {
+	f, err := os.Open(fw.filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fw.f, fw.pos = f, 0
+	if first {
+		fi, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		if fw.pos, err = lastLinesOffset(f, fi.Size(), lines); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// poll 读取新内容，并检查文件是否被轮转或截断
+func (fw *follower) poll() error {
+	if err := fw.read(); err != nil {
+		return err
+	}
+	st, err := os.Stat(fw.filename)
+	if errors.Is(err, os.ErrNotExist) {
+		// 已经改名，新文件还没有创建
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	cur, err := fw.f.Stat()
+	if err != nil {
+		return err
+	}
+	if !os.SameFile(st, cur) {
+		// 旧文件改名后可能还有最后一次写入
+		if err := fw.read(); err != nil {
+			return err
+		}
+		if err := fw.flush(); err != nil {
+			return err
+		}
+		fw.close()
+		return nil
+	}
+	if st.Size() < fw.pos {
+		fw.pos, fw.partial = 0, ""
+	}
+	return nil
+}
+
+// read 从 pos 读到文件末尾，完整的行交给 fn，最后不完整的行留到下次
+func (fw *follower) read() error {
+	for {
+		n, err := fw.f.ReadAt(fw.buf, fw.pos)
+		if n > 0 {
+			fw.pos += int64(n)
+			data := fw.partial + string(fw.buf[:n])
+			for {
+				i := strings.IndexByte(data, '\n')
+				if i < 0 {
+					break
+				}
+				if err := fw.fn(strings.TrimSuffix(data[:i], "\r")); err != nil {
+					return err
+				}
+				data = data[i+1:]
+			}
+			fw.partial = data
+		}
+		if err == io.EOF || n == 0 {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// flush 输出最后不完整的行
+func (fw *follower) flush() error {
+	if fw.partial == "" {
+		return nil
+	}
+	line := fw.partial
+	fw.partial = ""
+	return fw.fn(line)
+}
+
+func (fw *follower) close() {
+	if fw.f != nil {
+		_ = fw.f.Close()
+		fw.f = nil
+	}
+}
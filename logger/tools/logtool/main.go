@@ -0,0 +1,238 @@
+// logtool 查看 logger 写出的日志文件，包括轮转后的备份和 gzip 压缩的备份。
+//
+//	logtool ls   -file log/api.log                                     按时间列出备份和当前文件
+//	logtool cat  -file log/api.log -since 1h -level warn -request-id xxx  按时间顺序查询全部文件
+//	logtool tail -file log/api.log -n 20 -f -tag order                  最后 n 行，-f 持续跟踪并跟随轮转
+//
+// 备份文件名的格式和时区参数需要与写日志时的 LogOpt 一致
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/Chairou/toolbox/logger"
+	"github.com/Chairou/toolbox/logger/tools"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "ls":
+		err = runList(os.Args[2:])
+	case "cat":
+		err = runCat(os.Args[2:])
+	case "tail":
+		err = runTail(os.Args[2:])
+	case "-h", "--help", "help":
+		usage()
+		return
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintln(os.Stderr, "logtool:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: logtool ls|cat|tail -file FILE [flags]")
+}
+
+// fileFlags 日志文件和备份文件名的参数
+type fileFlags struct {
+	file       string
+	pattern    string
+	timeFormat string
+	timezone   string
+}
+
+func (ff *fileFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&ff.file, "file", "", "current log file, e.g. log/api.log")
+	fs.StringVar(&ff.pattern, "pattern", "", "LogOpt.BackupPattern, default {name}-{time}{ext}")
+	fs.StringVar(&ff.timeFormat, "time-format", "", "LogOpt.BackupTimeFormat")
+	fs.StringVar(&ff.timezone, "tz", "", "LogOpt.RotateTimezone and time zone of text logs, default local")
+}
+
+func (ff *fileFlags) loggerj() (*logger.Loggerj, error) {
+	if ff.file == "" {
+		return nil, errors.New("-file is required")
+	}
+	l := &logger.Loggerj{Filename: ff.file, BackupPattern: ff.pattern, BackupTimeFormat: ff.timeFormat}
+	// 没有指定时区时与 Loggerj 的默认值一致，备份文件名使用 UTC
+	if ff.timezone != "" {
+		loc, err := ff.location()
+		if err != nil {
+			return nil, err
+		}
+		l.Location = loc
+	}
+	return l, nil
+}
+
+// location text 格式日志的时区
+func (ff *fileFlags) location() (*time.Location, error) {
+	if ff.timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(ff.timezone)
+}
+
+// queryFlags 过滤条件的参数
+type queryFlags struct {
+	since     string
+	until     string
+	level     string
+	tag       string
+	requestID string
+	grep      string
+}
+
+func (qf *queryFlags) register(fs *flag.FlagSet, withTime bool) {
+	if withTime {
+		fs.StringVar(&qf.since, "since", "", "start time, e.g. 2026-10-19 08:00:00, RFC3339 or a duration like 1h")
+		fs.StringVar(&qf.until, "until", "", "end time, same formats as -since")
+	}
+	fs.StringVar(&qf.level, "level", "", "minimum level: trace, debug, info, warn, error, fatal")
+	fs.StringVar(&qf.tag, "tag", "", "log tag")
+	fs.StringVar(&qf.requestID, "request-id", "", "request_id field")
+	fs.StringVar(&qf.grep, "grep", "", "text in the first line of the log")
+}
+
+func (qf *queryFlags) query(loc *time.Location) (tools.Query, error) {
+	q := tools.Query{Level: qf.level, Tag: qf.tag, RequestID: qf.requestID, Contains: qf.grep, Location: loc}
+	var err error
+	if q.Since, err = parseTime(qf.since, loc); err != nil {
+		return q, fmt.Errorf("-since: %w", err)
+	}
+	if q.Until, err = parseTime(qf.until, loc); err != nil {
+		return q, fmt.Errorf("-until: %w", err)
+	}
+	return q, nil
+}
+
+// parseTime 支持 RFC3339、2006-01-02 15:04:05、2006-01-02 和表示多久之前的 time.Duration
+func parseTime(s string, loc *time.Location) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown time %q", s)
+}
+
+func printLine(line string) error {
+	_, err := fmt.Println(line)
+	return err
+}
+
+func runList(args []string) error {
+	fs := flag.NewFlagSet("ls", flag.ExitOnError)
+	var ff fileFlags
+	ff.register(fs)
+	_ = fs.Parse(args)
+	l, err := ff.loggerj()
+	if err != nil {
+		return err
+	}
+	backups, err := l.Backups()
+	if err != nil {
+		return err
+	}
+	for _, b := range backups {
+		fmt.Printf("%s\t%d\t%s\n", b.ModTime.Format(time.RFC3339), b.Size, b.Path)
+	}
+	if fi, err := os.Stat(l.Filename); err == nil {
+		fmt.Printf("%s\t%d\t%s (current)\n", fi.ModTime().Format(time.RFC3339), fi.Size(), l.Filename)
+	}
+	return nil
+}
+
+func runCat(args []string) error {
+	fs := flag.NewFlagSet("cat", flag.ExitOnError)
+	var ff fileFlags
+	var qf queryFlags
+	ff.register(fs)
+	qf.register(fs, true)
+	_ = fs.Parse(args)
+	l, err := ff.loggerj()
+	if err != nil {
+		return err
+	}
+	loc, err := ff.location()
+	if err != nil {
+		return err
+	}
+	q, err := qf.query(loc)
+	if err != nil {
+		return err
+	}
+	return tools.Search(l, q, printLine)
+}
+
+func runTail(args []string) error {
+	fs := flag.NewFlagSet("tail", flag.ExitOnError)
+	var ff fileFlags
+	var qf queryFlags
+	ff.register(fs)
+	qf.register(fs, false)
+	n := fs.Int("n", 10, "start from the last n lines, the filters apply to them too")
+	follow := fs.Bool("f", false, "keep reading new logs, following rotations")
+	_ = fs.Parse(args)
+	l, err := ff.loggerj()
+	if err != nil {
+		return err
+	}
+	loc, err := ff.location()
+	if err != nil {
+		return err
+	}
+	q, err := qf.query(loc)
+	if err != nil {
+		return err
+	}
+	filter, err := tools.NewFilter(q)
+	if err != nil {
+		return err
+	}
+	if !*follow {
+		lines, err := tools.Tail(l.Filename, *n)
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			if filter.Match(line) {
+				fmt.Println(line)
+			}
+		}
+		return nil
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return tools.Follow(ctx, l.Filename, tools.FollowOpt{Lines: *n}, func(line string) error {
+		if filter.Match(line) {
+			return printLine(line)
+		}
+		return nil
+	})
+}
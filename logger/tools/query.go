@@ -0,0 +1,308 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Chairou/toolbox/logger"
+)
+
+// textTimeFormat logger.TextEncoder 的时间格式，没有时区
+const textTimeFormat = "2006-01-02 15:04:05"
+
+// Entry 解析后的一条日志
+type Entry struct {
+	Time   time.Time
+	Level  int
+	Logger string
+	// Tag text 格式中无法区分 tag 和消息，为空，tag 包含在 Msg 的开头
+	Tag    string
+	Caller string
+	Msg    string
+	// Fields 结构化字段，text 格式中取自消息里的 key=value
+	Fields map[string]string
+	// Format logger.FormatText、logger.FormatLogfmt 或 logger.FormatJSON
+	Format string
+}
+
+// Parse 解析一行日志，支持 logger 的 text、logfmt、json 三种格式。不是一条日志开头的行（多行消息的后续行）返回 false。
+// text 格式的时间没有时区，按 loc 解析，loc 为 nil 时使用本地时区
+func Parse(line string, loc *time.Location) (Entry, bool) {
+	line = strings.TrimRight(line, "\r\n")
+	switch {
+	case strings.HasPrefix(line, `{"time":"`):
+		return parseJSON(line)
+	case strings.HasPrefix(line, "time="):
+		return parseLogfmt(line)
+	}
+	return parseText(line, loc)
+}
+
+// parseText 解析 LEVEL: 2006-01-02 15:04:05 file.go:12: [tag ]msg key=value
+func parseText(line string, loc *time.Location) (Entry, bool) {
+	name, rest, ok := strings.Cut(line, ": ")
+	if !ok {
+		return Entry{}, false
+	}
+	level, err := logger.ParseLevel(name)
+	if err != nil || logger.LevelName(level) != name {
+		return Entry{}, false
+	}
+	if len(rest) <= len(textTimeFormat) || rest[len(textTimeFormat)] != ' ' {
+		return Entry{}, false
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	t, err := time.ParseInLocation(textTimeFormat, rest[:len(textTimeFormat)], loc)
+	if err != nil {
+		return Entry{}, false
+	}
+	caller, msg, _ := strings.Cut(rest[len(textTimeFormat)+1:], ": ")
+	e := Entry{Time: t, Level: level, Caller: caller, Msg: msg, Fields: map[string]string{}, Format: logger.FormatText}
+	logfmtPairs(msg, func(key, value string) {
+		e.Fields[key] = value
+	})
+	return e, true
+}
+
+func parseLogfmt(line string) (Entry, bool) {
+	e := Entry{Fields: map[string]string{}, Format: logger.FormatLogfmt}
+	var ok bool
+	logfmtPairs(line, func(key, value string) {
+		switch key {
+		case "time":
+			if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+				e.Time = t
+			}
+		case "level":
+			e.Level, ok = parseLevel(value)
+		case "logger":
+			e.Logger = value
+		case "tag":
+			e.Tag = value
+		case "caller":
+			e.Caller = value
+		case "msg":
+			e.Msg = value
+		default:
+			e.Fields[key] = value
+		}
+	})
+	return e, ok && !e.Time.IsZero()
+}
+
+func parseJSON(line string) (Entry, bool) {
+	var m map[string]any
+	d := json.NewDecoder(strings.NewReader(line))
+	d.UseNumber()
+	if err := d.Decode(&m); err != nil {
+		return Entry{}, false
+	}
+	e := Entry{Fields: map[string]string{}, Format: logger.FormatJSON}
+	var ok bool
+	for key, v := range m {
+		value, isString := v.(string)
+		if !isString {
+			b, _ := json.Marshal(v)
+			value = string(b)
+		}
+		switch key {
+		case "time":
+			if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+				e.Time = t
+			}
+		case "level":
+			e.Level, ok = parseLevel(value)
+		case "logger":
+			e.Logger = value
+		case "tag":
+			e.Tag = value
+		case "caller":
+			e.Caller = value
+		case "msg":
+			e.Msg = value
+		default:
+			e.Fields[key] = value
+		}
+	}
+	return e, ok && !e.Time.IsZero()
+}
+
+func parseLevel(name string) (int, bool) {
+	level, err := logger.ParseLevel(name)
+	return level, err == nil
+}
+
+// logfmtPairs 依次取出 key=value，值可以带引号，不是 key=value 的词跳过
+func logfmtPairs(s string, fn func(key, value string)) {
+	for {
+		s = strings.TrimLeft(s, " ")
+		i := strings.IndexAny(s, "= ")
+		if i < 0 {
+			return
+		}
+		if s[i] == ' ' || i == 0 {
+			s = s[i+1:]
+			continue
+		}
+		key := s[:i]
+		s = s[i+1:]
+		if strings.HasPrefix(s, `"`) {
+			if q, err := strconv.QuotedPrefix(s); err == nil {
+				value, _ := strconv.Unquote(q)
+				fn(key, value)
+				s = s[len(q):]
+				continue
+			}
+		}
+		j := strings.IndexByte(s, ' ')
+		if j < 0 {
+			j = len(s)
+		}
+		fn(key, s[:j])
+		s = s[j:]
+	}
+}
+
+// Query 日志查询条件，为零值的条件不过滤
+type Query struct {
+	// Since、Until 时间范围 [Since, Until)
+	Since time.Time
+	Until time.Time
+	// Level 最低级别，例如 warn 表示 WARN、ERROR、FATAL
+	Level string
+	// Tag 日志的 tag，text 格式中匹配消息开头的词
+	Tag string
+	// RequestID request_id 字段，见 logger.WithRequestID
+	RequestID string
+	// Contains 第一行中包含的文字
+	Contains string
+	// Location text 格式的时区，默认本地时区
+	Location *time.Location
+}
+
+// Filter 按 Query 逐行过滤日志，多行消息的后续行跟随第一行的结果
+type Filter struct {
+	q        Query
+	level    int
+	hasLevel bool
+	matched  bool
+}
+
+// NewFilter 创建过滤器，Level 不合法时返回错误
+func NewFilter(q Query) (*Filter, error) {
+	f := &Filter{q: q}
+	if q.Level != "" {
+		level, err := logger.ParseLevel(q.Level)
+		if err != nil {
+			return nil, err
+		}
+		f.level, f.hasLevel = level, true
+	}
+	// 没有条件时，开头不完整的多行消息也输出
+	f.matched = q.Since.IsZero() && q.Until.IsZero() && !f.hasLevel && q.Tag == "" && q.RequestID == "" && q.Contains == ""
+	return f, nil
+}
+
+// Match 这一行是否匹配
+func (f *Filter) Match(line string) bool {
+	e, ok := Parse(line, f.q.Location)
+	if !ok {
+		return f.matched
+	}
+	f.matched = f.match(&e, line)
+	return f.matched
+}
+
+func (f *Filter) match(e *Entry, line string) bool {
+	q := &f.q
+	if !q.Since.IsZero() && e.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !e.Time.Before(q.Until) {
+		return false
+	}
+	if f.hasLevel && !logger.LevelEnabled(e.Level, f.level) {
+		return false
+	}
+	if q.Tag != "" && e.Tag != q.Tag && (e.Format != logger.FormatText || !strings.HasPrefix(e.Msg, q.Tag+" ")) {
+		return false
+	}
+	if q.RequestID != "" && e.Fields[logger.FieldRequestID] != q.RequestID {
+		return false
+	}
+	return q.Contains == "" || strings.Contains(line, q.Contains)
+}
+
+// Scan 逐行读取 r，把 f 匹配的行（不含换行）交给 fn，fn 返回错误时停止并返回该错误
+func Scan(r io.Reader, f *Filter, fn func(line string) error) error {
+	br := bufio.NewReaderSize(r, 64*1024)
+	for {
+		line, err := br.ReadString('\n')
+		if len(line) > 0 {
+			line = strings.TrimRight(line, "\r\n")
+			if f.Match(line) {
+				if err := fn(line); err != nil {
+					return err
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Search 按时间顺序读取 l 的备份和当前文件，把匹配 q 的行交给 fn
+func Search(l *logger.Loggerj, q Query, fn func(line string) error) error {
+	f, err := NewFilter(q)
+	if err != nil {
+		return err
+	}
+	paths, err := Files(l, q.Since)
+	if err != nil {
+		return err
+	}
+	r := Open(paths...)
+	defer r.Close()
+	if err := Scan(r, f, fn); err != nil {
+		return fmt.Errorf("logger tools: %w", err)
+	}
+	return nil
+}
+
+// lastLinesOffset 文件最后 n 行开始的位置，结尾的换行不算一行
+func lastLinesOffset(r io.ReaderAt, size int64, n int) (int64, error) {
+	if n <= 0 {
+		return size, nil
+	}
+	buf := make([]byte, 4096)
+	count := 0
+	for end := size; end > 0; {
+		start := max(end-int64(len(buf)), 0)
+		b := buf[:end-start]
+		if _, err := r.ReadAt(b, start); err != nil && err != io.EOF {
+			return 0, err
+		}
+		for i := bytes.LastIndexByte(b, '\n'); i >= 0; i = bytes.LastIndexByte(b[:i], '\n') {
+			if start+int64(i) == size-1 {
+				continue
+			}
+			if count++; count == n {
+				return start + int64(i) + 1, nil
+			}
+		}
+		end = start
+	}
+	return 0, nil
+}
@@ -0,0 +1,193 @@
+package tools
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Chairou/toolbox/logger"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		line  string
+		level int
+		tag   string
+		rid   string
+	}{
+		{"WARN: 2026-10-19 08:00:01 comm.go:12: order slow request_id=r1 latency=2s", logger.WARN_LEVEL, "", "r1"},
+		{`time=2026-10-19T08:00:01Z level=ERROR logger=api tag=db caller=a.go:1 msg="mysql down" request_id=r2`, logger.ERROR_LEVEL, "db", "r2"},
+		{`{"time":"2026-10-19T08:00:01Z","level":"INFO","caller":"a.go:1","msg":"ok","request_id":"r3","count":3}`, logger.INFO_LEVEL, "", "r3"},
+	}
+	for _, c := range cases {
+		e, ok := Parse(c.line, time.UTC)
+		if !ok {
+			t.Errorf("无法解析: %s", c.line)
+			continue
+		}
+		if e.Level != c.level || e.Tag != c.tag || e.Fields[logger.FieldRequestID] != c.rid {
+			t.Errorf("%s: %+v", c.line, e)
+		}
+		if !e.Time.Equal(time.Date(2026, 10, 19, 8, 0, 1, 0, time.UTC)) {
+			t.Errorf("时间: %v", e.Time)
+		}
+	}
+	for _, line := range []string{"  at main.go:12", "", "INFO: not a time", "time=x level=INFO"} {
+		if _, ok := Parse(line, time.UTC); ok {
+			t.Errorf("%q 不是日志的开头", line)
+		}
+	}
+}
+
+// writeLogs 写入 text 格式的日志，gz 为 true 时 gzip 压缩
+func writeLogs(t *testing.T, path string, gz bool, lines ...string) {
+	t.Helper()
+	data := []byte(strings.Join(lines, "\n") + "\n")
+	if gz {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, _ = w.Write(data)
+		_ = w.Close()
+		data = buf.Bytes()
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	dir := t.TempDir()
+	l := &logger.Loggerj{Filename: filepath.Join(dir, "api.log")}
+	writeLogs(t, filepath.Join(dir, "api-2026-10-19T07-00-00.000.log.gz"), true,
+		"INFO: 2026-10-19 06:59:00 a.go:1: start",
+		"ERROR: 2026-10-19 06:59:30 a.go:2: db mysql down request_id=r1",
+		"goroutine 1 [running]:")
+	writeLogs(t, filepath.Join(dir, "api-2026-10-19T08-00-00.000.log"), false,
+		"WARN: 2026-10-19 07:30:00 a.go:3: order slow request_id=r1")
+	writeLogs(t, l.Filename, false,
+		`time=2026-10-19T08:10:00Z level=ERROR logger=api tag=db caller=a.go:4 msg="timeout" request_id=r2`)
+	// 不属于这个日志的文件
+	writeLogs(t, filepath.Join(dir, "other-2026-10-19T08-00-00.000.log"), false, "ERROR: 2026-10-19 07:00:00 a.go:5: other")
+
+	files, err := Files(l, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 || !strings.HasSuffix(files[0], ".gz") || files[2] != l.Filename {
+		t.Fatalf("应按时间列出备份和当前文件: %v", files)
+	}
+
+	search := func(q Query) []string {
+		t.Helper()
+		q.Location = time.UTC
+		var got []string
+		if err := Search(l, q, func(line string) error {
+			got = append(got, line[:strings.IndexByte(line, ' ')])
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+	if got := search(Query{}); len(got) != 5 {
+		t.Errorf("全部: %v", got)
+	}
+	// 多行消息的后续行跟随第一行
+	if got := search(Query{Level: "error"}); !reflect.DeepEqual(got, []string{"ERROR:", "goroutine", "time=2026-10-19T08:10:00Z"}) {
+		t.Errorf("级别: %v", got)
+	}
+	if got := search(Query{RequestID: "r1"}); !reflect.DeepEqual(got, []string{"ERROR:", "goroutine", "WARN:"}) {
+		t.Errorf("request_id: %v", got)
+	}
+	if got := search(Query{Tag: "db"}); len(got) != 3 {
+		t.Errorf("tag: %v", got)
+	}
+	since := time.Date(2026, 10, 19, 7, 0, 0, 0, time.UTC)
+	if got := search(Query{Since: since, Until: since.Add(time.Hour)}); !reflect.DeepEqual(got, []string{"WARN:"}) {
+		t.Errorf("时间范围: %v", got)
+	}
+	if _, err := NewFilter(Query{Level: "loud"}); err == nil {
+		t.Error("未知的级别应返回错误")
+	}
+}
+
+func TestTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.log")
+	var lines []string
+	for i := 0; i < 1000; i++ {
+		lines = append(lines, strings.Repeat("x", i%50))
+	}
+	writeLogs(t, path, false, lines...)
+	got, err := Tail(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, lines[997:]) {
+		t.Errorf("最后 3 行: %q", got)
+	}
+	if got, _ := Tail(path, 2000); len(got) != 1000 {
+		t.Errorf("行数不够时返回全部, 实际 %d", len(got))
+	}
+}
+
+func TestFollow_Rotate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "api.log")
+	writeLogs(t, path, false, "old1", "old2")
+
+	var mu sync.Mutex
+	var got []string
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Follow(ctx, path, FollowOpt{Lines: 1, Interval: 5 * time.Millisecond}, func(line string) error {
+			mu.Lock()
+			got = append(got, line)
+			mu.Unlock()
+			return nil
+		})
+	}()
+	waitLines := func(n int) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			mu.Lock()
+			l := len(got)
+			mu.Unlock()
+			if l >= n {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatalf("等待 %d 行超时: %q", n, got)
+	}
+
+	waitLines(1)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("a1\n")
+	// 与 Loggerj 一样改名后创建新文件，改名后旧文件还有一次写入
+	if err := os.Rename(path, filepath.Join(dir, "api-backup.log")); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("a2\n")
+	_ = f.Close()
+	writeLogs(t, path, false, "b1")
+	waitLines(4)
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("取消后应返回 context.Canceled: %v", err)
+	}
+	if want := []string{"old2", "a1", "a2", "b1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,220 @@
+// Package breaker 提供熔断器实现，按路由或下游服务名区分，支持半开探测
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// State 熔断器状态
+type State int
+
+const (
+	// StateClosed 关闭：请求正常放行
+	StateClosed State = iota
+	// StateOpen 打开：请求直接拒绝
+	StateOpen
+	// StateHalfOpen 半开：放行少量探测请求，成功则关闭，失败则重新打开
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrOpen 熔断器打开或半开探测名额已满时返回
+var ErrOpen = errors.New("circuit breaker is open")
+
+// Options 熔断器参数
+type Options struct {
+	// FailureThreshold 连续失败多少次后打开，默认 5
+	FailureThreshold int
+	// OpenTimeout 打开状态持续多久后进入半开，默认 30s
+	OpenTimeout time.Duration
+	// HalfOpenRequests 半开状态允许同时进行的探测请求数，默认 1
+	HalfOpenRequests int
+	// SuccessThreshold 半开状态连续成功多少次后关闭，默认 1
+	SuccessThreshold int
+	// OnStateChange 状态变化回调，可用于记录日志。回调在持锁状态下执行，不要在回调中调用熔断器方法
+	OnStateChange func(name string, from, to State)
+}
+
+// DefaultOptions 通过 Get 自动创建的熔断器使用的参数
+var DefaultOptions = Options{
+	FailureThreshold: 5,
+	OpenTimeout:      30 * time.Second,
+	HalfOpenRequests: 1,
+	SuccessThreshold: 1,
+}
+
+// Breaker 熔断器
+type Breaker struct {
+	name string
+	opt  Options
+
+	mu        sync.Mutex
+	state     State
+	failures  int
+	successes int
+	probing   int
+	openedAt  time.Time
+	// generation 每次状态变化加一，用于识别状态变化前放行的请求
+	generation uint64
+	now        func() time.Time
+}
+
+var breakers sync.Map
+
+// New 创建熔断器并按 name 注册，同名熔断器会被替换
+func New(name string, opt Options) *Breaker {
+	b := newBreaker(name, opt)
+	breakers.Store(name, b)
+	return b
+}
+
+func newBreaker(name string, opt Options) *Breaker {
+	if opt.FailureThreshold <= 0 {
+		opt.FailureThreshold = DefaultOptions.FailureThreshold
+	}
+	if opt.OpenTimeout <= 0 {
+		opt.OpenTimeout = DefaultOptions.OpenTimeout
+	}
+	if opt.HalfOpenRequests <= 0 {
+		opt.HalfOpenRequests = DefaultOptions.HalfOpenRequests
+	}
+	if opt.SuccessThreshold <= 0 {
+		opt.SuccessThreshold = DefaultOptions.SuccessThreshold
+	}
+	return &Breaker{name: name, opt: opt, now: time.Now}
+}
+
+// Get 按 name 获取熔断器，不存在时使用 DefaultOptions 创建
+func Get(name string) *Breaker {
+	if b, ok := breakers.Load(name); ok {
+		return b.(*Breaker)
+	}
+	b, _ := breakers.LoadOrStore(name, newBreaker(name, DefaultOptions))
+	return b.(*Breaker)
+}
+
+// Name 熔断器名字
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State 当前状态，打开超时后会显示为半开
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.checkOpenTimeout()
+	return b.state
+}
+
+// Token Allow 放行请求时返回的凭证，记录放行时熔断器所处的状态阶段
+type Token uint64
+
+// Allow 判断请求是否放行，放行时必须用返回的 Token 调用一次 Report 汇报结果
+func (b *Breaker) Allow() (Token, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.checkOpenTimeout()
+	switch b.state {
+	case StateOpen:
+		return 0, ErrOpen
+	case StateHalfOpen:
+		if b.probing >= b.opt.HalfOpenRequests {
+			return 0, ErrOpen
+		}
+		b.probing++
+	}
+	return Token(b.generation), nil
+}
+
+// Report 汇报 Allow 放行的请求的结果。放行之后熔断器状态已经变化时忽略，
+// 例如 closed 时放行的慢请求在半开后才结束，不会被当作探测请求
+func (b *Breaker) Report(token Token, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if uint64(token) != b.generation {
+		return
+	}
+	switch b.state {
+	case StateClosed:
+		if success {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.opt.FailureThreshold {
+			b.setState(StateOpen)
+		}
+	case StateHalfOpen:
+		if b.probing > 0 {
+			b.probing--
+		}
+		if !success {
+			b.setState(StateOpen)
+			return
+		}
+		b.successes++
+		if b.successes >= b.opt.SuccessThreshold {
+			b.setState(StateClosed)
+		}
+	}
+}
+
+// Do 在熔断器保护下执行 fn，fn 返回错误视为失败；熔断时返回 ErrOpen
+func (b *Breaker) Do(fn func() error) error {
+	token, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	success := false
+	defer func() {
+		// fn panic 时同样记为失败
+		b.Report(token, success)
+	}()
+	err = fn()
+	success = err == nil
+	return err
+}
+
+// Reset 强制关闭熔断器并清空计数
+func (b *Breaker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.setState(StateClosed)
+}
+
+// checkOpenTimeout 打开状态超时后转为半开，调用方需持有锁
+func (b *Breaker) checkOpenTimeout() {
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.opt.OpenTimeout {
+		b.setState(StateHalfOpen)
+	}
+}
+
+// setState 切换状态并重置计数，之前放行的请求的结果不再计入，调用方需持有锁
+func (b *Breaker) setState(state State) {
+	from := b.state
+	b.state = state
+	b.generation++
+	b.failures = 0
+	b.successes = 0
+	b.probing = 0
+	if state == StateOpen {
+		b.openedAt = b.now()
+	}
+	if from != state && b.opt.OnStateChange != nil {
+		b.opt.OnStateChange(b.name, from, state)
+	}
+}
@@ -0,0 +1,116 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestBreaker(opt Options) (*Breaker, *time.Time) {
+	now := time.Now()
+	b := newBreaker("test", opt)
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+func TestBreaker_OpenAfterFailures(t *testing.T) {
+	b, _ := newTestBreaker(Options{FailureThreshold: 3, OpenTimeout: time.Second})
+	errFail := errors.New("fail")
+	for i := 0; i < 3; i++ {
+		if err := b.Do(func() error { return errFail }); err != errFail {
+			t.Fatalf("第 %d 次调用应返回原始错误, 实际 %v", i, err)
+		}
+	}
+	if b.State() != StateOpen {
+		t.Fatalf("连续失败后应为 open, 实际 %s", b.State())
+	}
+	if err := b.Do(func() error { return nil }); err != ErrOpen {
+		t.Errorf("open 状态应返回 ErrOpen, 实际 %v", err)
+	}
+}
+
+// report 放行一个请求并汇报结果
+func report(t *testing.T, b *Breaker, success bool) {
+	t.Helper()
+	token, err := b.Allow()
+	if err != nil {
+		t.Fatalf("请求应被放行: %v", err)
+	}
+	b.Report(token, success)
+}
+
+func TestBreaker_SuccessResetsFailures(t *testing.T) {
+	b, _ := newTestBreaker(Options{FailureThreshold: 2})
+	report(t, b, false)
+	report(t, b, true)
+	report(t, b, false)
+	if b.State() != StateClosed {
+		t.Errorf("非连续失败不应打开熔断器")
+	}
+}
+
+func TestBreaker_HalfOpenProbe(t *testing.T) {
+	b, now := newTestBreaker(Options{FailureThreshold: 1, OpenTimeout: time.Second})
+	report(t, b, false)
+	if b.State() != StateOpen {
+		t.Fatalf("应为 open")
+	}
+
+	*now = now.Add(time.Second)
+	if b.State() != StateHalfOpen {
+		t.Fatalf("超时后应为 half-open, 实际 %s", b.State())
+	}
+	token, err := b.Allow()
+	if err != nil {
+		t.Fatalf("half-open 应放行一个探测请求: %v", err)
+	}
+	if _, err := b.Allow(); err != ErrOpen {
+		t.Errorf("探测名额已满时应返回 ErrOpen")
+	}
+	b.Report(token, false)
+	if b.State() != StateOpen {
+		t.Fatalf("探测失败应重新打开")
+	}
+
+	*now = now.Add(time.Second)
+	report(t, b, true)
+	if b.State() != StateClosed {
+		t.Errorf("探测成功应关闭, 实际 %s", b.State())
+	}
+}
+
+func TestBreaker_StaleReport(t *testing.T) {
+	b, now := newTestBreaker(Options{FailureThreshold: 1, OpenTimeout: time.Second})
+	// closed 时放行的慢请求
+	slow, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	report(t, b, false)
+	*now = now.Add(time.Second)
+	probe, err := b.Allow()
+	if err != nil {
+		t.Fatalf("half-open 应放行一个探测请求: %v", err)
+	}
+	b.Report(slow, true)
+	if b.State() != StateHalfOpen {
+		t.Fatalf("之前状态放行的请求不应计为探测结果, 实际 %s", b.State())
+	}
+	if _, err := b.Allow(); err != ErrOpen {
+		t.Errorf("之前状态放行的请求不应释放探测名额")
+	}
+	b.Report(probe, true)
+	if b.State() != StateClosed {
+		t.Errorf("探测成功应关闭, 实际 %s", b.State())
+	}
+}
+
+func TestGet(t *testing.T) {
+	if Get("svc-a") != Get("svc-a") {
+		t.Errorf("同名熔断器应返回同一实例")
+	}
+	b := New("svc-b", Options{FailureThreshold: 1})
+	if Get("svc-b") != b {
+		t.Errorf("New 创建的熔断器应可通过 Get 获取")
+	}
+}
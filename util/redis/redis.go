@@ -1,6 +1,7 @@
 package redis
 
 import (
+	"context"
 	"errors"
 	"log"
 	"sync"
@@ -11,6 +12,9 @@ import (
 
 var redisMap sync.Map
 
+// ErrNil key 不存在时 Get、HGet 等方法返回的错误
+var ErrNil = redigo.ErrNil
+
 type RdPool struct {
 	pool   *redigo.Pool
 	Name   string
@@ -636,3 +640,58 @@ func (c *RdPool) LRange(key string, start, stop int64) ([]string, error) {
 	}
 	return ret, nil
 }
+
+// HIncrBy
+// redis> HSET myhash field 5
+// (integer) 1
+// redis> HINCRBY myhash field 1
+// (integer) 6
+func (c *RdPool) HIncrBy(key, field string, increment int64) (int64, error) {
+	conn := c.pool.Get()
+	defer func(conn redigo.Conn) {
+		_ = conn.Close()
+	}(conn)
+	ret, err := redigo.Int64(conn.Do("HINCRBY", key, field, increment))
+	if err != nil {
+		return ret, err
+	}
+	return ret, nil
+}
+
+// Publish
+// redis> PUBLISH mychannel "hello"
+// (integer) 1
+func (c *RdPool) Publish(channel string, message string) (int64, error) {
+	conn := c.pool.Get()
+	defer func(conn redigo.Conn) {
+		_ = conn.Close()
+	}(conn)
+	ret, err := redigo.Int64(conn.Do("PUBLISH", channel, message))
+	if err != nil {
+		return ret, err
+	}
+	return ret, nil
+}
+
+// Subscribe 订阅 channel，每收到一条消息调用一次 fn。
+// 阻塞直到 ctx 结束（返回 nil）或连接出错（返回错误），断线重连由调用方处理
+func (c *RdPool) Subscribe(ctx context.Context, channel string, fn func(message string)) error {
+	psc := redigo.PubSubConn{Conn: c.pool.Get()}
+	defer func(psc redigo.PubSubConn) {
+		_ = psc.Close()
+	}(psc)
+	if err := psc.Subscribe(channel); err != nil {
+		return err
+	}
+	for {
+		switch v := psc.ReceiveContext(ctx).(type) {
+		case redigo.Message:
+			fn(string(v.Data))
+		case error:
+			if ctx.Err() != nil {
+				return nil
+			}
+			return v
+		}
+	}
+}
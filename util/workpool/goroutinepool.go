@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Chairou/toolbox/logger"
 	"github.com/Chairou/toolbox/util/workqueue"
 	"github.com/Chairou/toolbox/util/workqueue/runtime"
 	"github.com/Chairou/toolbox/util/workqueue/wait"
@@ -40,6 +41,8 @@ type GoRoutineExecutor struct {
 	TaskID string
 	GoRoutineFunc
 	GoRoutineParams []interface{}
+	// Ctx 提交任务的请求 context，日志会带上其中 logger.WithContext 设置的 request_id 等字段，为空时使用池的 ctx
+	Ctx context.Context
 }
 
 // NewRateLimitedGoRoutinePool 创建一个带速率限制的 goroutine 池。
@@ -72,7 +75,15 @@ func (p *GoRoutinePool) Submit(executor GoRoutineExecutor) {
 	p.queue.AddRateLimited(executor.TaskID)
 	p.entry.Store(executor.TaskID, executor)
 	p.wg.Add(1)
-	klog.Infof("submit task %v to goroutine pool", executor.TaskID)
+	klog.InfoS("submit task to goroutine pool", p.logArgs(executor.Ctx, executor.TaskID)...)
+}
+
+// logArgs klog 结构化日志的字段：ctx 中的日志字段和任务ID
+func (p *GoRoutinePool) logArgs(ctx context.Context, taskID any) []any {
+	if ctx == nil {
+		ctx = p.ctx
+	}
+	return append(logger.ContextArgs(ctx), "task", taskID)
 }
 
 // Run 启动 goroutine 池并等待所有任务完成，返回所有任务的执行结果。
@@ -113,7 +124,6 @@ func (p *GoRoutinePool) processNextItem() bool {
 	if quit {
 		return false
 	}
-	klog.Infof("goroutine pool exec task: %v", name)
 	p.buff <- struct{}{}
 	entry, ok := p.entry.Load(name)
 	defer func() {
@@ -125,16 +135,17 @@ func (p *GoRoutinePool) processNextItem() bool {
 	}()
 
 	if !ok {
-		klog.Errorf("processNextItem not found key %v", name)
+		klog.ErrorS(nil, "processNextItem not found key", p.logArgs(nil, name)...)
 		return true
 	}
 	executor := entry.(GoRoutineExecutor)
+	klog.InfoS("goroutine pool exec task", p.logArgs(executor.Ctx, name)...)
 	fc := executor.GoRoutineFunc
 	params := executor.GoRoutineParams
 
 	result.Result, result.Err = fc(params...)
 	if result.Err != nil {
-		klog.Errorf("processNextItem %v error: %v", name, result.Err)
+		klog.ErrorS(result.Err, "processNextItem error", p.logArgs(executor.Ctx, name)...)
 	}
 
 	return true